@@ -3,13 +3,10 @@ package application
 import (
 	"context"
 	"errors"
-	"math/big"
-	"math/rand/v2"
 	"net/url"
+	"os"
 	"time"
 
-	"go.opentelemetry.io/otel/trace"
-
 	"github.com/neonmei/challenge_urlshortener/platform/o11y/semconv"
 
 	"github.com/neonmei/challenge_urlshortener/domain"
@@ -23,48 +20,77 @@ import (
 
 type shortenerService struct {
 	urlRepo      domain.URLRepository
+	seqRepo      domain.SequenceRepository
+	ids          IDGenerator
+	alphabet     validators.IdAlphabet
+	blocklist    validators.Blocklist
+	hitsRepo     domain.HitsRepository
+	clicks       ClickRecorder
+	tracker      ClickTracker
+	bots         BotClassifier
+	trending     TrendingTracker
+	trendingOn   bool
 	hitCounter   metric.Int64Counter
 	serviceMeter metric.Meter
 	svcURL       url.URL
+	policy       validators.URLPolicy
 	cfg          config.AppConfig
 }
 
-func (e shortenerService) Shorten(ctx context.Context, longURL string, author string) (*url.URL, error) {
+func (e shortenerService) Shorten(ctx context.Context, longURL string, author string, alias string, expiresAt time.Time) (*url.URL, error) {
 	u, err := url.Parse(longURL)
 	if err != nil {
 		return nil, errors.Join(domain.ErrInvalidURL, err)
 	}
 
-	if err := validators.ValidateURL(u); err != nil {
+	if err := e.policy.Validate(u); err != nil {
 		return nil, err
 	}
 
-	base62string, err := e.generateHash(ctx)
-	if err != nil {
-		return nil, err
-	}
+	for rounds := uint64(1); ; rounds++ {
+		urlID, err := e.pickId(ctx, alias)
+		if err != nil {
+			return nil, err
+		}
 
-	newURL := domain.ShortURL{
-		ID:        base62string,
-		Upstream:  *u,
-		CreatedBy: author,
-		CreatedAt: time.Now(),
-		Enabled:   true,
-	}
+		newURL := domain.ShortURL{
+			ID:        urlID,
+			Upstream:  *u,
+			CreatedBy: author,
+			CreatedAt: time.Now(),
+			Enabled:   true,
+			ExpiresAt: expiresAt,
+		}
 
-	o11y.TraceShortURL(ctx, &newURL)
-	if err := validators.ValidateShortURL(newURL); err != nil {
-		return nil, err
-	}
+		o11y.TraceShortURL(ctx, &newURL)
+		if err := validators.ValidateShortURL(newURL, e.alphabet); err != nil {
+			return nil, err
+		}
 
-	if err := e.urlRepo.Save(ctx, newURL); err != nil {
-		return nil, errors.Join(domain.ErrUnavailableRepo, err)
-	}
+		err = e.urlRepo.Save(ctx, newURL)
+		// REF: generated identifiers may still collide with aliases, draw another one
+		if errors.Is(err, domain.ErrURLAlreadyExists) && alias == "" && rounds < e.cfg.Hasher.MaxRounds {
+			continue
+		}
+
+		if err != nil {
+			// REF: the caller never chose a generated identifier, running out of them is on us
+			if errors.Is(err, domain.ErrURLAlreadyExists) && alias == "" {
+				return nil, errors.Join(domain.ErrUnavailableRepo, domain.ErrIdsExhausted)
+			}
+
+			// REF: conditional write lost, someone else owns this identifier
+			if errors.Is(err, domain.ErrURLAlreadyExists) {
+				return nil, err
+			}
+			return nil, errors.Join(domain.ErrUnavailableRepo, err)
+		}
 
-	return e.svcURL.JoinPath(newURL.ID), nil
+		return e.svcURL.JoinPath(newURL.ID), nil
+	}
 }
 
-func (e shortenerService) Redirect(ctx context.Context, urlID string) (string, error) {
+func (e shortenerService) Redirect(ctx context.Context, urlID string, visit domain.Visit) (string, error) {
 	urlEntry, err := e.urlRepo.Get(ctx, urlID)
 	o11y.TraceShortURL(ctx, urlEntry)
 
@@ -73,15 +99,26 @@ func (e shortenerService) Redirect(ctx context.Context, urlID string) (string, e
 		err = errors.Join(domain.ErrCannotUseDisabled)
 	}
 
+	// Expired entries are treated like disabled ones, with their own error
+	if urlEntry != nil && urlEntry.Enabled && urlEntry.Expired(time.Now()) {
+		err = domain.ErrURLExpired
+	}
+
 	if err != nil {
 		return "", err
 	}
 
 	metric.WithAttributeSet(attribute.NewSet())
 
+	visit.Bot = e.bots.IsBot(visit)
 	e.hitCounter.Add(ctx, 1, metric.WithAttributes(
-		attribute.String("url_id", urlID)),
+		attribute.String("url_id", urlID),
+		attribute.Bool(semconv.HitIsBot, visit.Bot)),
 	)
+	now := time.Now()
+	e.clicks.Record(urlID, now, visit)
+	e.tracker.Track(urlID, now, visit)
+	e.trending.Hit(urlID, now)
 
 	return urlEntry.Upstream.String(), nil
 }
@@ -90,6 +127,14 @@ func (e shortenerService) Delete(ctx context.Context, urlID string) error {
 	return e.urlRepo.Delete(ctx, urlID)
 }
 
+func (e shortenerService) Restore(ctx context.Context, urlID string) error {
+	return e.urlRepo.Restore(ctx, urlID)
+}
+
+func (e shortenerService) Purge(ctx context.Context, urlID string) error {
+	return e.urlRepo.Purge(ctx, urlID)
+}
+
 func (e shortenerService) Fetch(ctx context.Context, urlID string) (*domain.ShortURL, error) {
 	urlEntry, err := e.urlRepo.Get(ctx, urlID)
 	if err != nil {
@@ -100,40 +145,119 @@ func (e shortenerService) Fetch(ctx context.Context, urlID string) (*domain.Shor
 	return urlEntry, err
 }
 
-func (e shortenerService) generateHash(ctx context.Context) (string, error) {
-	currentRounds := uint64(0)
-	base62string := ""
-	resultErr := error(nil)
+// pickId uses the caller-chosen alias when present, otherwise generates one.
+// Aliases are not probed for existence, the repository decides on Save.
+func (e shortenerService) pickId(ctx context.Context, alias string) (string, error) {
+	if alias == "" {
+		return e.generateId(ctx)
+	}
+
+	if err := validators.ValidateAlias(alias, e.alphabet, e.cfg.ReservedIds); err != nil {
+		return "", err
+	}
+
+	return alias, nil
+}
+
+// Update repoints or toggles an URL, keeping its previous state as a revision
+func (e shortenerService) Update(ctx context.Context, urlID string, author string, changes URLChanges) (*domain.ShortURL, error) {
+	if err := validators.ValidateAuthor(author); err != nil {
+		return nil, err
+	}
+
+	current, err := e.urlRepo.Get(ctx, urlID)
+	if err != nil {
+		return nil, err
+	}
+
+	if changes.Version != nil && *changes.Version != current.Version {
+		return nil, domain.ErrVersionConflict
+	}
+
+	updated := *current
+	if changes.Upstream != nil {
+		u, err := url.Parse(*changes.Upstream)
+		if err != nil {
+			return nil, errors.Join(domain.ErrInvalidURL, err)
+		}
+
+		if err := e.policy.Validate(u); err != nil {
+			return nil, err
+		}
+		updated.Upstream = *u
+	}
+
+	if changes.Enabled != nil {
+		updated.Enabled = *changes.Enabled
+	}
+
+	revision := domain.Revision{
+		Version:   current.Version,
+		Upstream:  current.Upstream,
+		Enabled:   current.Enabled,
+		ChangedBy: author,
+		ChangedAt: time.Now(),
+	}
+
+	if err := e.urlRepo.Update(ctx, updated, revision); err != nil {
+		return nil, err
+	}
+
+	updated.Version++
+	o11y.TraceShortURL(ctx, &updated)
+	return &updated, nil
+}
+
+func (e shortenerService) Revisions(ctx context.Context, urlID string) ([]domain.Revision, error) {
+	return e.urlRepo.Revisions(ctx, urlID)
+}
+
+// List clamps the page size into the configured bounds
+func (e shortenerService) List(ctx context.Context, filter domain.URLFilter, cursor string, limit int) (*domain.URLPage, error) {
+	if limit <= 0 {
+		limit = e.cfg.List.DefaultLimit
+	}
+
+	if limit > e.cfg.List.MaxLimit {
+		limit = e.cfg.List.MaxLimit
+	}
+
+	return e.urlRepo.List(ctx, filter, cursor, limit)
+}
+
+// maxFilteredIds bounds how many generated identifiers can be skipped in a
+// row. Sequential generators emit runs of identifiers sharing a blocked
+// prefix, so it is much larger than the collision rounds
+const maxFilteredIds = 10000
 
-	for currentRounds < e.cfg.Hasher.MaxRounds {
-		bigInt := big.Int{}
-		base62string = bigInt.SetUint64(rand.Uint64N(e.cfg.Hasher.RandomMaxValue)).Text(62)
-		_, resultErr = e.urlRepo.Get(ctx, base62string)
+// generateId skips generated identifiers colliding with reserved or blocked
+// words, as generators are unaware of them
+func (e shortenerService) generateId(ctx context.Context) (string, error) {
+	for range maxFilteredIds {
+		urlID, err := e.ids.Next(ctx)
+		if err != nil {
+			return "", err
+		}
 
-		// REF: already exists
-		if resultErr == nil {
-			currentRounds++
+		if e.blocklist.Contains(urlID) {
 			continue
 		}
 
-		// REF: does not exist
-		if errors.Is(resultErr, domain.ErrURLNotFound) {
-			trace.SpanFromContext(ctx).SetAttributes(
-				attribute.Int64(semconv.HasherRounds, int64(currentRounds)),
-				attribute.Int(semconv.HasherLength, len(base62string)),
-			)
-			return base62string, nil
+		// REF: generators must stick to the configured alphabet, only lookups fall back to base62
+		if err := validators.ValidateGeneratedId(urlID, e.alphabet); err != nil {
+			return "", err
 		}
 
-		if resultErr != nil {
-			break
+		err = validators.ValidateAlias(urlID, e.alphabet, e.cfg.ReservedIds)
+		if !errors.Is(err, domain.ErrReservedId) {
+			return urlID, err
 		}
 	}
 
-	return "", errors.Join(domain.ErrUnavailableRepo, resultErr)
+	return "", domain.ErrReservedId
 }
 
-func New(cfg config.AppConfig, urlRepo domain.URLRepository) (Service, error) {
+func New(cfg config.AppConfig, urlRepo domain.URLRepository, opts ...Option) (Service, error) {
 	m := otel.GetMeterProvider().Meter("application")
 	c, err := m.Int64Counter(
 		semconv.MetricURLHits,
@@ -149,11 +273,57 @@ func New(cfg config.AppConfig, urlRepo domain.URLRepository) (Service, error) {
 		return nil, err
 	}
 
-	return &shortenerService{
+	svc := &shortenerService{
 		urlRepo:      urlRepo,
+		clicks:       noopRecorder{},
+		tracker:      noopTracker{},
+		trending:     noopTrending{},
+		bots:         noopClassifier{},
 		hitCounter:   c,
 		serviceMeter: m,
 		svcURL:       *baseHost,
-		cfg:          cfg,
-	}, nil
+		policy: validators.URLPolicy{
+			MaxLength:  cfg.MaxLength,
+			OwnHosts:   []string{baseHost.Hostname()},
+			Shorteners: cfg.Policy.Shorteners,
+			AllowHosts: cfg.Policy.AllowHosts,
+			DenyHosts:  cfg.Policy.DenyHosts,
+		},
+		cfg: cfg,
+	}
+
+	svc.alphabet, err = validators.NewIdAlphabet(cfg.Hasher.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		opt(svc)
+	}
+
+	if cfg.Hasher.BlocklistFile != "" {
+		svc.blocklist, err = loadBlocklist(cfg.Hasher.BlocklistFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if svc.ids == nil {
+		svc.ids, err = NewIDGenerator(cfg, svc.alphabet, urlRepo, svc.seqRepo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return svc, nil
+}
+
+func loadBlocklist(path string) (validators.Blocklist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return validators.ParseBlocklist(f)
 }
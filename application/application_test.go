@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -31,12 +32,12 @@ func TestBasicShortenShouldGenerate(t *testing.T) {
 	svc, err := New(cfg, repositories.NewMemory())
 	assert.NoError(t, err)
 
-	u, err := svc.Shorten(ctx, validURL.String(), validAuthor)
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
 	assert.NoError(t, err)
 	assert.NotNil(t, u)
 	assert.Equal(t, u.Scheme, baseURL.Scheme)
 	assert.Equal(t, u.Host, baseURL.Host)
-	assert.NoError(t, validators.ValidateId(u.Path))
+	assert.NoError(t, validators.ValidateId(u.Path, validators.IdAlphabet{}))
 }
 
 func TestBadURLShouldNotValidate(t *testing.T) {
@@ -45,7 +46,7 @@ func TestBadURLShouldNotValidate(t *testing.T) {
 	svc, err := New(cfg, repositories.NewMemory())
 	assert.NoError(t, err)
 
-	u, err := svc.Shorten(ctx, invalidURL.String(), validAuthor)
+	u, err := svc.Shorten(ctx, invalidURL.String(), validAuthor, "", time.Time{})
 	assert.ErrorIs(t, err, domain.ErrInvalidURL)
 	assert.Nil(t, u)
 }
@@ -56,7 +57,7 @@ func TestBadURLShouldNotParse(t *testing.T) {
 	svc, err := New(cfg, repositories.NewMemory())
 	assert.NoError(t, err)
 
-	u, err := svc.Shorten(ctx, "hello!", validAuthor)
+	u, err := svc.Shorten(ctx, "hello!", validAuthor, "", time.Time{})
 	assert.ErrorIs(t, err, domain.ErrInvalidURL)
 	assert.Nil(t, u)
 }
@@ -71,7 +72,7 @@ func TestBrokenBackendErrShouldPropagate(t *testing.T) {
 	svc, err := New(cfg, repo)
 	assert.NoError(t, err)
 
-	u, err := svc.Shorten(ctx, validURL.String(), validAuthor)
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
 	assert.ErrorIs(t, err, repoErr)
 	assert.Nil(t, u)
 }
@@ -88,7 +89,7 @@ func TestBrokenBackendSaveErrShouldPropagate(t *testing.T) {
 	svc, err := New(cfg, repo)
 	assert.NoError(t, err)
 
-	u, err := svc.Shorten(ctx, validURL.String(), validAuthor)
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
 	assert.ErrorIs(t, err, repoErr)
 	assert.Nil(t, u)
 }
@@ -100,11 +101,11 @@ func TestRedirectOk(t *testing.T) {
 	svc, err := New(cfg, repositories.NewMemory())
 	assert.NoError(t, err)
 
-	u, err := svc.Shorten(ctx, validURL.String(), validAuthor)
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
 	assert.NoError(t, err)
 	assert.NotNil(t, u)
 
-	upstream, err := svc.Redirect(ctx, u.Path)
+	upstream, err := svc.Redirect(ctx, u.Path, domain.Visit{})
 	assert.NoError(t, err)
 	assert.Equal(t, validURL.String(), upstream)
 }
@@ -127,7 +128,7 @@ func TestRedirectDisabled(t *testing.T) {
 	svc, err := New(cfg, repo)
 	assert.NoError(t, err)
 
-	upstream, err := svc.Redirect(ctx, validURL.Path)
+	upstream, err := svc.Redirect(ctx, validURL.Path, domain.Visit{})
 	assert.ErrorIs(t, err, domain.ErrCannotUseDisabled)
 	assert.Equal(t, "", upstream)
 }
@@ -139,18 +140,43 @@ func TestDeleteOk(t *testing.T) {
 	svc, err := New(cfg, repositories.NewMemory())
 	assert.NoError(t, err)
 
-	u, err := svc.Shorten(ctx, validURL.String(), validAuthor)
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
 	assert.NoError(t, err)
 	assert.NotNil(t, u)
 
 	err = svc.Delete(ctx, u.Path)
 	assert.NoError(t, err)
 
-	upstream, err := svc.Redirect(ctx, u.Path)
-	assert.ErrorIs(t, domain.ErrURLNotFound, err)
+	upstream, err := svc.Redirect(ctx, u.Path, domain.Visit{})
+	assert.ErrorIs(t, err, domain.ErrCannotUseDisabled)
 	assert.Equal(t, "", upstream)
 }
 
+func TestRestoreAndPurge(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	svc, err := New(cfg, repositories.NewMemory())
+	assert.NoError(t, err)
+
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
+	assert.NoError(t, err)
+	assert.NoError(t, svc.Delete(ctx, u.Path))
+
+	// REF: restored URLs redirect again
+	assert.NoError(t, svc.Restore(ctx, u.Path))
+	upstream, err := svc.Redirect(ctx, u.Path, domain.Visit{})
+	assert.NoError(t, err)
+	assert.Equal(t, validURL.String(), upstream)
+
+	// REF: purged URLs are gone, and cannot be restored
+	assert.NoError(t, svc.Purge(ctx, u.Path))
+	_, err = svc.Redirect(ctx, u.Path, domain.Visit{})
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	assert.ErrorIs(t, svc.Restore(ctx, u.Path), domain.ErrURLNotFound)
+	assert.ErrorIs(t, svc.Delete(ctx, u.Path), domain.ErrURLNotFound)
+	assert.ErrorIs(t, svc.Purge(ctx, u.Path), domain.ErrURLNotFound)
+}
+
 func TestFetchOk(t *testing.T) {
 	ctx := context.Background()
 	cfg := config.Load()
@@ -158,7 +184,7 @@ func TestFetchOk(t *testing.T) {
 	svc, err := New(cfg, repositories.NewMemory())
 	assert.NoError(t, err)
 
-	u, err := svc.Shorten(ctx, validURL.String(), validAuthor)
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
 	assert.NoError(t, err)
 	assert.NotNil(t, u)
 
@@ -178,3 +204,267 @@ func TestFetchNonExistant(t *testing.T) {
 	assert.ErrorIs(t, domain.ErrURLNotFound, err)
 	assert.Nil(t, upstream)
 }
+
+func TestShortenWithAlias(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	cfg.BaseUrl = baseURL.String()
+	svc, err := New(cfg, repositories.NewMemory())
+	assert.NoError(t, err)
+
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "hotsale25", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, baseURL.JoinPath("hotsale25").String(), u.String())
+
+	upstream, err := svc.Redirect(ctx, "hotsale25", domain.Visit{})
+	assert.NoError(t, err)
+	assert.Equal(t, validURL.String(), upstream)
+}
+
+func TestSwitchingAlphabetKeepsLinks(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	cfg.BaseUrl = baseURL.String()
+	noLookalikes, err := validators.NewIdAlphabet("nolookalikes")
+	assert.NoError(t, err)
+	repo := repositories.NewMemory(repositories.WithIdAlphabet(noLookalikes))
+
+	before, err := New(cfg, repo)
+	assert.NoError(t, err)
+	_, err = before.Shorten(ctx, validURL.String(), validAuthor, "Oo01", time.Time{})
+	assert.NoError(t, err)
+
+	cfg.Hasher.Alphabet = "nolookalikes"
+	after, err := New(cfg, repo)
+	assert.NoError(t, err)
+
+	// REF: links already handed out and base62 aliases keep working
+	upstream, err := after.Redirect(ctx, "Oo01", domain.Visit{})
+	assert.NoError(t, err)
+	assert.Equal(t, validURL.String(), upstream)
+	_, err = after.Shorten(ctx, validURL.String(), validAuthor, "Il10", time.Time{})
+	assert.NoError(t, err)
+
+	for range 50 {
+		u, err := after.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
+		assert.NoError(t, err)
+		assert.NoError(t, validators.ValidateGeneratedId(u.Path, noLookalikes))
+	}
+}
+
+func TestShortenAliasConflict(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	svc, err := New(cfg, repositories.NewMemory())
+	assert.NoError(t, err)
+
+	_, err = svc.Shorten(ctx, validURL.String(), validAuthor, "hotsale25", time.Time{})
+	assert.NoError(t, err)
+
+	// REF: a taken alias must not fall back to a generated one
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "hotsale25", time.Time{})
+	assert.ErrorIs(t, err, domain.ErrURLAlreadyExists)
+	assert.NotErrorIs(t, err, domain.ErrUnavailableRepo)
+	assert.Nil(t, u)
+}
+
+func TestShortenAliasShouldNotProbe(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	repo := mockDomain.NewMockURLRepository(t)
+
+	// REF: only the conditional Save decides, no Get is expected
+	repo.On("Save", mock.Anything, mock.Anything).Return(domain.ErrURLAlreadyExists)
+
+	svc, err := New(cfg, repo)
+	assert.NoError(t, err)
+
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "hotsale25", time.Time{})
+	assert.ErrorIs(t, err, domain.ErrURLAlreadyExists)
+	assert.Nil(t, u)
+}
+
+func TestShortenBadAlias(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	svc, err := New(cfg, repositories.NewMemory())
+	assert.NoError(t, err)
+
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "hot-sale", time.Time{})
+	assert.ErrorIs(t, err, domain.ErrInvalidId)
+	assert.Nil(t, u)
+
+	u, err = svc.Shorten(ctx, validURL.String(), validAuthor, "healthz", time.Time{})
+	assert.ErrorIs(t, err, domain.ErrReservedId)
+	assert.Nil(t, u)
+}
+
+func TestRedirectExpired(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	repo := mockDomain.NewMockURLRepository(t)
+
+	repo.On("Get", mock.Anything, mock.Anything).Return(&domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now().Add(-2 * time.Hour),
+		Enabled:   true,
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}, nil)
+
+	svc, err := New(cfg, repo)
+	assert.NoError(t, err)
+
+	upstream, err := svc.Redirect(ctx, validId, domain.Visit{})
+	assert.ErrorIs(t, err, domain.ErrURLExpired)
+	assert.Equal(t, "", upstream)
+}
+
+func TestShortenWithExpiration(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	svc, err := New(cfg, repositories.NewMemory())
+	assert.NoError(t, err)
+
+	// REF: expiration in the past is rejected
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Now().Add(-time.Minute))
+	assert.ErrorIs(t, err, domain.ErrExpiresBeforeCreation)
+	assert.Nil(t, u)
+
+	expiresAt := time.Now().Add(time.Hour)
+	u, err = svc.Shorten(ctx, validURL.String(), validAuthor, "", expiresAt)
+	assert.NoError(t, err)
+
+	item, err := svc.Fetch(ctx, u.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, expiresAt.Unix(), item.ExpiresAt.Unix())
+
+	upstream, err := svc.Redirect(ctx, u.Path, domain.Visit{})
+	assert.NoError(t, err)
+	assert.Equal(t, validURL.String(), upstream)
+}
+
+func TestListClampsLimit(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	repo := mockDomain.NewMockURLRepository(t)
+
+	repo.On("List", mock.Anything, domain.URLFilter{}, "", cfg.List.DefaultLimit).Return(&domain.URLPage{}, nil).Once()
+	repo.On("List", mock.Anything, domain.URLFilter{}, "", cfg.List.MaxLimit).Return(&domain.URLPage{}, nil).Once()
+
+	svc, err := New(cfg, repo)
+	assert.NoError(t, err)
+
+	_, err = svc.List(ctx, domain.URLFilter{}, "", 0)
+	assert.NoError(t, err)
+
+	_, err = svc.List(ctx, domain.URLFilter{}, "", cfg.List.MaxLimit*10)
+	assert.NoError(t, err)
+}
+
+func TestUpdateKeepsRevisions(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	svc, err := New(cfg, repositories.NewMemory())
+	assert.NoError(t, err)
+
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
+	assert.NoError(t, err)
+
+	newUpstream := "https://go.dev/"
+	updated, err := svc.Update(ctx, u.Path, validAuthor, URLChanges{Upstream: &newUpstream})
+	assert.NoError(t, err)
+	assert.Equal(t, newUpstream, updated.Upstream.String())
+	assert.Equal(t, int64(1), updated.Version)
+
+	upstream, err := svc.Redirect(ctx, u.Path, domain.Visit{})
+	assert.NoError(t, err)
+	assert.Equal(t, newUpstream, upstream)
+
+	revisions, err := svc.Revisions(ctx, u.Path)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+	assert.Equal(t, validURL.String(), revisions[0].Upstream.String())
+	assert.Equal(t, int64(0), revisions[0].Version)
+	assert.Equal(t, validAuthor, revisions[0].ChangedBy)
+
+	// REF: a stale version is rejected
+	staleVersion := int64(0)
+	disabled := false
+	_, err = svc.Update(ctx, u.Path, validAuthor, URLChanges{Enabled: &disabled, Version: &staleVersion})
+	assert.ErrorIs(t, err, domain.ErrVersionConflict)
+
+	// REF: invalid destinations are rejected
+	badUpstream := invalidURL.String()
+	_, err = svc.Update(ctx, u.Path, validAuthor, URLChanges{Upstream: &badUpstream})
+	assert.ErrorIs(t, err, domain.ErrInvalidURL)
+
+	_, err = svc.Update(ctx, validId, validAuthor, URLChanges{Enabled: &disabled})
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestShortenAppliesURLPolicy(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	cfg.BaseUrl = baseURL.String()
+	cfg.MaxLength = 64
+	cfg.Policy.DenyHosts = []string{"*.evil.example"}
+	svc, err := New(cfg, repositories.NewMemory())
+	assert.NoError(t, err)
+
+	cases := map[string]error{
+		"https://base.url/someId":                                domain.ErrSelfReferencingURL,
+		"https://bit.ly/abc":                                     domain.ErrShortenerURL,
+		"https://www.evil.example/":                              domain.ErrHostDenied,
+		"https://opentelemetry.io/" + strings.Repeat(validId, 8): domain.ErrURLTooLong,
+	}
+
+	for upstream, expected := range cases {
+		u, err := svc.Shorten(ctx, upstream, validAuthor, "", time.Time{})
+		assert.ErrorIs(t, err, expected, upstream)
+		assert.Nil(t, u)
+	}
+
+	// REF: updates cannot bypass the policy either
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
+	assert.NoError(t, err)
+
+	selfUpstream := baseURL.JoinPath(validId).String()
+	_, err = svc.Update(ctx, u.Path, validAuthor, URLChanges{Upstream: &selfUpstream})
+	assert.ErrorIs(t, err, domain.ErrSelfReferencingURL)
+}
+
+type trackedClick struct {
+	urlID string
+	visit domain.Visit
+}
+
+type sliceTracker struct {
+	clicks *[]trackedClick
+}
+
+func (s sliceTracker) Track(urlID string, _ time.Time, visit domain.Visit) {
+	*s.clicks = append(*s.clicks, trackedClick{urlID, visit})
+}
+
+func TestRedirectTracksVisits(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	clicks := []trackedClick{}
+	svc, err := New(cfg, repositories.NewMemory(), WithClickTracker(sliceTracker{&clicks}))
+	assert.NoError(t, err)
+
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
+	assert.NoError(t, err)
+
+	visit := domain.Visit{Referrer: "https://t.co/", UserAgent: "curl/8.0"}
+	_, err = svc.Redirect(ctx, u.Path, visit)
+	assert.NoError(t, err)
+
+	// REF: failed redirects are not clicks
+	_, err = svc.Redirect(ctx, "missing", visit)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	assert.Equal(t, []trackedClick{{u.Path, visit}}, clicks)
+}
@@ -0,0 +1,147 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/o11y/semconv"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	StrategyRandom    = "random"
+	StrategyCounter   = "counter"
+	StrategySnowflake = "snowflake"
+	StrategyHashids   = "hashids"
+)
+
+// NewIDGenerator builds the generator selected by cfg.Hasher.Strategy, drawing
+// identifiers from ids. The sequence repository is only required by counter
+// and hashids
+func NewIDGenerator(cfg config.AppConfig, ids validators.IdAlphabet, urlRepo domain.URLRepository, seqRepo domain.SequenceRepository) (IDGenerator, error) {
+	alphabet := ids.Generated()
+	switch cfg.Hasher.Strategy {
+	case StrategyRandom, "":
+		return newRandomGenerator(urlRepo, alphabet, cfg.Hasher.Length, cfg.Hasher.RandomMaxValue, cfg.Hasher.MaxRounds), nil
+	case StrategyCounter:
+		if seqRepo == nil {
+			return nil, domain.ErrMissingSequence
+		}
+		return &counterGenerator{
+			seq:      newLeasedSequence(seqRepo, StrategyCounter, cfg.Hasher.BlockSize),
+			alphabet: alphabet,
+			length:   cfg.Hasher.Length,
+		}, nil
+	case StrategyHashids:
+		if seqRepo == nil {
+			return nil, domain.ErrMissingSequence
+		}
+		return newHashidsGenerator(newLeasedSequence(seqRepo, StrategyHashids, cfg.Hasher.BlockSize), alphabet, cfg.Hasher.Salt, cfg.Hasher.Length), nil
+	case StrategySnowflake:
+		return newSnowflakeGenerator(cfg.Hasher.NodeId, alphabet, cfg.Hasher.Length)
+	}
+
+	return nil, domain.ErrUnknownGenerator
+}
+
+// traceGenerated reports how much work an identifier took, rounds meaning
+// repository round trips such as collision probes or block leases
+func traceGenerated(ctx context.Context, strategy string, rounds int64, id string) {
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String(semconv.HasherStrategy, strategy),
+		attribute.Int64(semconv.HasherRounds, rounds),
+		attribute.Int(semconv.HasherLength, len(id)),
+	)
+}
+
+// encodeId writes value in the base of alphabet, left padded with its zero
+// digit up to length characters
+func encodeId(value uint64, alphabet string, length int) string {
+	base := uint64(len(alphabet))
+	digits := []byte{}
+	for {
+		digits = append(digits, alphabet[value%base])
+		value /= base
+		if value == 0 {
+			break
+		}
+	}
+
+	for len(digits) < length {
+		digits = append(digits, alphabet[0])
+	}
+
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+
+	return string(digits)
+}
+
+// randomGenerator draws random identifiers and probes the repository for
+// collisions up to maxRounds times
+type randomGenerator struct {
+	urlRepo   domain.URLRepository
+	alphabet  string
+	length    int
+	maxValue  uint64
+	maxRounds uint64
+}
+
+// newRandomGenerator draws from every identifier of exactly length
+// characters, or below maxValue when length is zero
+func newRandomGenerator(urlRepo domain.URLRepository, alphabet string, length int, maxValue uint64, maxRounds uint64) randomGenerator {
+	if length > 0 {
+		maxValue = 1
+		for range length {
+			if maxValue > math.MaxUint64/uint64(len(alphabet)) {
+				maxValue = math.MaxUint64
+				break
+			}
+			maxValue *= uint64(len(alphabet))
+		}
+	}
+
+	return randomGenerator{
+		urlRepo:   urlRepo,
+		alphabet:  alphabet,
+		length:    length,
+		maxValue:  maxValue,
+		maxRounds: maxRounds,
+	}
+}
+
+func (g randomGenerator) Next(ctx context.Context) (string, error) {
+	currentRounds := uint64(0)
+	generated := ""
+	resultErr := error(nil)
+
+	for currentRounds < g.maxRounds {
+		generated = encodeId(rand.Uint64N(g.maxValue), g.alphabet, g.length)
+		_, resultErr = g.urlRepo.Get(ctx, generated)
+
+		// REF: already exists
+		if resultErr == nil {
+			currentRounds++
+			continue
+		}
+
+		// REF: does not exist
+		if errors.Is(resultErr, domain.ErrURLNotFound) {
+			traceGenerated(ctx, StrategyRandom, int64(currentRounds), generated)
+			return generated, nil
+		}
+
+		if resultErr != nil {
+			break
+		}
+	}
+
+	return "", errors.Join(domain.ErrUnavailableRepo, resultErr)
+}
@@ -0,0 +1,128 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+// leasedSequence serves values from a block leased from the repository, only
+// going back to it once the block is exhausted. Values left in a block when
+// the process exits are never used
+type leasedSequence struct {
+	mu        sync.Mutex
+	repo      domain.SequenceRepository
+	name      string
+	blockSize uint64
+	next      uint64
+	end       uint64
+}
+
+func newLeasedSequence(repo domain.SequenceRepository, name string, blockSize uint64) *leasedSequence {
+	if blockSize == 0 {
+		blockSize = 1
+	}
+
+	return &leasedSequence{repo: repo, name: name, blockSize: blockSize}
+}
+
+// Next returns the next value and how many leases it took, 0 or 1
+func (s *leasedSequence) Next(ctx context.Context) (uint64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	leases := int64(0)
+	if s.next == s.end {
+		start, err := s.repo.Lease(ctx, s.name, s.blockSize)
+		if err != nil {
+			return 0, 0, errors.Join(domain.ErrUnavailableRepo, err)
+		}
+
+		s.next, s.end = start, start+s.blockSize
+		leases++
+	}
+
+	value := s.next
+	s.next++
+	return value, leases, nil
+}
+
+// counterGenerator encodes a shared sequence in the configured alphabet,
+// yielding the shortest possible identifiers without collision probes
+type counterGenerator struct {
+	seq      *leasedSequence
+	alphabet string
+	length   int
+}
+
+func (g *counterGenerator) Next(ctx context.Context) (string, error) {
+	value, leases, err := g.seq.Next(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	id := encodeId(value, g.alphabet, g.length)
+	traceGenerated(ctx, StrategyCounter, leases, id)
+	return id, nil
+}
+
+// hashidsGenerator encodes a shared sequence like hashids does, so consecutive
+// identifiers do not look consecutive: a salted alphabet, reshuffled for every
+// value by a lottery character that prefixes the identifier
+type hashidsGenerator struct {
+	seq       *leasedSequence
+	salt      string
+	alphabet  string
+	minLength int
+}
+
+func newHashidsGenerator(seq *leasedSequence, alphabet string, salt string, minLength int) *hashidsGenerator {
+	return &hashidsGenerator{
+		seq:       seq,
+		salt:      salt,
+		alphabet:  consistentShuffle(alphabet, salt),
+		minLength: minLength,
+	}
+}
+
+func (g *hashidsGenerator) Next(ctx context.Context) (string, error) {
+	value, leases, err := g.seq.Next(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	id := g.encode(value)
+	traceGenerated(ctx, StrategyHashids, leases, id)
+	return id, nil
+}
+
+// encode is injective: the lottery character determines the alphabet, and
+// padding uses that alphabet's zero digit as leading zeros
+func (g *hashidsGenerator) encode(value uint64) string {
+	base := uint64(len(g.alphabet))
+	lottery := g.alphabet[value%base]
+	alphabet := consistentShuffle(g.alphabet, (string(lottery) + g.salt + g.alphabet)[:len(g.alphabet)])
+
+	return string(lottery) + encodeId(value, alphabet, g.minLength-1)
+}
+
+// consistentShuffle is the hashids shuffle, a deterministic permutation of
+// alphabet driven by salt
+func consistentShuffle(alphabet string, salt string) string {
+	if salt == "" {
+		return alphabet
+	}
+
+	result := []byte(alphabet)
+	for i, v, p := len(result)-1, 0, 0; i > 0; i-- {
+		v %= len(salt)
+		p += int(salt[v])
+		j := (int(salt[v]) + v + p) % i
+		result[i], result[j] = result[j], result[i]
+		v++
+	}
+
+	return string(result)
+}
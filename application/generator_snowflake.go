@@ -0,0 +1,67 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	snowflakeMaxNode      = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSequence  = 1<<snowflakeSequenceBits - 1
+)
+
+// snowflakeEpoch keeps timestamps small, 41 bits of milliseconds last ~69 years
+var snowflakeEpoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// snowflakeGenerator composes milliseconds since epoch, a node identifier and
+// a per-millisecond sequence, so replicas never collide without coordination
+type snowflakeGenerator struct {
+	mu       sync.Mutex
+	node     uint64
+	alphabet string
+	length   int
+	lastMs   int64
+	sequence uint64
+	now      func() time.Time
+}
+
+func newSnowflakeGenerator(node uint16, alphabet string, length int) (*snowflakeGenerator, error) {
+	if node > snowflakeMaxNode {
+		return nil, fmt.Errorf("%w: snowflake node %d out of range", domain.ErrUnknownGenerator, node)
+	}
+
+	return &snowflakeGenerator{node: uint64(node), alphabet: alphabet, length: length, now: time.Now}, nil
+}
+
+func (g *snowflakeGenerator) Next(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// REF: a clock going backwards keeps using the last millisecond
+	waits := int64(0)
+	ms := max(g.now().Sub(snowflakeEpoch).Milliseconds(), g.lastMs)
+	if ms == g.lastMs {
+		g.sequence = (g.sequence + 1) & snowflakeMaxSequence
+
+		// REF: sequence exhausted, spin until the next millisecond
+		for g.sequence == 0 && ms <= g.lastMs {
+			waits++
+			time.Sleep(100 * time.Microsecond)
+			ms = g.now().Sub(snowflakeEpoch).Milliseconds()
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMs = ms
+
+	value := uint64(ms)<<(snowflakeNodeBits+snowflakeSequenceBits) | g.node<<snowflakeSequenceBits | g.sequence
+	id := encodeId(value, g.alphabet, g.length)
+	traceGenerated(ctx, StrategySnowflake, waits, id)
+	return id, nil
+}
@@ -0,0 +1,215 @@
+package application
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	mockDomain "github.com/neonmei/challenge_urlshortener/mocks/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fixedGenerator replays identifiers, for testing how the service reacts to them
+type fixedGenerator struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (g *fixedGenerator) Next(context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := g.ids[0]
+	g.ids = g.ids[1:]
+	return id, nil
+}
+
+func TestGeneratorsProduceUniqueValidIds(t *testing.T) {
+	ctx := context.Background()
+
+	for _, strategy := range []string{StrategyRandom, StrategyCounter, StrategySnowflake, StrategyHashids} {
+		cfg := config.Load()
+		cfg.Hasher.Strategy = strategy
+		cfg.Hasher.BlockSize = 10
+		g, err := NewIDGenerator(cfg, validators.IdAlphabet{}, repositories.NewMemory(), repositories.NewMemorySequences())
+		assert.NoError(t, err, strategy)
+
+		seen := map[string]bool{}
+		for range 5000 {
+			id, err := g.Next(ctx)
+			assert.NoError(t, err, strategy)
+			assert.NoError(t, validators.ValidateGeneratedId(id, validators.IdAlphabet{}), strategy)
+			assert.False(t, seen[id], strategy)
+			seen[id] = true
+		}
+	}
+}
+
+func TestGeneratorConfigErrors(t *testing.T) {
+	cfg := config.Load()
+
+	cfg.Hasher.Strategy = "uuid"
+	_, err := NewIDGenerator(cfg, validators.IdAlphabet{}, repositories.NewMemory(), nil)
+	assert.ErrorIs(t, err, domain.ErrUnknownGenerator)
+
+	cfg.Hasher.Strategy = StrategyCounter
+	_, err = NewIDGenerator(cfg, validators.IdAlphabet{}, repositories.NewMemory(), nil)
+	assert.ErrorIs(t, err, domain.ErrMissingSequence)
+
+	cfg.Hasher.Strategy = StrategySnowflake
+	cfg.Hasher.NodeId = 1024
+	_, err = NewIDGenerator(cfg, validators.IdAlphabet{}, repositories.NewMemory(), nil)
+	assert.ErrorIs(t, err, domain.ErrUnknownGenerator)
+}
+
+func TestCounterLeasesBlocksWithoutProbes(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	cfg.Hasher.Strategy = StrategyCounter
+	cfg.Hasher.BlockSize = 100
+
+	// REF: a mock without expectations fails on any probe
+	urlRepo := mockDomain.NewMockURLRepository(t)
+	seqRepo := repositories.NewMemorySequences()
+	g, err := NewIDGenerator(cfg, validators.IdAlphabet{}, urlRepo, seqRepo)
+	assert.NoError(t, err)
+
+	for expected := range 150 {
+		id, err := g.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, new(big.Int).SetInt64(int64(expected)).Text(62), id)
+	}
+
+	// REF: two blocks were leased
+	next, err := seqRepo.Lease(ctx, StrategyCounter, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(200), next)
+}
+
+func TestHashidsIsSaltedAndPadded(t *testing.T) {
+	seq := newLeasedSequence(repositories.NewMemorySequences(), StrategyHashids, 10)
+	a := newHashidsGenerator(seq, validators.AlphabetBase62, "pepper", 6)
+	b := newHashidsGenerator(seq, validators.AlphabetBase62, "salt", 6)
+
+	assert.Len(t, a.encode(1), 6)
+	assert.NotEqual(t, a.encode(1), b.encode(1))
+	assert.Equal(t, a.encode(42), a.encode(42))
+	assert.Greater(t, len(a.encode(1<<60)), 6)
+}
+
+func TestSnowflakeNodesDoNotCollide(t *testing.T) {
+	ctx := context.Background()
+	frozen := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	seen := map[string]bool{}
+
+	for node := range uint16(4) {
+		g, err := newSnowflakeGenerator(node, validators.AlphabetBase62, 0)
+		assert.NoError(t, err)
+		g.now = func() time.Time { return frozen }
+
+		for range 100 {
+			id, err := g.Next(ctx)
+			assert.NoError(t, err)
+			assert.False(t, seen[id])
+			seen[id] = true
+		}
+	}
+}
+
+func TestShortenSkipsReservedAndTakenIds(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	repo := repositories.NewMemory()
+	svc, err := New(cfg, repo, WithIDGenerator(&fixedGenerator{ids: []string{"taken", "api", "fresh"}}))
+	assert.NoError(t, err)
+
+	_, err = svc.Shorten(ctx, validURL.String(), validAuthor, "taken", time.Time{})
+	assert.NoError(t, err)
+
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", u.Path)
+}
+
+func TestShortenGivesUpOnRepeatedConflicts(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	cfg.Hasher.MaxRounds = 2
+	repo := mockDomain.NewMockURLRepository(t)
+	repo.On("Save", mock.Anything, mock.Anything).Return(domain.ErrURLAlreadyExists).Twice()
+	svc, err := New(cfg, repo, WithIDGenerator(&fixedGenerator{ids: []string{"a", "b", "c"}}))
+	assert.NoError(t, err)
+
+	// REF: the caller did not pick the identifier, it is not a conflict of theirs
+	_, err = svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+	assert.ErrorIs(t, err, domain.ErrIdsExhausted)
+	assert.NotErrorIs(t, err, domain.ErrURLAlreadyExists)
+}
+
+func TestGeneratorsHonorAlphabetAndLength(t *testing.T) {
+	ctx := context.Background()
+
+	for _, strategy := range []string{StrategyRandom, StrategyCounter, StrategySnowflake, StrategyHashids} {
+		cfg := config.Load()
+		cfg.Hasher.Strategy = strategy
+		cfg.Hasher.Length = 8
+		noLookalikes, err := validators.NewIdAlphabet("nolookalikes")
+		assert.NoError(t, err)
+		g, err := NewIDGenerator(cfg, noLookalikes, repositories.NewMemory(), repositories.NewMemorySequences())
+		assert.NoError(t, err, strategy)
+
+		for range 100 {
+			id, err := g.Next(ctx)
+			assert.NoError(t, err, strategy)
+			assert.GreaterOrEqual(t, len(id), 8, strategy)
+			assert.NotContains(t, id, "0", strategy)
+			for _, r := range id {
+				assert.Contains(t, validators.AlphabetNoLookalikes, string(r), strategy)
+			}
+		}
+	}
+
+	cfg := config.Load()
+	cfg.Hasher.Alphabet = "aa"
+	_, err := New(cfg, repositories.NewMemory())
+	assert.ErrorIs(t, err, domain.ErrInvalidAlphabet)
+}
+
+func TestRandomLengthIsExact(t *testing.T) {
+	ctx := context.Background()
+	g := newRandomGenerator(repositories.NewMemory(), "ab", 3, 0, 4)
+
+	for range 100 {
+		id, err := g.Next(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, id, 3)
+	}
+}
+
+func TestShortenSkipsBlockedIds(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	cfg.Hasher.BlocklistFile = filepath.Join(t.TempDir(), "blocklist.txt")
+	assert.NoError(t, os.WriteFile(cfg.Hasher.BlocklistFile, []byte("# words\nbad\n\nugly\n"), 0o600))
+
+	svc, err := New(cfg, repositories.NewMemory(), WithIDGenerator(&fixedGenerator{ids: []string{"xBADx", "u6ly", "fine"}}))
+	assert.NoError(t, err)
+
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "fine", u.Path)
+
+	cfg.Hasher.BlocklistFile = filepath.Join(t.TempDir(), "missing.txt")
+	_, err = New(cfg, repositories.NewMemory())
+	assert.Error(t, err)
+}
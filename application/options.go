@@ -0,0 +1,79 @@
+package application
+
+import (
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+// Option customizes optional collaborators of the service
+type Option func(*shortenerService)
+
+// WithClickRecorder records every successful redirect for analytics
+func WithClickRecorder(r ClickRecorder) Option {
+	return func(e *shortenerService) {
+		e.clicks = r
+	}
+}
+
+// WithClickTracker captures the visit behind every successful redirect
+func WithClickTracker(t ClickTracker) Option {
+	return func(e *shortenerService) {
+		e.tracker = t
+	}
+}
+
+// WithBotClassifier flags redirects from bots in metrics, hits and clicks
+func WithBotClassifier(b BotClassifier) Option {
+	return func(e *shortenerService) {
+		e.bots = b
+	}
+}
+
+// WithTrendingTracker feeds every successful redirect to trending links
+func WithTrendingTracker(t TrendingTracker) Option {
+	return func(e *shortenerService) {
+		e.trending = t
+		e.trendingOn = true
+	}
+}
+
+// WithHitsRepository enables per-link stats queries
+func WithHitsRepository(r domain.HitsRepository) Option {
+	return func(e *shortenerService) {
+		e.hitsRepo = r
+	}
+}
+
+// WithSequenceRepository backs the counter and hashids generators
+func WithSequenceRepository(r domain.SequenceRepository) Option {
+	return func(e *shortenerService) {
+		e.seqRepo = r
+	}
+}
+
+// WithIDGenerator overrides the generator selected by configuration
+func WithIDGenerator(g IDGenerator) Option {
+	return func(e *shortenerService) {
+		e.ids = g
+	}
+}
+
+type noopRecorder struct{}
+
+func (noopRecorder) Record(string, time.Time, domain.Visit) {}
+
+type noopClassifier struct{}
+
+func (noopClassifier) IsBot(domain.Visit) bool { return false }
+
+type noopTracker struct{}
+
+func (noopTracker) Track(string, time.Time, domain.Visit) {}
+
+// noopTrending tracks nothing until WithTrendingTracker replaces it
+type noopTrending struct{}
+
+func (noopTrending) Hit(string, time.Time) {}
+
+func (noopTrending) Top(time.Time, time.Duration, int) []domain.TrendingURL { return nil }
@@ -3,13 +3,67 @@ package application
 import (
 	"context"
 	"net/url"
+	"time"
 
 	"github.com/neonmei/challenge_urlshortener/domain"
 )
 
 type Service interface {
-	Redirect(ctx context.Context, urlID string) (string, error)
-	Shorten(ctx context.Context, longURL string, author string) (*url.URL, error)
+	Redirect(ctx context.Context, urlID string, visit domain.Visit) (string, error)
+	Shorten(ctx context.Context, longURL string, author string, alias string, expiresAt time.Time) (*url.URL, error)
 	Delete(ctx context.Context, urlID string) error
+	Restore(ctx context.Context, urlID string) error
+	Purge(ctx context.Context, urlID string) error
 	Fetch(ctx context.Context, urlID string) (*domain.ShortURL, error)
+	List(ctx context.Context, filter domain.URLFilter, cursor string, limit int) (*domain.URLPage, error)
+	Update(ctx context.Context, urlID string, author string, changes URLChanges) (*domain.ShortURL, error)
+	Revisions(ctx context.Context, urlID string) ([]domain.Revision, error)
+	Stats(ctx context.Context, urlID string, g domain.Granularity, from time.Time, to time.Time, excludeBots bool) (*domain.URLStats, error)
+	Trending(ctx context.Context, window time.Duration, limit int) (*TrendingPage, error)
+}
+
+// TrendingPage holds the most redirected URLs within Window, which may
+// differ from the requested one once defaulted and capped
+type TrendingPage struct {
+	Window time.Duration
+	URLs   []domain.TrendingURL
+}
+
+// URLChanges holds the mutable fields of an URL, nil fields are left untouched
+type URLChanges struct {
+	Upstream *string
+	Enabled  *bool
+
+	// Version, when set, must match the current version of the URL
+	Version *int64
+}
+
+// IDGenerator produces identifiers for new short URLs, implementations must be
+// safe for concurrent use
+type IDGenerator interface {
+	Next(ctx context.Context) (string, error)
+}
+
+// BotClassifier tells crawlers and link preview fetchers apart from people,
+// implementations must be safe for concurrent use
+type BotClassifier interface {
+	IsBot(visit domain.Visit) bool
+}
+
+// ClickRecorder receives every successful redirect, implementations must not block
+type ClickRecorder interface {
+	Record(urlID string, at time.Time, visit domain.Visit)
+}
+
+// TrendingTracker follows the most redirected URLs, implementations must be
+// safe for concurrent use and Hit must not block
+type TrendingTracker interface {
+	Hit(urlID string, at time.Time)
+	Top(now time.Time, window time.Duration, limit int) []domain.TrendingURL
+}
+
+// ClickTracker receives the visit behind every successful redirect,
+// implementations must not block
+type ClickTracker interface {
+	Track(urlID string, at time.Time, visit domain.Visit)
 }
@@ -0,0 +1,98 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/sketch"
+)
+
+// Stats returns a dense series of hits, buckets without hits are zero, and
+// the unique visitors of the whole days it spans. Excluding bots leaves only
+// hits from people, bots are still reported apart
+func (e shortenerService) Stats(ctx context.Context, urlID string, g domain.Granularity, from time.Time, to time.Time, excludeBots bool) (*domain.URLStats, error) {
+	if e.hitsRepo == nil {
+		return nil, domain.ErrAnalyticsDisabled
+	}
+
+	if err := validators.ValidateId(urlID, e.alphabet); err != nil {
+		return nil, err
+	}
+
+	if err := validators.ValidateRange(g, from, to, e.cfg.Analytics.MaxBuckets); err != nil {
+		return nil, err
+	}
+
+	from, to = g.Bucket(from), g.Bucket(to)
+	stored, err := e.hitsRepo.Series(ctx, urlID, g, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	byStart := make(map[int64]domain.HitCount, len(stored))
+	for _, h := range stored {
+		bucket := byStart[h.Start.Unix()]
+		bucket.Hits += h.Hits
+		bucket.Bots += h.Bots
+		byStart[h.Start.Unix()] = bucket
+	}
+
+	series := []domain.HitCount{}
+	for start := from; !start.After(to); start = start.Add(g.Duration()) {
+		bucket := byStart[start.Unix()]
+		if excludeBots {
+			bucket.Hits -= bucket.Bots
+		}
+
+		series = append(series, domain.HitCount{
+			URLId:       urlID,
+			Granularity: g,
+			Start:       start,
+			Hits:        bucket.Hits,
+			Bots:        bucket.Bots,
+		})
+	}
+
+	stats := &domain.URLStats{Series: series, DailyVisitors: map[int64]int64{}}
+	if err := e.uniqueVisitors(ctx, stats, urlID, from, to); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// uniqueVisitors estimates every day apart and the range by merging them,
+// adding up daily estimates would count returning visitors once per day
+func (e shortenerService) uniqueVisitors(ctx context.Context, stats *domain.URLStats, urlID string, from time.Time, to time.Time) error {
+	sketches, err := e.hitsRepo.Visitors(ctx, urlID, domain.GranularityDay.Bucket(from), domain.GranularityDay.Bucket(to))
+	if err != nil {
+		return err
+	}
+
+	var union *sketch.HyperLogLog
+	for _, s := range sketches {
+		daily, err := sketch.UnmarshalHyperLogLog(s.Sketch)
+		if err != nil {
+			return errors.Join(domain.ErrRepoSchema, err)
+		}
+		stats.DailyVisitors[s.Day.Unix()] = int64(daily.Estimate())
+
+		if union == nil {
+			union = daily
+			continue
+		}
+
+		if err := union.Merge(daily); err != nil {
+			return errors.Join(domain.ErrRepoSchema, err)
+		}
+	}
+
+	if union != nil {
+		stats.UniqueVisitors = int64(union.Estimate())
+	}
+
+	return nil
+}
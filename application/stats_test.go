@@ -0,0 +1,155 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories"
+	"github.com/neonmei/challenge_urlshortener/platform/sketch"
+	"github.com/stretchr/testify/assert"
+)
+
+type syncRecorder struct {
+	repo domain.HitsRepository
+}
+
+func (s syncRecorder) Record(urlID string, at time.Time, visit domain.Visit) {
+	count := domain.HitCount{URLId: urlID, Granularity: domain.GranularityHour, Start: at, Hits: 1}
+	if visit.Bot {
+		count.Bots = 1
+	}
+	_ = s.repo.AddHits(context.Background(), []domain.HitCount{count})
+	if visit.Bot {
+		return
+	}
+
+	visitors, _ := sketch.NewHyperLogLog(12)
+	visitors.AddString(visit.UserAgent)
+	data, _ := visitors.MarshalBinary()
+	_ = s.repo.MergeVisitors(context.Background(), []domain.VisitorSketch{
+		{URLId: urlID, Day: domain.GranularityDay.Bucket(at), Sketch: data},
+	})
+}
+
+func TestStatsAfterRedirects(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	hits := repositories.NewMemoryHits()
+	svc, err := New(cfg, repositories.NewMemory(), WithHitsRepository(hits), WithClickRecorder(syncRecorder{hits}))
+	assert.NoError(t, err)
+
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
+	assert.NoError(t, err)
+
+	for _, userAgent := range []string{"curl/8.5.0", "curl/8.5.0", "Wget/1.21"} {
+		_, err := svc.Redirect(ctx, u.Path, domain.Visit{UserAgent: userAgent})
+		assert.NoError(t, err)
+	}
+
+	now := time.Now()
+	stats, err := svc.Stats(ctx, u.Path, domain.GranularityHour, now.Add(-2*time.Hour), now, false)
+	assert.NoError(t, err)
+
+	// REF: series is dense, empty buckets are zero
+	assert.Len(t, stats.Series, 3)
+	assert.Equal(t, int64(0), stats.Series[0].Hits)
+	assert.Equal(t, int64(0), stats.Series[1].Hits)
+	assert.Equal(t, int64(3), stats.Series[2].Hits)
+
+	// REF: refreshes by the same visitor are not unique visits
+	assert.Equal(t, int64(2), stats.UniqueVisitors)
+	assert.Equal(t, int64(2), stats.DailyVisitors[domain.GranularityDay.Bucket(now).Unix()])
+}
+
+type userAgentClassifier string
+
+func (c userAgentClassifier) IsBot(visit domain.Visit) bool {
+	return visit.UserAgent == string(c)
+}
+
+func TestStatsExcludesBots(t *testing.T) {
+	ctx := context.Background()
+	hits := repositories.NewMemoryHits()
+	svc, err := New(config.Load(), repositories.NewMemory(),
+		WithHitsRepository(hits),
+		WithClickRecorder(syncRecorder{hits}),
+		WithBotClassifier(userAgentClassifier("WhatsApp/2.23.20.0 A")),
+	)
+	assert.NoError(t, err)
+
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
+	assert.NoError(t, err)
+
+	for _, userAgent := range []string{"WhatsApp/2.23.20.0 A", "WhatsApp/2.23.20.0 A", "Mozilla/5.0"} {
+		_, err := svc.Redirect(ctx, u.Path, domain.Visit{UserAgent: userAgent})
+		assert.NoError(t, err)
+	}
+
+	now := time.Now()
+	stats, err := svc.Stats(ctx, u.Path, domain.GranularityHour, now, now, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Series[0].Hits)
+	assert.Equal(t, int64(2), stats.Series[0].Bots)
+
+	stats, err = svc.Stats(ctx, u.Path, domain.GranularityHour, now, now, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Series[0].Hits)
+	assert.Equal(t, int64(2), stats.Series[0].Bots)
+
+	// REF: preview fetchers are never visitors
+	assert.Equal(t, int64(1), stats.UniqueVisitors)
+}
+
+func TestStatsMergesDailyVisitors(t *testing.T) {
+	ctx := context.Background()
+	hits := repositories.NewMemoryHits()
+	svc, err := New(config.Load(), repositories.NewMemory(), WithHitsRepository(hits))
+	assert.NoError(t, err)
+
+	today := domain.GranularityDay.Bucket(time.Now())
+	yesterday := today.Add(-24 * time.Hour)
+	for day, visitors := range map[time.Time][]string{
+		yesterday: {"alice", "bob"},
+		today:     {"bob", "carol"},
+	} {
+		s, _ := sketch.NewHyperLogLog(12)
+		for _, v := range visitors {
+			s.AddString(v)
+		}
+		data, _ := s.MarshalBinary()
+		assert.NoError(t, hits.MergeVisitors(ctx, []domain.VisitorSketch{{URLId: validId, Day: day, Sketch: data}}))
+	}
+
+	stats, err := svc.Stats(ctx, validId, domain.GranularityDay, yesterday, today, false)
+	assert.NoError(t, err)
+
+	// REF: bob came back, the range has fewer visitors than the sum of its days
+	assert.Equal(t, int64(3), stats.UniqueVisitors)
+	assert.Equal(t, map[int64]int64{yesterday.Unix(): 2, today.Unix(): 2}, stats.DailyVisitors)
+}
+
+func TestStatsValidation(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	now := time.Now()
+
+	svc, err := New(cfg, repositories.NewMemory())
+	assert.NoError(t, err)
+	_, err = svc.Stats(ctx, validId, domain.GranularityHour, now.Add(-time.Hour), now, false)
+	assert.ErrorIs(t, err, domain.ErrAnalyticsDisabled)
+
+	svc, err = New(cfg, repositories.NewMemory(), WithHitsRepository(repositories.NewMemoryHits()))
+	assert.NoError(t, err)
+
+	_, err = svc.Stats(ctx, validId, "week", now.Add(-time.Hour), now, false)
+	assert.ErrorIs(t, err, domain.ErrInvalidGranularity)
+
+	_, err = svc.Stats(ctx, validId, domain.GranularityHour, now, now.Add(-time.Hour), false)
+	assert.ErrorIs(t, err, domain.ErrInvalidRange)
+
+	_, err = svc.Stats(ctx, validId, domain.GranularityMinute, now.Add(-365*24*time.Hour), now, false)
+	assert.ErrorIs(t, err, domain.ErrInvalidRange)
+}
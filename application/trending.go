@@ -0,0 +1,42 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+// Trending returns the most redirected URLs of the last window as seen by
+// this replica, bots included as they take traffic as well. Zero window and
+// limit pick the defaults, larger ones are capped
+func (e shortenerService) Trending(_ context.Context, window time.Duration, limit int) (*TrendingPage, error) {
+	if !e.trendingOn {
+		return nil, domain.ErrTrendingDisabled
+	}
+
+	if window < 0 {
+		return nil, domain.ErrInvalidWindow
+	}
+
+	if window == 0 {
+		window = e.cfg.Trending.DefaultWindow
+	}
+
+	if window > e.cfg.Trending.MaxWindow {
+		window = e.cfg.Trending.MaxWindow
+	}
+
+	if limit <= 0 {
+		limit = e.cfg.Trending.DefaultLimit
+	}
+
+	if limit > e.cfg.Trending.MaxLimit {
+		limit = e.cfg.Trending.MaxLimit
+	}
+
+	return &TrendingPage{
+		Window: window,
+		URLs:   e.trending.Top(time.Now(), window, limit),
+	}, nil
+}
@@ -0,0 +1,80 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+type trendingCall struct {
+	window time.Duration
+	limit  int
+}
+
+type fakeTrending struct {
+	hits  []string
+	calls []trendingCall
+}
+
+func (f *fakeTrending) Hit(urlID string, _ time.Time) {
+	f.hits = append(f.hits, urlID)
+}
+
+func (f *fakeTrending) Top(_ time.Time, window time.Duration, limit int) []domain.TrendingURL {
+	f.calls = append(f.calls, trendingCall{window, limit})
+	return []domain.TrendingURL{{URLId: validId, Hits: int64(len(f.hits))}}
+}
+
+func TestTrendingFedByRedirects(t *testing.T) {
+	ctx := context.Background()
+	trending := &fakeTrending{}
+	svc, err := New(config.Load(), repositories.NewMemory(), WithTrendingTracker(trending))
+	assert.NoError(t, err)
+
+	u, err := svc.Shorten(ctx, validURL.String(), validAuthor, "", time.Time{})
+	assert.NoError(t, err)
+
+	_, err = svc.Redirect(ctx, u.Path, domain.Visit{})
+	assert.NoError(t, err)
+
+	// REF: failed redirects take no traffic upstream
+	_, err = svc.Redirect(ctx, "missing", domain.Visit{})
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	assert.Equal(t, []string{u.Path}, trending.hits)
+}
+
+func TestTrendingLimits(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	trending := &fakeTrending{}
+	svc, err := New(cfg, repositories.NewMemory(), WithTrendingTracker(trending))
+	assert.NoError(t, err)
+
+	page, err := svc.Trending(ctx, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.Trending.DefaultWindow, page.Window)
+	assert.Len(t, page.URLs, 1)
+
+	page, err = svc.Trending(ctx, 24*time.Hour, 100000)
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.Trending.MaxWindow, page.Window)
+
+	assert.Equal(t, []trendingCall{
+		{cfg.Trending.DefaultWindow, cfg.Trending.DefaultLimit},
+		{cfg.Trending.MaxWindow, cfg.Trending.MaxLimit},
+	}, trending.calls)
+
+	_, err = svc.Trending(ctx, -time.Minute, 10)
+	assert.ErrorIs(t, err, domain.ErrInvalidWindow)
+
+	svc, err = New(cfg, repositories.NewMemory())
+	assert.NoError(t, err)
+	_, err = svc.Trending(ctx, time.Minute, 10)
+	assert.ErrorIs(t, err, domain.ErrTrendingDisabled)
+}
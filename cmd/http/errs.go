@@ -1,5 +1,41 @@
 package main
 
-import "errors"
+import (
+	"errors"
 
-var ErrHttpRequestDecode = errors.New("cannot decode request body")
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+)
+
+var (
+	ErrHttpRequestDecode = errors.New("cannot decode request body")
+	ErrHttpBadQuery      = errors.New("invalid query parameter")
+)
+
+// policyRules names each URL policy violation for API clients
+var policyRules = []struct {
+	err  error
+	rule string
+}{
+	{domain.ErrURLTooLong, "max_length"},
+	{domain.ErrUserinfoURL, "userinfo"},
+	{domain.ErrIPLiteralHost, "ip_literal"},
+	{domain.ErrHomographHost, "homograph"},
+	{domain.ErrSelfReferencingURL, "self_reference"},
+	{domain.ErrShortenerURL, "shortener"},
+	{domain.ErrHostDenied, "denylist"},
+	{domain.ErrHostNotAllowed, "allowlist"},
+	{domain.ErrInvalidURL, "invalid_url"},
+}
+
+func badRequest(err error) dtos.ErrorResponse {
+	response := dtos.ErrorResponse{Error: err.Error()}
+	for _, p := range policyRules {
+		if errors.Is(err, p.err) {
+			response.Rule = p.rule
+			break
+		}
+	}
+
+	return response
+}
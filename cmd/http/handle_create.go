@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/neonmei/challenge_urlshortener/application"
+	"github.com/neonmei/challenge_urlshortener/domain"
 	"github.com/neonmei/challenge_urlshortener/platform/dtos"
 )
 
@@ -18,10 +19,22 @@ func handleCreate(e application.Service, c *gin.Context) {
 		return
 	}
 
-	shortURL, err := e.Shorten(c.Request.Context(), createRequest.Upstream, c.GetString(UserContextKey))
+	shortURL, err := e.Shorten(c.Request.Context(), createRequest.Upstream, c.GetString(UserContextKey), createRequest.Alias, createRequest.Expiration())
+	if errors.Is(err, domain.ErrURLAlreadyExists) {
+		_ = c.Error(err)
+		c.JSON(http.StatusConflict, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrUnavailableRepo) {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
 	if err != nil {
 		_ = c.Error(err)
-		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		c.JSON(http.StatusBadRequest, badRequest(err))
 		return
 	}
 
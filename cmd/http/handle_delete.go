@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/neonmei/challenge_urlshortener/application"
@@ -11,15 +12,20 @@ import (
 	"github.com/neonmei/challenge_urlshortener/platform/dtos"
 )
 
-func handleDelete(e application.Service, c *gin.Context) {
+func handleDelete(e application.Service, ids validators.IdAlphabet, c *gin.Context) {
 	urlId := c.Param("url_id")
-	if err := validators.ValidateId(urlId); err != nil {
+	if err := validators.ValidateId(urlId, ids); err != nil {
 		_ = c.Error(err)
 		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	err := e.Delete(c.Request.Context(), urlId)
+	deleteFunc := e.Delete
+	if purge, _ := strconv.ParseBool(c.Query("purge")); purge {
+		deleteFunc = e.Purge
+	}
+
+	err := deleteFunc(c.Request.Context(), urlId)
 	if err == nil {
 		c.Status(http.StatusNoContent)
 		return
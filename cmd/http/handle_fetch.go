@@ -11,9 +11,9 @@ import (
 	"github.com/neonmei/challenge_urlshortener/platform/dtos"
 )
 
-func handleFetch(e application.Service, c *gin.Context) {
+func handleFetch(e application.Service, ids validators.IdAlphabet, c *gin.Context) {
 	urlId := c.Param("url_id")
-	if err := validators.ValidateId(urlId); err != nil {
+	if err := validators.ValidateId(urlId, ids); err != nil {
 		_ = c.Error(err)
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/neonmei/challenge_urlshortener/application"
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+)
+
+func handleList(e application.Service, c *gin.Context) {
+	filter, err := parseURLFilter(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	limit := 0
+	if raw, found := c.GetQuery("limit"); found {
+		if limit, err = strconv.Atoi(raw); err != nil {
+			_ = c.Error(err)
+			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: ErrHttpBadQuery.Error()})
+			return
+		}
+	}
+
+	page, err := e.List(c.Request.Context(), filter, c.Query("cursor"), limit)
+	if err == nil {
+		c.JSON(http.StatusOK, dtos.FromPage(*page))
+		return
+	}
+
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	_ = c.Error(err)
+	c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+}
+
+func parseURLFilter(c *gin.Context) (domain.URLFilter, error) {
+	filter := domain.URLFilter{
+		CreatedBy:    c.Query("created_by"),
+		UpstreamHost: c.Query("host"),
+	}
+
+	if raw, found := c.GetQuery("enabled"); found {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter, errors.Join(ErrHttpBadQuery, err)
+		}
+		filter.Enabled = &enabled
+	}
+
+	var err error
+	if filter.CreatedAfter, err = unixQuery(c, "created_after", time.Time{}); err != nil {
+		return filter, errors.Join(ErrHttpBadQuery, err)
+	}
+
+	if filter.CreatedBefore, err = unixQuery(c, "created_before", time.Time{}); err != nil {
+		return filter, errors.Join(ErrHttpBadQuery, err)
+	}
+
+	return filter, nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"net/http"
+	"net/netip"
 
 	"github.com/gin-gonic/gin"
 	"github.com/neonmei/challenge_urlshortener/application"
@@ -12,17 +13,18 @@ import (
 
 const (
 	StatusNotFoundTemplate       = "404.html"
+	StatusGoneTemplate           = "410.html"
 	InternalServiceErrorTemplate = "500.html"
 )
 
-func handleRedirect(e application.Service, c *gin.Context) {
+func handleRedirect(e application.Service, ids validators.IdAlphabet, c *gin.Context) {
 	urlId := c.Param("url_id")
-	if err := validators.ValidateId(urlId); err != nil {
+	if err := validators.ValidateId(urlId, ids); err != nil {
 		c.HTML(http.StatusNotFound, StatusNotFoundTemplate, nil)
 		_ = c.Error(err)
 	}
 
-	newURL, err := e.Redirect(c.Request.Context(), urlId)
+	newURL, err := e.Redirect(c.Request.Context(), urlId, visitOf(c))
 	if err == nil {
 		c.Redirect(http.StatusFound, newURL)
 		return
@@ -34,6 +36,39 @@ func handleRedirect(e application.Service, c *gin.Context) {
 		return
 	}
 
+	if errors.Is(err, domain.ErrURLExpired) {
+		c.HTML(http.StatusGone, StatusGoneTemplate, nil)
+		_ = c.Error(err)
+		return
+	}
+
 	c.HTML(http.StatusInternalServerError, InternalServiceErrorTemplate, nil)
 	_ = c.Error(err)
 }
+
+// purposeHeaders announce prefetches and previews, browsers disagree on which
+var purposeHeaders = []string{"Sec-Purpose", "Purpose", "X-Purpose", "X-Moz"}
+
+// visitOf collects what the request tells about the visitor, the client
+// address honors forwarding headers from trusted proxies
+func visitOf(c *gin.Context) domain.Visit {
+	clientIP, _ := netip.ParseAddr(c.ClientIP())
+
+	visit := domain.Visit{
+		Referrer:       c.Request.Referer(),
+		UserAgent:      c.Request.UserAgent(),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		Accept:         c.GetHeader("Accept"),
+		Method:         c.Request.Method,
+		ClientIP:       clientIP,
+	}
+
+	for _, header := range purposeHeaders {
+		if purpose := c.GetHeader(header); purpose != "" {
+			visit.Purpose = purpose
+			break
+		}
+	}
+
+	return visit
+}
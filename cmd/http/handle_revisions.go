@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/neonmei/challenge_urlshortener/application"
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+)
+
+func handleRevisions(e application.Service, ids validators.IdAlphabet, c *gin.Context) {
+	urlId := c.Param("url_id")
+	if err := validators.ValidateId(urlId, ids); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	revisions, err := e.Revisions(c.Request.Context(), urlId)
+	if err == nil {
+		c.JSON(http.StatusOK, dtos.FromRevisions(revisions))
+		return
+	}
+
+	if errors.Is(err, domain.ErrURLNotFound) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	_ = c.Error(err)
+	c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/neonmei/challenge_urlshortener/application"
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+)
+
+const defaultStatsWindow = 24 * time.Hour
+
+func handleStats(e application.Service, ids validators.IdAlphabet, c *gin.Context) {
+	urlId := c.Param("url_id")
+	if err := validators.ValidateId(urlId, ids); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	to, err := unixQuery(c, "to", time.Now())
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	from, err := unixQuery(c, "from", to.Add(-defaultStatsWindow))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	excludeBots, err := strconv.ParseBool(c.DefaultQuery("exclude_bots", "false"))
+	if err != nil {
+		err = errors.Join(domain.ErrInvalidBotFilter, err)
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	granularity := domain.Granularity(c.DefaultQuery("granularity", string(domain.GranularityHour)))
+	stats, err := e.Stats(c.Request.Context(), urlId, granularity, from, to, excludeBots)
+	if err == nil {
+		c.JSON(http.StatusOK, dtos.FromURLStats(urlId, granularity, from.Unix(), to.Unix(), excludeBots, stats))
+		return
+	}
+
+	if errors.Is(err, domain.ErrInvalidGranularity) || errors.Is(err, domain.ErrInvalidRange) {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrAnalyticsDisabled) {
+		_ = c.Error(err)
+		c.JSON(http.StatusNotImplemented, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	_ = c.Error(err)
+	c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+}
+
+// unixQuery parses an optional unix timestamp query parameter
+func unixQuery(c *gin.Context, name string, fallback time.Time) (time.Time, error) {
+	raw, found := c.GetQuery(name)
+	if !found {
+		return fallback, nil
+	}
+
+	seconds, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Join(domain.ErrInvalidRange, err)
+	}
+
+	return time.Unix(seconds, 0), nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/neonmei/challenge_urlshortener/application"
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+)
+
+func handleTrending(e application.Service, c *gin.Context) {
+	var window time.Duration
+	if raw, found := c.GetQuery("window"); found {
+		var err error
+		if window, err = time.ParseDuration(raw); err != nil {
+			err = errors.Join(domain.ErrInvalidWindow, err)
+			_ = c.Error(err)
+			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	limit := 0
+	if raw, found := c.GetQuery("limit"); found {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			_ = c.Error(err)
+			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: ErrHttpBadQuery.Error()})
+			return
+		}
+	}
+
+	page, err := e.Trending(c.Request.Context(), window, limit)
+	if err == nil {
+		c.JSON(http.StatusOK, dtos.FromTrending(page.Window, page.URLs))
+		return
+	}
+
+	if errors.Is(err, domain.ErrInvalidWindow) {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrTrendingDisabled) {
+		_ = c.Error(err)
+		c.JSON(http.StatusNotImplemented, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	_ = c.Error(err)
+	c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+}
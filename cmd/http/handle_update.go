@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/neonmei/challenge_urlshortener/application"
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+)
+
+func handleUpdate(e application.Service, ids validators.IdAlphabet, c *gin.Context) {
+	urlId := c.Param("url_id")
+	if err := validators.ValidateId(urlId, ids); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	updateRequest := dtos.URLUpdateRequest{}
+	if err := json.NewDecoder(c.Request.Body).Decode(&updateRequest); err != nil {
+		_ = c.Error(errors.Join(ErrHttpRequestDecode, err))
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: ErrHttpRequestDecode.Error()})
+		return
+	}
+
+	item, err := e.Update(c.Request.Context(), urlId, c.GetString(UserContextKey), application.URLChanges{
+		Upstream: updateRequest.Upstream,
+		Enabled:  updateRequest.Enabled,
+		Version:  updateRequest.Version,
+	})
+	if err == nil {
+		c.JSON(http.StatusOK, dtos.FromDomain(*item))
+		return
+	}
+
+	_ = c.Error(err)
+	switch {
+	case errors.Is(err, domain.ErrURLNotFound):
+		c.Status(http.StatusNotFound)
+	case errors.Is(err, domain.ErrVersionConflict):
+		c.JSON(http.StatusConflict, dtos.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, domain.ErrUnavailableRepo):
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, badRequest(err))
+	}
+}
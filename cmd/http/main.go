@@ -15,56 +15,178 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/honeycombio/otel-config-go/otelconfig"
 	"github.com/neonmei/challenge_urlshortener/application"
-	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/analytics"
 	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/invalidation"
 	"github.com/neonmei/challenge_urlshortener/platform/o11y"
 	"github.com/neonmei/challenge_urlshortener/platform/repositories"
+	"github.com/neonmei/challenge_urlshortener/platform/storage"
+	"github.com/neonmei/challenge_urlshortener/platform/warmup"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel/sdk/trace"
 )
 
 func main() {
 	cfg := config.Load()
-	otelShutdown, err := otelconfig.ConfigureOpenTelemetry(buildOtelOpts(cfg)...)
+	var otelCfg *otelconfig.Config
+	otelShutdown, err := otelconfig.ConfigureOpenTelemetry(append(buildOtelOpts(cfg), o11y.CaptureConfig(&otelCfg))...)
 	if err != nil {
 		panic(err)
 	}
 
 	defer otelShutdown()
 
-	// TODO: Sumar instrumentaciones async de OTel si se habilita metrics
-	cache, err := ristretto.NewCache(&repositories.URLCacheConfig{
-		NumCounters: cfg.Cache.NumCounters,
-		MaxCost:     cfg.Cache.MaxCost,
-		BufferItems: cfg.Cache.BufferItems,
-		Metrics:     cfg.Cache.MetricsEnabled,
-	})
+	ids, err := validators.NewIdAlphabet(cfg.Hasher.Alphabet)
 	if err != nil {
 		panic(err)
 	}
-	defer cache.Close()
 
-	if cfg.Cache.MetricsEnabled {
-		o11y.InstrumentCacheAsync(cache)
+	store, err := storage.Open(cfg)
+	if err != nil {
+		panic(err)
 	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			slog.Error("cannot close storage", "error", err.Error())
+		}
+	}()
+
+	if cfg.Storage.Fixtures != "" {
+		created, err := storage.SeedFile(context.Background(), store.URLs, cfg.Storage.Fixtures, cfg.ApiUser)
+		if err != nil {
+			panic(err)
+		}
 
-	dynamoClient, err := clients.NewDynamoClient(cfg)
+		slog.Info("seeded fixtures", "file", cfg.Storage.Fixtures, "created", created)
+	}
+
+	bots, err := analytics.NewBotClassifier(cfg)
 	if err != nil {
 		panic(err)
 	}
 
-	urlRepository := repositories.NewCached(repositories.NewDynamoURLRepository(cfg, dynamoClient), cache)
-	app, err := application.New(cfg, urlRepository)
+	appOpts := []application.Option{
+		application.WithSequenceRepository(store.Sequences),
+		application.WithBotClassifier(bots),
+	}
+	var drains []func(context.Context) error
+	if cfg.Analytics.Enabled {
+		recorder, err := analytics.NewBatchRecorder(cfg, store.Hits)
+		if err != nil {
+			panic(err)
+		}
+
+		defer recorder.Close()
+		drains = append(drains, recorder.Shutdown)
+		appOpts = append(appOpts, application.WithClickRecorder(recorder), application.WithHitsRepository(store.Hits))
+	}
+
+	if cfg.Trending.Enabled {
+		trending, err := analytics.NewTrending(cfg)
+		if err != nil {
+			panic(err)
+		}
+
+		appOpts = append(appOpts, application.WithTrendingTracker(trending))
+	}
+
+	if cfg.Clicks.Enabled {
+		sink, err := analytics.NewClickSink(cfg, o11y.LogsTarget(otelCfg))
+		if err != nil {
+			panic(err)
+		}
+
+		pipeline, err := analytics.NewClickPipeline(cfg, sink)
+		if err != nil {
+			panic(err)
+		}
+
+		defer pipeline.Close()
+		drains = append(drains, pipeline.Shutdown)
+		appOpts = append(appOpts, application.WithClickTracker(pipeline))
+	}
+
+	backendRepository := store.URLs
+	if cfg.Hedging.Enabled {
+		backendRepository, err = repositories.NewHedged(cfg, backendRepository)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if cfg.Resilience.Enabled {
+		backendRepository, err = repositories.NewResilient(cfg, backendRepository)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	urlRepository := backendRepository
+	var cacheTarget invalidation.Target
+	if cfg.Cache.Enabled {
+		// TODO: Sumar instrumentaciones async de OTel si se habilita metrics
+		cache, err := ristretto.NewCache(&repositories.URLCacheConfig{
+			NumCounters: cfg.Cache.NumCounters,
+			MaxCost:     cfg.Cache.MaxCost,
+			BufferItems: cfg.Cache.BufferItems,
+			Metrics:     cfg.Cache.MetricsEnabled,
+		})
+		if err != nil {
+			panic(err)
+		}
+		defer cache.Close()
+
+		cacheCounters := &repositories.CacheCounters{}
+		if cfg.Cache.MetricsEnabled {
+			o11y.InstrumentCacheAsync(cache, cacheCounters)
+		}
+
+		staleStore := repositories.NewStaleStore(cfg.Cache.StaleEntries)
+		cachedOpts := []repositories.CachedOption{
+			repositories.WithNegativeCache(cfg.Cache.NegativeTtl, cfg.Cache.NegativeCost),
+			repositories.WithCacheCounters(cacheCounters),
+			repositories.WithStaleWhileRevalidate(cfg.Cache.SoftTtl),
+			repositories.WithStaleIfError(staleStore),
+		}
+		var peers invalidation.PeerSource
+		if cfg.Invalidation.Token != "" {
+			peers = invalidation.NewPeerSource(cfg.Invalidation.Peers, cfg.Invalidation.PeersDns, cfg.Port)
+			broadcaster, err := invalidation.NewBroadcaster(peers, cfg.Invalidation.Token, cfg.Invalidation.Timeout)
+			if err != nil {
+				panic(err)
+			}
+
+			defer broadcaster.Wait()
+			cachedOpts = append(cachedOpts, repositories.WithPeerNotifier(broadcaster))
+		}
+
+		urlRepository = repositories.NewCached(backendRepository, cache, cachedOpts...)
+		cacheTarget = repositories.NewCacheTarget(cache, staleStore)
+
+		if peers != nil && cfg.Trending.WarmupLimit > 0 {
+			warmed, err := warmup.NewWarmer(cfg, peers).Warm(context.Background(), urlRepository)
+			if err != nil {
+				slog.Warn("cannot warm up cache from peers", "error", err)
+			} else {
+				slog.Info("warmed up cache from peers", "links", warmed)
+			}
+		}
+	}
+
+	app, err := application.New(cfg, urlRepository, appOpts...)
 	if err != nil {
 		panic(app)
 	}
 
-	if err := gracefulServe(cfg, app); err != nil {
+	if err := gracefulServe(cfg, app, ids, cacheTarget, drains...); err != nil {
 		slog.Error(err.Error())
 	}
 }
 
-func gracefulServe(cfg config.AppConfig, e application.Service) error {
+// gracefulServe serves until a signal arrives, then stops taking requests and
+// runs drains, which flush work the handlers left behind, within ShutdownTimeout
+func gracefulServe(cfg config.AppConfig, e application.Service, ids validators.IdAlphabet, cacheTarget invalidation.Target, drains ...func(context.Context) error) error {
 	gin.SetMode(gin.ReleaseMode)
 	ginRouter := gin.New()
 	ginRouter.Use(gin.Recovery())
@@ -74,7 +196,7 @@ func gracefulServe(cfg config.AppConfig, e application.Service) error {
 		return r.URL.Path != "/healthz"
 	})))
 
-	routes(ginRouter, cfg, e)
+	routes(ginRouter, cfg, e, ids, cacheTarget)
 
 	httpServer := &http.Server{
 		Addr:    apiAddress,
@@ -102,6 +224,12 @@ func gracefulServe(cfg config.AppConfig, e application.Service) error {
 		return errors.Join(errors.New("Shutdown error"), err)
 	}
 
+	for _, drain := range drains {
+		if err := drain(ctx); err != nil {
+			return errors.Join(errors.New("Drain error"), err)
+		}
+	}
+
 	select {
 	case <-ctx.Done():
 		return errors.New("Shutdown timeout")
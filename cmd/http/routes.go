@@ -1,37 +1,69 @@
 package main
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/neonmei/challenge_urlshortener/application"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
 	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/invalidation"
+	"github.com/neonmei/challenge_urlshortener/platform/warmup"
 )
 
 const (
 	UserContextKey = "auth.user"
 )
 
-func routes(apiRouter *gin.Engine, cfg config.AppConfig, e application.Service) {
+func routes(apiRouter *gin.Engine, cfg config.AppConfig, e application.Service, ids validators.IdAlphabet, cacheTarget invalidation.Target) {
 	// Public endpoints /v1/urls/redirect/:url_id
-	apiRouter.GET("/:url_id", func(ctx *gin.Context) { handleRedirect(e, ctx) })
+	apiRouter.GET("/:url_id", func(ctx *gin.Context) { handleRedirect(e, ids, ctx) })
 
 	// Administrative endpoints
 	groupUrls := apiRouter.Group("/v1/urls").Use(TokenAuthMiddleware(cfg))
 	groupUrls.POST("/short", func(ctx *gin.Context) { handleCreate(e, ctx) })
-	groupUrls.DELETE("/short/:url_id", func(ctx *gin.Context) { handleDelete(e, ctx) })
-	groupUrls.GET("/short/:url_id", func(ctx *gin.Context) { handleFetch(e, ctx) })
+	groupUrls.GET("/short", func(ctx *gin.Context) { handleList(e, ctx) })
+	groupUrls.DELETE("/short/:url_id", func(ctx *gin.Context) { handleDelete(e, ids, ctx) })
+	groupUrls.GET("/short/:url_id", func(ctx *gin.Context) { handleFetch(e, ids, ctx) })
+	groupUrls.PATCH("/short/:url_id", func(ctx *gin.Context) { handleUpdate(e, ids, ctx) })
+	groupUrls.POST("/short/:url_id/restore", func(ctx *gin.Context) { handleRestore(e, ids, ctx) })
+	groupUrls.GET("/short/:url_id/revisions", func(ctx *gin.Context) { handleRevisions(e, ids, ctx) })
+	groupUrls.GET("/short/:url_id/stats", func(ctx *gin.Context) { handleStats(e, ids, ctx) })
+	groupUrls.GET("/trending", func(ctx *gin.Context) { handleTrending(e, ctx) })
 
 	// Platform endpoints
 	apiRouter.GET("/platform/healthz", func(ctx *gin.Context) { handleHealth(e, ctx) })
+	// REF: without a local cache there is nothing to invalidate
+	if cfg.Invalidation.Token != "" && cacheTarget != nil {
+		apiRouter.POST(invalidation.Path, gin.WrapH(invalidation.NewHandler(cfg.Invalidation.Token, ids, cacheTarget)))
+	}
+	// REF: peers read trending links to warm up their cache, with the token they share
+	if cfg.Invalidation.Token != "" {
+		apiRouter.GET(warmup.Path, PeerAuthMiddleware(cfg), func(ctx *gin.Context) { handleTrending(e, ctx) })
+	}
 
 	apiRouter.LoadHTMLFiles(
 		fmt.Sprintf("assets/%s", StatusNotFoundTemplate),
+		fmt.Sprintf("assets/%s", StatusGoneTemplate),
 		fmt.Sprintf("assets/%s", InternalServiceErrorTemplate),
 	)
 }
 
+// PeerAuthMiddleware lets through other replicas, which hold the invalidation token
+func PeerAuthMiddleware(cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		headerToken := c.Request.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(headerToken), []byte(cfg.Invalidation.Token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
 func TokenAuthMiddleware(cfg config.AppConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		headerToken := c.Request.Header.Get("Authorization")
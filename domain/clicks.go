@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"context"
+	"net/netip"
+	"time"
+)
+
+// Visit is what a redirect request tells about who followed the link. It
+// holds personal data and must never leave the process as is
+type Visit struct {
+	Referrer       string
+	UserAgent      string
+	AcceptLanguage string
+	Accept         string
+	Method         string
+	ClientIP       netip.Addr
+
+	// Purpose is the prefetch or preview intent announced by the client, if any
+	Purpose string
+
+	// Bot is set by the redirect path once the visit is classified
+	Bot bool
+}
+
+// ClickEvent is an anonymized redirect, safe to store and export
+type ClickEvent struct {
+	At    time.Time
+	URLId string
+
+	// ReferrerHost is the host of the referring page, empty for direct traffic
+	ReferrerHost string
+
+	// Browser is the user-agent family, i.e: Chrome or Facebook for in-app browsers
+	Browser string
+
+	// Device is one of the Device constants
+	Device string
+
+	// Country is an ISO 3166 alpha-2 code, empty when unknown
+	Country string
+
+	// Language is the preferred ISO 639 language of the visitor, empty when unknown
+	Language string
+
+	// VisitorHash is a salted hash of the truncated client IP and user agent
+	VisitorHash string
+
+	// Bot flags crawlers and link preview fetchers
+	Bot bool
+}
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceOther   = "other"
+)
+
+// ClickSink receives click events in batches, usually shipping them elsewhere
+type ClickSink interface {
+	Write(ctx context.Context, events []ClickEvent) error
+}
@@ -5,16 +5,41 @@ import (
 )
 
 var (
-	ErrEmptyId           = errors.New("empty URL identifier")
-	ErrInvalidId         = errors.New("invalid URL identifier")
-	ErrInvalidURL        = errors.New("invalid, insecure or empty URL")
-	ErrInvalidAuthor     = errors.New("invalid author")
-	ErrEmptyAuthor       = errors.New("empty author")
-	ErrEmptyTime         = errors.New("empty time")
-	ErrCreatedInFuture   = errors.New("creation dates in the future are not accepted")
-	ErrURLNotFound       = errors.New("url not found")
-	ErrURLTooLong        = errors.New("URL is too long")
-	ErrCannotUseDisabled = errors.New("URL exist but is currently disabled")
-	ErrUnavailableRepo   = errors.New("unavailable repository")
-	ErrRepoSchema        = errors.New("repository anticorruption layer is erroring")
+	ErrEmptyId               = errors.New("empty URL identifier")
+	ErrInvalidId             = errors.New("invalid URL identifier")
+	ErrInvalidURL            = errors.New("invalid, insecure or empty URL")
+	ErrInvalidAuthor         = errors.New("invalid author")
+	ErrEmptyAuthor           = errors.New("empty author")
+	ErrEmptyTime             = errors.New("empty time")
+	ErrCreatedInFuture       = errors.New("creation dates in the future are not accepted")
+	ErrURLNotFound           = errors.New("url not found")
+	ErrURLTooLong            = errors.New("URL is too long")
+	ErrCannotUseDisabled     = errors.New("URL exist but is currently disabled")
+	ErrUnavailableRepo       = errors.New("unavailable repository")
+	ErrRepoSchema            = errors.New("repository anticorruption layer is erroring")
+	ErrURLAlreadyExists      = errors.New("URL identifier is already taken")
+	ErrReservedId            = errors.New("URL identifier is reserved")
+	ErrIdsExhausted          = errors.New("no unused URL identifier could be generated")
+	ErrURLExpired            = errors.New("URL exist but has expired")
+	ErrExpiresBeforeCreation = errors.New("expiration must be after creation")
+	ErrInvalidGranularity    = errors.New("granularity must be one of minute, hour or day")
+	ErrInvalidRange          = errors.New("invalid or too wide time range")
+	ErrAnalyticsDisabled     = errors.New("analytics are not enabled")
+	ErrInvalidCursor         = errors.New("invalid pagination cursor")
+	ErrVersionConflict       = errors.New("URL was modified concurrently")
+	ErrSelfReferencingURL    = errors.New("URL points back to this service")
+	ErrShortenerURL          = errors.New("URL points to another URL shortener")
+	ErrHostNotAllowed        = errors.New("URL host is not in the allowlist")
+	ErrHostDenied            = errors.New("URL host is in the denylist")
+	ErrIPLiteralHost         = errors.New("URL host cannot be an IP address")
+	ErrUserinfoURL           = errors.New("URL cannot embed credentials")
+	ErrInvalidAlphabet       = errors.New("alphabet must be a preset or unique letters, digits, '-' or '_'")
+	ErrUnknownGenerator      = errors.New("unknown identifier generator strategy")
+	ErrMissingSequence       = errors.New("identifier generator requires a sequence repository")
+	ErrHomographHost         = errors.New("URL host mixes scripts or imitates another domain")
+	ErrUnknownBackend        = errors.New("unknown storage backend")
+	ErrUnknownClickSink      = errors.New("unknown click sink")
+	ErrInvalidBotFilter      = errors.New("exclude_bots must be true or false")
+	ErrTrendingDisabled      = errors.New("trending links are not enabled")
+	ErrInvalidWindow         = errors.New("window must be a positive duration, i.e: 5m")
 )
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Granularity is the width of the time buckets hits are aggregated into
+type Granularity string
+
+const (
+	GranularityMinute Granularity = "minute"
+	GranularityHour   Granularity = "hour"
+	GranularityDay    Granularity = "day"
+)
+
+// Granularities lists every bucket width a hit is aggregated into
+var Granularities = []Granularity{GranularityMinute, GranularityHour, GranularityDay}
+
+// HitCount is the number of hits an URL got inside a bucket starting at Start
+type HitCount struct {
+	URLId       string
+	Granularity Granularity
+	Start       time.Time
+	Hits        int64
+
+	// Bots is how many of Hits came from crawlers and link preview fetchers
+	Bots int64
+}
+
+// VisitorSketch is a serialized HyperLogLog of the visitors an URL got during
+// the UTC day starting at Day
+type VisitorSketch struct {
+	URLId  string
+	Day    time.Time
+	Sketch []byte
+}
+
+// URLStats is the hits series of an URL along with its unique visitors
+type URLStats struct {
+	Series []HitCount
+
+	// UniqueVisitors estimates the distinct visitors over the days the series
+	// spans, bots are never counted as visitors
+	UniqueVisitors int64
+
+	// DailyVisitors estimates the distinct visitors of each day, keyed by its unix start
+	DailyVisitors map[int64]int64
+}
+
+// TrendingURL is an URL among the most redirected ones lately. Hits may
+// overestimate its redirects by up to Error
+type TrendingURL struct {
+	URLId string
+	Hits  int64
+	Error int64
+}
+
+type HitsRepository interface {
+	// AddHits increments counters, adding to whatever was already stored
+	AddHits(ctx context.Context, counts []HitCount) error
+
+	// Series returns the non-empty buckets starting between from and to, inclusive
+	Series(ctx context.Context, urlID string, g Granularity, from time.Time, to time.Time) ([]HitCount, error)
+
+	// MergeVisitors merges sketches into the stored ones of the same URL and day
+	MergeVisitors(ctx context.Context, sketches []VisitorSketch) error
+
+	// Visitors returns the stored sketches of days starting between from and to, inclusive
+	Visitors(ctx context.Context, urlID string, from time.Time, to time.Time) ([]VisitorSketch, error)
+}
+
+func (g Granularity) Duration() time.Duration {
+	switch g {
+	case GranularityMinute:
+		return time.Minute
+	case GranularityHour:
+		return time.Hour
+	case GranularityDay:
+		return 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// Bucket returns the UTC start of the bucket containing t
+func (g Granularity) Bucket(t time.Time) time.Time {
+	return t.UTC().Truncate(g.Duration())
+}
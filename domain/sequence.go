@@ -0,0 +1,9 @@
+package domain
+
+import "context"
+
+// SequenceRepository hands out disjoint blocks of named, monotonic sequences
+type SequenceRepository interface {
+	// Lease reserves size consecutive values and returns the first one
+	Lease(ctx context.Context, name string, size uint64) (uint64, error)
+}
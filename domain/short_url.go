@@ -20,4 +20,33 @@ type ShortURL struct {
 
 	// Enabled flags if current URL is active
 	Enabled bool
+
+	// ExpiresAt is when the URL stops redirecting, zero value means never
+	ExpiresAt time.Time
+
+	// Version increments on every update, used for optimistic concurrency
+	Version int64
+}
+
+// Revision is the state an URL had before an update replaced it
+type Revision struct {
+	// Version is the version that was replaced
+	Version int64
+
+	// Upstream is the previous destination
+	Upstream url.URL
+
+	// Enabled is the previous enabled flag
+	Enabled bool
+
+	// ChangedBy identifies who performed the update
+	ChangedBy string
+
+	// ChangedAt indicates when the update happened
+	ChangedAt time.Time
+}
+
+// Expired reports whether the URL has an expiration and it has been reached
+func (u ShortURL) Expired(now time.Time) bool {
+	return !u.ExpiresAt.IsZero() && !now.Before(u.ExpiresAt)
 }
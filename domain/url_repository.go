@@ -2,10 +2,70 @@ package domain
 
 import (
 	"context"
+	"strings"
+	"time"
 )
 
 type URLRepository interface {
 	Get(ctx context.Context, urlID string) (*ShortURL, error)
-	Delete(ctx context.Context, urlID string) error
 	Save(ctx context.Context, shortUrl ShortURL) error
+
+	// Delete is a soft delete, the URL is kept but disabled
+	Delete(ctx context.Context, urlID string) error
+
+	// Restore enables back a soft deleted URL
+	Restore(ctx context.Context, urlID string) error
+
+	// Purge removes the URL and its history for good
+	Purge(ctx context.Context, urlID string) error
+
+	// List returns up to limit items matching filter, starting after cursor
+	List(ctx context.Context, filter URLFilter, cursor string, limit int) (*URLPage, error)
+
+	// Update stores shortUrl only if the stored version still is shortUrl.Version,
+	// bumping it and appending revision to the history
+	Update(ctx context.Context, shortUrl ShortURL, revision Revision) error
+
+	// Revisions returns previous states of an URL, oldest first
+	Revisions(ctx context.Context, urlID string) ([]Revision, error)
+}
+
+// URLFilter narrows List results, zero values do not filter
+type URLFilter struct {
+	CreatedBy     string
+	Enabled       *bool
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpstreamHost  string
+}
+
+// URLPage holds a page of results, Cursor is empty on the last page
+type URLPage struct {
+	Items  []ShortURL
+	Cursor string
+}
+
+// Matches reports whether an item satisfies every condition of the filter
+func (f URLFilter) Matches(u ShortURL) bool {
+	if f.CreatedBy != "" && f.CreatedBy != u.CreatedBy {
+		return false
+	}
+
+	if f.Enabled != nil && *f.Enabled != u.Enabled {
+		return false
+	}
+
+	if !f.CreatedAfter.IsZero() && u.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+
+	if !f.CreatedBefore.IsZero() && u.CreatedAt.After(f.CreatedBefore) {
+		return false
+	}
+
+	if f.UpstreamHost != "" && !strings.EqualFold(f.UpstreamHost, u.Upstream.Hostname()) {
+		return false
+	}
+
+	return true
 }
@@ -0,0 +1,121 @@
+package validators
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+const (
+	AlphabetBase62 = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	// AlphabetNoLookalikes drops characters mistaken for one another when read
+	// aloud or printed (0 O o, 1 l I i, 2 Z z, 5 S s, u v) and vowels, which
+	// also keeps most words out of generated identifiers
+	AlphabetNoLookalikes = "6789BCDFGHJKLMNPQRTWbcdfghjkmnpqrtwz"
+)
+
+// alphabetPresets are the names accepted by ResolveAlphabet
+var alphabetPresets = map[string]string{
+	"base62":       AlphabetBase62,
+	"nolookalikes": AlphabetNoLookalikes,
+}
+
+// ResolveAlphabet accepts a preset name or the literal characters to use,
+// which must be unique letters, digits, '-' or '_'
+func ResolveAlphabet(alphabet string) (string, error) {
+	if preset, found := alphabetPresets[alphabet]; found {
+		return preset, nil
+	}
+
+	if len(alphabet) < 2 {
+		return "", domain.ErrInvalidAlphabet
+	}
+
+	for i, r := range alphabet {
+		if !isIdRune(r) || strings.ContainsRune(alphabet[:i], r) {
+			return "", domain.ErrInvalidAlphabet
+		}
+	}
+
+	return alphabet, nil
+}
+
+// IdAlphabet is the alphabet identifiers are generated in. Lookups, aliases
+// and stored records are accepted in base62 as well, so switching alphabets
+// keeps the links already handed out working. The zero value is base62
+type IdAlphabet struct {
+	generated string
+}
+
+// NewIdAlphabet resolves a preset name or literal characters, see
+// ResolveAlphabet. An empty one is base62
+func NewIdAlphabet(alphabet string) (IdAlphabet, error) {
+	if alphabet == "" {
+		return IdAlphabet{}, nil
+	}
+
+	resolved, err := ResolveAlphabet(alphabet)
+	if err != nil {
+		return IdAlphabet{}, err
+	}
+
+	return IdAlphabet{generated: resolved}, nil
+}
+
+// Generated returns the characters new identifiers are made of
+func (a IdAlphabet) Generated() string {
+	if a.generated == "" {
+		return AlphabetBase62
+	}
+
+	return a.generated
+}
+
+// accepts tells whether r may appear in an identifier looked up or stored
+func (a IdAlphabet) accepts(r rune) bool {
+	return strings.ContainsRune(AlphabetBase62, r) || strings.ContainsRune(a.generated, r)
+}
+
+func isIdRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_'
+}
+
+// Blocklist holds lowercase words generated identifiers must not contain
+type Blocklist []string
+
+// leetReplacer undoes common digit for letter substitutions
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "6", "g", "7", "t", "8", "b", "9", "g", "-", "", "_", "")
+
+// Contains matches case-insensitively, also after undoing digit
+// substitutions such as 4 for a
+func (b Blocklist) Contains(id string) bool {
+	lower := strings.ToLower(id)
+	normalized := leetReplacer.Replace(lower)
+
+	for _, word := range b {
+		if strings.Contains(lower, word) || strings.Contains(normalized, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseBlocklist reads one word per line, ignoring blank lines and lines
+// starting with #
+func ParseBlocklist(r io.Reader) (Blocklist, error) {
+	result := Blocklist{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		result = append(result, word)
+	}
+
+	return result, scanner.Err()
+}
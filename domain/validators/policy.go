@@ -0,0 +1,213 @@
+package validators
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"golang.org/x/net/idna"
+)
+
+// URLPolicy holds the rules an upstream URL has to satisfy on top of
+// ValidateURL. Host lists accept exact hosts (example.com) or wildcard
+// subdomains (*.example.com, which does not match example.com itself)
+type URLPolicy struct {
+	// MaxLength caps the length of the whole URL, zero disables the check
+	MaxLength int
+
+	// OwnHosts are the hosts this service answers on, they and their
+	// subdomains are rejected to prevent redirect loops
+	OwnHosts []string
+
+	// Shorteners are other URL shorteners, they and their subdomains are
+	// rejected to prevent redirect chains
+	Shorteners []string
+
+	// AllowHosts when not empty is the only set of hosts accepted
+	AllowHosts []string
+
+	// DenyHosts are always rejected
+	DenyHosts []string
+}
+
+// Validate checks every rule in order and returns the first violation, each
+// rule maps to its own domain error
+func (p URLPolicy) Validate(u *url.URL) error {
+	if err := ValidateURL(u); err != nil {
+		return err
+	}
+
+	if p.MaxLength > 0 && len(u.String()) > p.MaxLength {
+		return domain.ErrURLTooLong
+	}
+
+	if u.User != nil {
+		return domain.ErrUserinfoURL
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if isIPLiteral(host) {
+		return domain.ErrIPLiteralHost
+	}
+
+	asciiHost, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return errors.Join(domain.ErrInvalidURL, err)
+	}
+
+	if err := validateHomograph(asciiHost); err != nil {
+		return err
+	}
+
+	switch {
+	case matchesDomain(asciiHost, p.OwnHosts):
+		return domain.ErrSelfReferencingURL
+	case matchesDomain(asciiHost, p.Shorteners):
+		return domain.ErrShortenerURL
+	case matchesHost(asciiHost, p.DenyHosts):
+		return domain.ErrHostDenied
+	case len(p.AllowHosts) > 0 && !matchesHost(asciiHost, p.AllowHosts):
+		return domain.ErrHostNotAllowed
+	}
+
+	return nil
+}
+
+// isIPLiteral also catches the numeric forms resolvers accept, such as
+// https://2130706433 or https://0x7f.1
+func isIPLiteral(host string) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+
+	for _, label := range strings.Split(host, ".") {
+		if label == "" {
+			continue
+		}
+
+		digits := strings.TrimPrefix(label, "0x")
+		for _, r := range digits {
+			if !strings.ContainsRune("0123456789abcdef", r) {
+				return false
+			}
+		}
+
+		// REF: hexadecimal letters alone are a valid label (i.e: cafe)
+		if digits == label && strings.ContainsAny(label, "abcdef") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesHost applies allow and deny list semantics
+func matchesHost(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+
+		if host == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesDomain matches a host and every subdomain of it
+func matchesDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		d = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d == "" {
+			continue
+		}
+
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// latinLookalikes are non latin letters rendered the same as latin ones in
+// most fonts, a label made only of them imitates a latin label
+const latinLookalikes = "аеіјорсухѕԁһӏԛԝɡαεικνορτυχϲ"
+
+// validateHomograph rejects labels mixing scripts, or made entirely of
+// letters that imitate latin ones. Latin mixed with CJK scripts is accepted
+// as it is common in japanese domains
+func validateHomograph(asciiHost string) error {
+	for _, label := range strings.Split(asciiHost, ".") {
+		if !strings.HasPrefix(label, "xn--") {
+			continue
+		}
+
+		decoded, err := idna.Punycode.ToUnicode(label)
+		if err != nil {
+			return errors.Join(domain.ErrHomographHost, err)
+		}
+
+		scripts := map[string]bool{}
+		lookalikes := true
+		for _, r := range decoded {
+			script := scriptOf(r)
+			if script == "" {
+				continue
+			}
+
+			scripts[script] = true
+			if !strings.ContainsRune(latinLookalikes, r) {
+				lookalikes = false
+			}
+		}
+
+		if len(scripts) > 2 || (len(scripts) == 2 && !(scripts["latin"] && scripts["cjk"])) {
+			return domain.ErrHomographHost
+		}
+
+		if lookalikes && (scripts["cyrillic"] || scripts["greek"]) {
+			return domain.ErrHomographHost
+		}
+	}
+
+	return nil
+}
+
+var scriptTables = []struct {
+	name   string
+	tables []*unicode.RangeTable
+}{
+	{"latin", []*unicode.RangeTable{unicode.Latin}},
+	{"cyrillic", []*unicode.RangeTable{unicode.Cyrillic}},
+	{"greek", []*unicode.RangeTable{unicode.Greek}},
+	{"cjk", []*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul}},
+	{"armenian", []*unicode.RangeTable{unicode.Armenian}},
+	{"arabic", []*unicode.RangeTable{unicode.Arabic}},
+	{"hebrew", []*unicode.RangeTable{unicode.Hebrew}},
+}
+
+// scriptOf returns an empty script for digits, hyphens and other characters
+// shared by every script
+func scriptOf(r rune) string {
+	if !unicode.IsLetter(r) {
+		return ""
+	}
+
+	for _, s := range scriptTables {
+		if unicode.IsOneOf(s.tables, r) {
+			return s.name
+		}
+	}
+
+	return "other"
+}
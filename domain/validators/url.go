@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/mail"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/neonmei/challenge_urlshortener/domain"
@@ -50,13 +51,45 @@ func ValidateCreated(t time.Time) error {
 	return nil
 }
 
-func ValidateId(u string) error {
+// ValidateExpiration accepts a zero expiration (never expires) or one that
+// happens after creation
+func ValidateExpiration(created time.Time, expires time.Time) error {
+	if expires.IsZero() {
+		return nil
+	}
+
+	if !expires.After(created) {
+		return domain.ErrExpiresBeforeCreation
+	}
+
+	return nil
+}
+
+// ValidateId checks an identifier being looked up or stored, which may come
+// from the configured alphabet or from base62
+func ValidateId(u string, ids IdAlphabet) error {
+	if len(u) < 1 {
+		return domain.ErrEmptyId
+	}
+
+	for _, r := range u {
+		if !ids.accepts(r) {
+			return domain.ErrInvalidId
+		}
+	}
+
+	return nil
+}
+
+// ValidateGeneratedId checks a generated identifier only uses the configured alphabet
+func ValidateGeneratedId(u string, ids IdAlphabet) error {
 	if len(u) < 1 {
 		return domain.ErrEmptyId
 	}
 
+	alphabet := ids.Generated()
 	for _, r := range u {
-		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+		if !strings.ContainsRune(alphabet, r) {
 			return domain.ErrInvalidId
 		}
 	}
@@ -64,11 +97,46 @@ func ValidateId(u string) error {
 	return nil
 }
 
-func ValidateShortURL(u domain.ShortURL) error {
+// ValidateAlias checks a caller-chosen identifier, which on top of being a
+// valid identifier cannot collide with reserved words such as route prefixes
+func ValidateAlias(alias string, ids IdAlphabet, reserved []string) error {
+	if err := ValidateId(alias, ids); err != nil {
+		return err
+	}
+
+	for _, word := range reserved {
+		if strings.EqualFold(alias, word) {
+			return domain.ErrReservedId
+		}
+	}
+
+	return nil
+}
+
+// ValidateRange checks a stats query does not go backwards nor request more
+// than maxBuckets buckets of the given granularity
+func ValidateRange(g domain.Granularity, from time.Time, to time.Time, maxBuckets int) error {
+	if g.Duration() == 0 {
+		return domain.ErrInvalidGranularity
+	}
+
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return domain.ErrInvalidRange
+	}
+
+	if to.Sub(from)/g.Duration() >= time.Duration(maxBuckets) {
+		return domain.ErrInvalidRange
+	}
+
+	return nil
+}
+
+func ValidateShortURL(u domain.ShortURL, ids IdAlphabet) error {
 	return errors.Join(
 		ValidateAuthor(u.CreatedBy),
 		ValidateCreated(u.CreatedAt),
+		ValidateExpiration(u.CreatedAt, u.ExpiresAt),
 		ValidateURL(&u.Upstream),
-		ValidateId(u.ID),
+		ValidateId(u.ID, ids),
 	)
 }
@@ -26,7 +26,7 @@ func TestValidateShouldPass(t *testing.T) {
 		Enabled:   true,
 	}
 
-	assert.NoError(t, ValidateShortURL(u))
+	assert.NoError(t, ValidateShortURL(u, IdAlphabet{}))
 }
 
 func TestValidateShouldFail(t *testing.T) {
@@ -105,6 +105,53 @@ func TestValidateShouldFail(t *testing.T) {
 	}
 
 	for _, testCase := range cases {
-		assert.ErrorContains(t, ValidateShortURL(testCase.item), testCase.err.Error())
+		assert.ErrorContains(t, ValidateShortURL(testCase.item, IdAlphabet{}), testCase.err.Error())
+	}
+}
+
+func TestValidateAlias(t *testing.T) {
+	reserved := []string{"platform", "v1"}
+
+	assert.NoError(t, ValidateAlias("hotsale25", IdAlphabet{}, reserved))
+	assert.ErrorIs(t, ValidateAlias("", IdAlphabet{}, reserved), domain.ErrEmptyId)
+	assert.ErrorIs(t, ValidateAlias("hot_sale", IdAlphabet{}, reserved), domain.ErrInvalidId)
+	assert.ErrorIs(t, ValidateAlias("v1", IdAlphabet{}, reserved), domain.ErrReservedId)
+	assert.ErrorIs(t, ValidateAlias("Platform", IdAlphabet{}, reserved), domain.ErrReservedId)
+}
+
+func TestValidateExpiration(t *testing.T) {
+	now := time.Now()
+
+	assert.NoError(t, ValidateExpiration(now, time.Time{}))
+	assert.NoError(t, ValidateExpiration(now, now.Add(time.Minute)))
+	assert.ErrorIs(t, ValidateExpiration(now, now), domain.ErrExpiresBeforeCreation)
+	assert.ErrorIs(t, ValidateExpiration(now, now.Add(-time.Minute)), domain.ErrExpiresBeforeCreation)
+}
+
+func TestValidateIdUsesConfiguredAlphabet(t *testing.T) {
+	base62 := IdAlphabet{}
+	assert.NoError(t, ValidateId("Oo01", base62))
+	assert.NoError(t, ValidateGeneratedId("Oo01", base62))
+	assert.ErrorIs(t, ValidateId("a-b", base62), domain.ErrInvalidId)
+	assert.Equal(t, AlphabetBase62, base62.Generated())
+
+	// REF: links printed before switching alphabets keep resolving
+	noLookalikes, err := NewIdAlphabet("nolookalikes")
+	assert.NoError(t, err)
+	assert.NoError(t, ValidateId("Oo01", noLookalikes))
+	assert.NoError(t, ValidateAlias("Oo01", noLookalikes, nil))
+	assert.ErrorIs(t, ValidateGeneratedId("Oo01", noLookalikes), domain.ErrInvalidId)
+	assert.NoError(t, ValidateGeneratedId("bcd789", noLookalikes))
+
+	custom, err := NewIdAlphabet("abc-_")
+	assert.NoError(t, err)
+	assert.NoError(t, ValidateId("a-b_c", custom))
+	assert.NoError(t, ValidateId("xyz", custom))
+	assert.ErrorIs(t, ValidateGeneratedId("xyz", custom), domain.ErrInvalidId)
+	assert.ErrorIs(t, ValidateId("a/b", custom), domain.ErrInvalidId)
+
+	for _, invalid := range []string{"abca", "ab/", "a"} {
+		_, err := NewIdAlphabet(invalid)
+		assert.ErrorIs(t, err, domain.ErrInvalidAlphabet, invalid)
 	}
 }
@@ -128,6 +128,220 @@ func (_c *MockURLRepository_Get_Call) RunAndReturn(run func(context.Context, str
 	return _c
 }
 
+// List provides a mock function with given fields: ctx, filter, cursor, limit
+func (_m *MockURLRepository) List(ctx context.Context, filter domain.URLFilter, cursor string, limit int) (*domain.URLPage, error) {
+	ret := _m.Called(ctx, filter, cursor, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 *domain.URLPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.URLFilter, string, int) (*domain.URLPage, error)); ok {
+		return rf(ctx, filter, cursor, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.URLFilter, string, int) *domain.URLPage); ok {
+		r0 = rf(ctx, filter, cursor, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.URLPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.URLFilter, string, int) error); ok {
+		r1 = rf(ctx, filter, cursor, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockURLRepository_List_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'List'
+type MockURLRepository_List_Call struct {
+	*mock.Call
+}
+
+// List is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter domain.URLFilter
+//   - cursor string
+//   - limit int
+func (_e *MockURLRepository_Expecter) List(ctx interface{}, filter interface{}, cursor interface{}, limit interface{}) *MockURLRepository_List_Call {
+	return &MockURLRepository_List_Call{Call: _e.mock.On("List", ctx, filter, cursor, limit)}
+}
+
+func (_c *MockURLRepository_List_Call) Run(run func(ctx context.Context, filter domain.URLFilter, cursor string, limit int)) *MockURLRepository_List_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.URLFilter), args[2].(string), args[3].(int))
+	})
+	return _c
+}
+
+func (_c *MockURLRepository_List_Call) Return(_a0 *domain.URLPage, _a1 error) *MockURLRepository_List_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockURLRepository_List_Call) RunAndReturn(run func(context.Context, domain.URLFilter, string, int) (*domain.URLPage, error)) *MockURLRepository_List_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Purge provides a mock function with given fields: ctx, urlID
+func (_m *MockURLRepository) Purge(ctx context.Context, urlID string) error {
+	ret := _m.Called(ctx, urlID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Purge")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, urlID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockURLRepository_Purge_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Purge'
+type MockURLRepository_Purge_Call struct {
+	*mock.Call
+}
+
+// Purge is a helper method to define mock.On call
+//   - ctx context.Context
+//   - urlID string
+func (_e *MockURLRepository_Expecter) Purge(ctx interface{}, urlID interface{}) *MockURLRepository_Purge_Call {
+	return &MockURLRepository_Purge_Call{Call: _e.mock.On("Purge", ctx, urlID)}
+}
+
+func (_c *MockURLRepository_Purge_Call) Run(run func(ctx context.Context, urlID string)) *MockURLRepository_Purge_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockURLRepository_Purge_Call) Return(_a0 error) *MockURLRepository_Purge_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockURLRepository_Purge_Call) RunAndReturn(run func(context.Context, string) error) *MockURLRepository_Purge_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Restore provides a mock function with given fields: ctx, urlID
+func (_m *MockURLRepository) Restore(ctx context.Context, urlID string) error {
+	ret := _m.Called(ctx, urlID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Restore")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, urlID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockURLRepository_Restore_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Restore'
+type MockURLRepository_Restore_Call struct {
+	*mock.Call
+}
+
+// Restore is a helper method to define mock.On call
+//   - ctx context.Context
+//   - urlID string
+func (_e *MockURLRepository_Expecter) Restore(ctx interface{}, urlID interface{}) *MockURLRepository_Restore_Call {
+	return &MockURLRepository_Restore_Call{Call: _e.mock.On("Restore", ctx, urlID)}
+}
+
+func (_c *MockURLRepository_Restore_Call) Run(run func(ctx context.Context, urlID string)) *MockURLRepository_Restore_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockURLRepository_Restore_Call) Return(_a0 error) *MockURLRepository_Restore_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockURLRepository_Restore_Call) RunAndReturn(run func(context.Context, string) error) *MockURLRepository_Restore_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Revisions provides a mock function with given fields: ctx, urlID
+func (_m *MockURLRepository) Revisions(ctx context.Context, urlID string) ([]domain.Revision, error) {
+	ret := _m.Called(ctx, urlID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Revisions")
+	}
+
+	var r0 []domain.Revision
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]domain.Revision, error)); ok {
+		return rf(ctx, urlID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []domain.Revision); ok {
+		r0 = rf(ctx, urlID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Revision)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, urlID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockURLRepository_Revisions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Revisions'
+type MockURLRepository_Revisions_Call struct {
+	*mock.Call
+}
+
+// Revisions is a helper method to define mock.On call
+//   - ctx context.Context
+//   - urlID string
+func (_e *MockURLRepository_Expecter) Revisions(ctx interface{}, urlID interface{}) *MockURLRepository_Revisions_Call {
+	return &MockURLRepository_Revisions_Call{Call: _e.mock.On("Revisions", ctx, urlID)}
+}
+
+func (_c *MockURLRepository_Revisions_Call) Run(run func(ctx context.Context, urlID string)) *MockURLRepository_Revisions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockURLRepository_Revisions_Call) Return(_a0 []domain.Revision, _a1 error) *MockURLRepository_Revisions_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockURLRepository_Revisions_Call) RunAndReturn(run func(context.Context, string) ([]domain.Revision, error)) *MockURLRepository_Revisions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Save provides a mock function with given fields: ctx, shortUrl
 func (_m *MockURLRepository) Save(ctx context.Context, shortUrl domain.ShortURL) error {
 	ret := _m.Called(ctx, shortUrl)
@@ -175,6 +389,54 @@ func (_c *MockURLRepository_Save_Call) RunAndReturn(run func(context.Context, do
 	return _c
 }
 
+// Update provides a mock function with given fields: ctx, shortUrl, revision
+func (_m *MockURLRepository) Update(ctx context.Context, shortUrl domain.ShortURL, revision domain.Revision) error {
+	ret := _m.Called(ctx, shortUrl, revision)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ShortURL, domain.Revision) error); ok {
+		r0 = rf(ctx, shortUrl, revision)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockURLRepository_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockURLRepository_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - shortUrl domain.ShortURL
+//   - revision domain.Revision
+func (_e *MockURLRepository_Expecter) Update(ctx interface{}, shortUrl interface{}, revision interface{}) *MockURLRepository_Update_Call {
+	return &MockURLRepository_Update_Call{Call: _e.mock.On("Update", ctx, shortUrl, revision)}
+}
+
+func (_c *MockURLRepository_Update_Call) Run(run func(ctx context.Context, shortUrl domain.ShortURL, revision domain.Revision)) *MockURLRepository_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(domain.ShortURL), args[2].(domain.Revision))
+	})
+	return _c
+}
+
+func (_c *MockURLRepository_Update_Call) Return(_a0 error) *MockURLRepository_Update_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockURLRepository_Update_Call) RunAndReturn(run func(context.Context, domain.ShortURL, domain.Revision) error) *MockURLRepository_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockURLRepository creates a new instance of MockURLRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockURLRepository(t interface {
@@ -0,0 +1,130 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	errBufferFull = errors.New("buffer is full")
+	errShutdown   = errors.New("shut down")
+)
+
+// batcher is the bounded queue behind BatchRecorder and ClickPipeline. Items
+// are handed to add from a single background goroutine, which calls flush
+// when add reports the batch full, every flushInterval and once more after
+// draining the buffer on Shutdown. Only that goroutine touches the batch
+type batcher[T any] struct {
+	items         chan T
+	add           func(item T) (full bool)
+	flush         func(ctx context.Context)
+	flushInterval time.Duration
+	writeTimeout  time.Duration
+	done          chan struct{}
+	closeOnce     sync.Once
+
+	// mu guards closed, items are never sent once it is set. items itself is
+	// not closed as callers may still be offering when Shutdown times out
+	mu     sync.RWMutex
+	closed bool
+	stop   chan struct{}
+
+	// ctx is cancelled when Shutdown runs out of time, aborting the last writes
+	ctx       context.Context
+	cancelCtx context.CancelFunc
+}
+
+// offer queues item without blocking, failing with errBufferFull or
+// errShutdown when it is dropped
+func (b *batcher[T]) offer(item T) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return errShutdown
+	}
+
+	select {
+	case b.items <- item:
+		return nil
+	default:
+		return errBufferFull
+	}
+}
+
+// Shutdown stops accepting items and flushes whatever is pending. When ctx is
+// done first, pending writes are cancelled and ctx.Err is returned
+func (b *batcher[T]) Shutdown(ctx context.Context) error {
+	b.closeOnce.Do(func() {
+		b.mu.Lock()
+		b.closed = true
+		b.mu.Unlock()
+		close(b.stop)
+	})
+
+	defer b.cancelCtx()
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *batcher[T]) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case item := <-b.items:
+			if b.add(item) {
+				b.write()
+			}
+		case <-ticker.C:
+			b.write()
+		case <-b.stop:
+			// REF: nothing is sent once stop is closed, the buffer is all that is left
+			for {
+				select {
+				case item := <-b.items:
+					if b.add(item) {
+						b.write()
+					}
+				default:
+					b.write()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (b *batcher[T]) write() {
+	ctx, cancelFunc := context.WithTimeout(b.ctx, b.writeTimeout)
+	defer cancelFunc()
+
+	b.flush(ctx)
+}
+
+func newBatcher[T any](bufferSize int, flushInterval, writeTimeout time.Duration, add func(T) bool, flush func(context.Context)) *batcher[T] {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	b := &batcher[T]{
+		items:         make(chan T, bufferSize),
+		add:           add,
+		flush:         flush,
+		flushInterval: flushInterval,
+		writeTimeout:  writeTimeout,
+		done:          make(chan struct{}),
+		stop:          make(chan struct{}),
+		ctx:           ctx,
+		cancelCtx:     cancelCtx,
+	}
+
+	go b.run()
+	return b
+}
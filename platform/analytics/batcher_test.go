@@ -0,0 +1,56 @@
+package analytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatcherDrainsOnShutdown(t *testing.T) {
+	var batch, written []int
+	var flushes int
+	queue := newBatcher(10, time.Hour, time.Second,
+		func(item int) bool {
+			batch = append(batch, item)
+			return len(batch) >= 2
+		},
+		func(context.Context) {
+			flushes++
+			written, batch = append(written, batch...), nil
+		},
+	)
+
+	for i := range 3 {
+		assert.NoError(t, queue.offer(i))
+	}
+
+	// REF: one full batch plus the remainder, all written before Shutdown returns
+	assert.NoError(t, queue.Shutdown(context.Background()))
+	assert.Equal(t, []int{0, 1, 2}, written)
+	assert.Equal(t, 2, flushes)
+
+	assert.ErrorIs(t, queue.offer(3), errShutdown)
+	assert.NoError(t, queue.Shutdown(context.Background()))
+}
+
+func TestBatcherRejectsWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	queue := newBatcher(1, time.Hour, time.Second,
+		func(int) bool {
+			<-release
+			return false
+		},
+		func(context.Context) {},
+	)
+
+	// REF: the consumer holds one item, the buffer the next and the rest are rejected
+	assert.NoError(t, queue.offer(0))
+	assert.Eventually(t, func() bool { return len(queue.items) == 0 }, time.Second, time.Millisecond)
+	assert.NoError(t, queue.offer(1))
+	assert.ErrorIs(t, queue.offer(2), errBufferFull)
+
+	close(release)
+	assert.NoError(t, queue.Shutdown(context.Background()))
+}
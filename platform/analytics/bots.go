@@ -0,0 +1,131 @@
+package analytics
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+)
+
+var ErrBotRules = errors.New("invalid bot rules")
+
+// defaultBotRules match crawlers and the link preview fetchers of messaging
+// apps and social networks, which hit links seconds after they are shared.
+// A bare bot token would also match phone models such as Cubot
+var defaultBotRules = []string{
+	"bot/", "bot;", "bot)",
+	"crawler", "spider", "slurp",
+	"facebookexternalhit", "facebookcatalog", "meta-externalagent",
+	"whatsapp/", "telegrambot", "discordbot", "slack-imgproxy", "slackbot",
+	"skypeuripreview", "linkedinbot", "pinterest/0.", "redditbot", "embedly",
+	"bitlybot", "iframely", "vkshare", "bingpreview",
+	"headlesschrome", "phantomjs", "python-requests", "python-urllib",
+	"go-http-client", "java/", "apache-httpclient", "node-fetch", "axios/",
+}
+
+// BotRules are lowercase user-agent substrings. Allow ones exempt matching
+// user agents from the deny ones and from the non-browser heuristic
+type BotRules struct {
+	Deny  []string
+	Allow []string
+}
+
+// ParseBotRules reads one case insensitive user-agent substring per line.
+// Lines starting with ! are allow rules, with # comments
+func ParseBotRules(r io.Reader) (BotRules, error) {
+	rules := BotRules{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if allowed, found := strings.CutPrefix(line, "!"); found {
+			if allowed = strings.TrimSpace(allowed); allowed == "" {
+				return BotRules{}, errors.Join(ErrBotRules, errors.New("empty allow rule"))
+			}
+			rules.Allow = append(rules.Allow, allowed)
+			continue
+		}
+
+		rules.Deny = append(rules.Deny, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return BotRules{}, errors.Join(ErrBotRules, err)
+	}
+
+	return rules, nil
+}
+
+// OpenBotRules loads a rules file written in the format ParseBotRules expects
+func OpenBotRules(path string) (BotRules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return BotRules{}, err
+	}
+	defer f.Close()
+
+	return ParseBotRules(f)
+}
+
+// BotClassifier flags visits from crawlers and link preview fetchers by their
+// user agent and by requests no browser following a link would make
+type BotClassifier struct {
+	rules BotRules
+}
+
+// IsBot is cheap enough to be called on every redirect
+func (b *BotClassifier) IsBot(visit domain.Visit) bool {
+	// REF: prefetches are not someone following the link
+	if isPrefetch(visit.Purpose) {
+		return true
+	}
+
+	ua := strings.ToLower(strings.TrimSpace(visit.UserAgent))
+	if ua == "" {
+		return true
+	}
+
+	for _, allowed := range b.rules.Allow {
+		if strings.Contains(ua, allowed) {
+			return false
+		}
+	}
+
+	for _, denied := range b.rules.Deny {
+		if strings.Contains(ua, denied) {
+			return true
+		}
+	}
+
+	// REF: browsers always claim to be Mozilla and send their languages
+	return !strings.HasPrefix(ua, "mozilla/") && visit.AcceptLanguage == ""
+}
+
+func isPrefetch(purpose string) bool {
+	purpose = strings.ToLower(purpose)
+	return strings.Contains(purpose, "prefetch") || strings.Contains(purpose, "preview")
+}
+
+// NewBotClassifier extends the built-in rules with the configured rules file
+func NewBotClassifier(cfg config.AppConfig) (*BotClassifier, error) {
+	b := &BotClassifier{rules: BotRules{Deny: defaultBotRules}}
+	if cfg.Analytics.BotRulesFile == "" {
+		return b, nil
+	}
+
+	rules, err := OpenBotRules(cfg.Analytics.BotRulesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	b.rules.Deny = append(append([]string{}, defaultBotRules...), rules.Deny...)
+	b.rules.Allow = rules.Allow
+	return b, nil
+}
@@ -0,0 +1,85 @@
+package analytics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	chromeUA    = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	instagramUA = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Instagram 309.0.0.0"
+)
+
+func TestBotClassifierDefaults(t *testing.T) {
+	bots, err := NewBotClassifier(config.Load())
+	assert.NoError(t, err)
+
+	cases := map[string]bool{
+		"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)": true,
+		"WhatsApp/2.23.20.0 A":                                                                            true,
+		"TelegramBot (like TwitterBot)":                                                                   true,
+		"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)":                                      true,
+		"Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)":                               true,
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)":                        true,
+		"Mozilla/5.0 (Linux; Android 10; CUBOT_X19) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36": false,
+		chromeUA:    false,
+		instagramUA: false,
+	}
+
+	for ua, bot := range cases {
+		assert.Equal(t, bot, bots.IsBot(domain.Visit{UserAgent: ua, AcceptLanguage: "es-AR"}), ua)
+	}
+}
+
+func TestBotClassifierHeuristics(t *testing.T) {
+	bots, err := NewBotClassifier(config.Load())
+	assert.NoError(t, err)
+
+	browser := domain.Visit{Method: "GET", UserAgent: chromeUA, AcceptLanguage: "es-AR"}
+	assert.False(t, bots.IsBot(browser))
+
+	// REF: prefetches give themselves away even with a browser user agent
+	prefetch := browser
+	prefetch.Purpose = "prefetch;prerender"
+	assert.True(t, bots.IsBot(prefetch))
+
+	assert.True(t, bots.IsBot(domain.Visit{Method: "GET", AcceptLanguage: "es-AR"}))
+
+	// REF: non-browser clients only pass with a language, as browsers send one
+	assert.True(t, bots.IsBot(domain.Visit{Method: "GET", UserAgent: "curl/8.5.0"}))
+	assert.False(t, bots.IsBot(domain.Visit{Method: "GET", UserAgent: "curl/8.5.0", AcceptLanguage: "en"}))
+	assert.False(t, bots.IsBot(domain.Visit{Method: "GET", UserAgent: chromeUA}))
+}
+
+func TestParseBotRules(t *testing.T) {
+	rules, err := ParseBotRules(strings.NewReader("# campaign monitors\nUptimeRobot\n\n ! Googlebot \n"))
+	assert.NoError(t, err)
+	assert.Equal(t, BotRules{Deny: []string{"uptimerobot"}, Allow: []string{"googlebot"}}, rules)
+
+	_, err = ParseBotRules(strings.NewReader("!\n"))
+	assert.ErrorIs(t, err, ErrBotRules)
+}
+
+func TestBotClassifierRulesFile(t *testing.T) {
+	cfg := config.Load()
+	cfg.Analytics.BotRulesFile = filepath.Join(t.TempDir(), "bots.txt")
+	assert.NoError(t, os.WriteFile(cfg.Analytics.BotRulesFile, []byte("InternalMonitor\n!curl/\n"), 0o600))
+
+	bots, err := NewBotClassifier(cfg)
+	assert.NoError(t, err)
+
+	// REF: rules extend the built-in ones rather than replace them
+	assert.True(t, bots.IsBot(domain.Visit{UserAgent: "Mozilla/5.0 InternalMonitor/1.0", AcceptLanguage: "en"}))
+	assert.True(t, bots.IsBot(domain.Visit{UserAgent: "WhatsApp/2.23.20.0 A"}))
+	assert.False(t, bots.IsBot(domain.Visit{UserAgent: "curl/8.5.0"}))
+
+	cfg.Analytics.BotRulesFile = filepath.Join(t.TempDir(), "missing.txt")
+	_, err = NewBotClassifier(cfg)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,158 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/o11y/semconv"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Reasons a click event is dropped, as recorded by MetricClicksDropped
+const (
+	dropBufferFull  = "buffer_full"
+	dropWriteFailed = "write_failed"
+	dropShutdown    = "shutdown"
+)
+
+type click struct {
+	urlID string
+	at    time.Time
+	visit domain.Visit
+}
+
+// ClickPipeline enriches visits and writes them to a sink in batches from a
+// background goroutine, so tracking a click is a non-blocking channel send.
+// Memory is bounded by the buffer plus one batch, events beyond that are
+// dropped and counted rather than slowing redirects down
+type ClickPipeline struct {
+	enricher *Enricher
+	sink     domain.ClickSink
+	maxBatch int
+	dropped  metric.Int64Counter
+	queue    *batcher[click]
+
+	// pending is only touched by the queue goroutine
+	pending []domain.ClickEvent
+}
+
+// Track queues a click, dropping it when the buffer is full or the pipeline
+// is shut down
+func (p *ClickPipeline) Track(urlID string, at time.Time, visit domain.Visit) {
+	switch err := p.queue.offer(click{urlID: urlID, at: at, visit: visit}); {
+	case errors.Is(err, errShutdown):
+		p.drop(1, dropShutdown)
+	case errors.Is(err, errBufferFull):
+		p.drop(1, dropBufferFull)
+	}
+}
+
+// Shutdown stops accepting clicks, writes whatever is pending and closes the
+// sink. When ctx is done first, pending writes are cancelled and the sink is
+// left open, as it may still be in use
+func (p *ClickPipeline) Shutdown(ctx context.Context) error {
+	if err := p.queue.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	if closer, ok := p.sink.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// Close is Shutdown without a deadline
+func (p *ClickPipeline) Close() {
+	if err := p.Shutdown(context.Background()); err != nil {
+		slog.Warn("cannot close click sink", "error", err)
+	}
+}
+
+func (p *ClickPipeline) drop(events int, reason string) {
+	p.dropped.Add(context.Background(), int64(events), metric.WithAttributes(
+		attribute.String(semconv.ClicksDropReason, reason),
+	))
+}
+
+// enrich reports whether the batch is big enough to be written
+func (p *ClickPipeline) enrich(c click) bool {
+	p.pending = append(p.pending, p.enricher.Enrich(c.urlID, c.at, c.visit))
+	return len(p.pending) >= p.maxBatch
+}
+
+func (p *ClickPipeline) flush(ctx context.Context) {
+	if len(p.pending) == 0 {
+		return
+	}
+
+	if err := p.sink.Write(ctx, p.pending); err != nil {
+		p.drop(len(p.pending), dropWriteFailed)
+		slog.Warn("cannot write click events", "events", len(p.pending), "error", err)
+	}
+
+	p.pending = make([]domain.ClickEvent, 0, p.maxBatch)
+}
+
+func NewClickPipeline(cfg config.AppConfig, sink domain.ClickSink) (*ClickPipeline, error) {
+	m := otel.GetMeterProvider().Meter("analytics")
+	c, err := m.Int64Counter(
+		semconv.MetricClicksDropped,
+		metric.WithDescription("Number of click events lost because the buffer was full or the sink failed."),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	enricher, err := NewEnricher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ClickPipeline{
+		enricher: enricher,
+		sink:     sink,
+		maxBatch: cfg.Clicks.MaxBatch,
+		dropped:  c,
+		pending:  make([]domain.ClickEvent, 0, cfg.Clicks.MaxBatch),
+	}
+	p.queue = newBatcher(cfg.Clicks.BufferSize, cfg.Clicks.FlushInterval, cfg.Clicks.WriteTimeout, p.enrich, p.flush)
+
+	return p, nil
+}
+
+// LogSink writes every click event as a structured log line, handy while
+// developing or when logs are already shipped somewhere
+type LogSink struct {
+	logger *slog.Logger
+}
+
+func (s LogSink) Write(ctx context.Context, events []domain.ClickEvent) error {
+	for _, e := range events {
+		s.logger.InfoContext(ctx, "click",
+			semconv.UrlId, e.URLId,
+			"at", e.At,
+			"referrer_host", e.ReferrerHost,
+			"browser", e.Browser,
+			"device", e.Device,
+			"country", e.Country,
+			"language", e.Language,
+			"visitor_hash", e.VisitorHash,
+			"is_bot", e.Bot,
+		)
+	}
+
+	return nil
+}
+
+func NewLogSink(logger *slog.Logger) LogSink {
+	return LogSink{logger: logger}
+}
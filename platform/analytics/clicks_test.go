@@ -0,0 +1,145 @@
+package analytics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type memorySink struct {
+	mu     sync.Mutex
+	events []domain.ClickEvent
+	writes int
+}
+
+func (s *memorySink) Write(_ context.Context, events []domain.ClickEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, events...)
+	s.writes++
+	return nil
+}
+
+func (s *memorySink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.events)
+}
+
+func TestClickPipelineBatches(t *testing.T) {
+	cfg := config.Load()
+	cfg.Clicks.MaxBatch = 2
+	cfg.Clicks.FlushInterval = time.Hour
+	sink := &memorySink{}
+	pipeline, err := NewClickPipeline(cfg, sink)
+	assert.NoError(t, err)
+
+	at := time.Now()
+	for i := 0; i < 5; i++ {
+		pipeline.Track("abc", at, domain.Visit{UserAgent: "curl/8.5.0"})
+	}
+
+	// REF: Close writes the last, incomplete batch
+	pipeline.Close()
+	assert.Equal(t, 5, sink.count())
+	assert.Equal(t, 3, sink.writes)
+	assert.Equal(t, "curl", sink.events[0].Browser)
+	assert.Equal(t, "abc", sink.events[0].URLId)
+}
+
+func TestClickPipelineFlushesPeriodically(t *testing.T) {
+	cfg := config.Load()
+	cfg.Clicks.FlushInterval = 10 * time.Millisecond
+	sink := &memorySink{}
+	pipeline, err := NewClickPipeline(cfg, sink)
+	assert.NoError(t, err)
+	defer pipeline.Close()
+
+	pipeline.Track("abc", time.Now(), domain.Visit{})
+	assert.Eventually(t, func() bool { return sink.count() == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestClickPipelineDropsWhenFull(t *testing.T) {
+	cfg := config.Load()
+	cfg.Clicks.BufferSize = 0
+	pipeline, err := NewClickPipeline(cfg, &memorySink{})
+	assert.NoError(t, err)
+	defer pipeline.Close()
+
+	// REF: an unbuffered channel with a busy consumer must never block callers
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			pipeline.Track("abc", time.Now(), domain.Visit{})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Track blocked")
+	}
+}
+
+func TestClickPipelineShutdownClosesSink(t *testing.T) {
+	cfg := config.Load()
+	cfg.Clicks.FlushInterval = time.Hour
+	cfg.Clicks.FilePath = filepath.Join(t.TempDir(), "clicks.jsonl")
+	sink, err := NewFileSink(cfg)
+	assert.NoError(t, err)
+
+	pipeline, err := NewClickPipeline(cfg, sink)
+	assert.NoError(t, err)
+
+	pipeline.Track("abc", time.Now(), domain.Visit{})
+	assert.NoError(t, pipeline.Shutdown(context.Background()))
+
+	lines, err := os.ReadFile(cfg.Clicks.FilePath)
+	assert.NoError(t, err)
+	events, err := decodeLines(lines)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.ErrorIs(t, sink.Write(context.Background(), events), os.ErrClosed)
+
+	// REF: redirects still in flight after the drain are dropped, not a panic
+	pipeline.Track("abc", time.Now(), domain.Visit{})
+
+	// REF: main still closes the pipeline after gracefulServe drained it
+	pipeline.Close()
+}
+
+type blockingSink struct{}
+
+func (blockingSink) Write(ctx context.Context, _ []domain.ClickEvent) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestClickPipelineShutdownDeadline(t *testing.T) {
+	cfg := config.Load()
+	cfg.Clicks.WriteTimeout = time.Hour
+	pipeline, err := NewClickPipeline(cfg, blockingSink{})
+	assert.NoError(t, err)
+
+	pipeline.Track("abc", time.Now(), domain.Visit{})
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelFunc()
+	assert.ErrorIs(t, pipeline.Shutdown(ctx), context.DeadlineExceeded)
+
+	// REF: the stuck write was cancelled, so the pipeline finishes right away
+	select {
+	case <-pipeline.queue.done:
+	case <-time.After(time.Second):
+		t.Fatal("pending write was not cancelled")
+	}
+}
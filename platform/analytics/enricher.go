@@ -0,0 +1,146 @@
+package analytics
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"golang.org/x/text/language"
+)
+
+const (
+	// ipv4Prefix and ipv6Prefix are kept from client addresses before hashing,
+	// so visitors are told apart by network and not by device
+	ipv4Prefix = 24
+	ipv6Prefix = 48
+)
+
+// VisitorHasher fingerprints visitors without keeping who they are
+type VisitorHasher struct {
+	salt []byte
+}
+
+// Enricher turns visits into anonymized click events. The client address is
+// only used to resolve the country and, truncated, to hash the visitor
+type Enricher struct {
+	*VisitorHasher
+	geo *GeoDB
+}
+
+// Enrich never fails, whatever cannot be resolved is left empty
+func (e *Enricher) Enrich(urlID string, at time.Time, visit domain.Visit) domain.ClickEvent {
+	browser, device := ParseUserAgent(visit.UserAgent)
+
+	return domain.ClickEvent{
+		At:           at.UTC(),
+		URLId:        urlID,
+		ReferrerHost: ReferrerHost(visit.Referrer),
+		Browser:      browser,
+		Device:       device,
+		Country:      e.geo.Country(visit.ClientIP),
+		Language:     PreferredLanguage(visit.AcceptLanguage),
+		VisitorHash:  e.VisitorHash(visit),
+		Bot:          visit.Bot,
+	}
+}
+
+// VisitorHash is an HMAC of the truncated client address and user agent, it
+// only matches across replicas sharing the salt
+func (h *VisitorHasher) VisitorHash(visit domain.Visit) string {
+	mac := hmac.New(sha256.New, h.salt)
+	mac.Write([]byte(TruncateIP(visit.ClientIP).String()))
+	mac.Write([]byte{0})
+	mac.Write([]byte(visit.UserAgent))
+
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// TruncateIP zeroes the host part of an address, invalid ones stay invalid
+func TruncateIP(ip netip.Addr) netip.Addr {
+	if !ip.IsValid() {
+		return ip
+	}
+
+	ip = ip.Unmap()
+	bits := ipv6Prefix
+	if ip.Is4() {
+		bits = ipv4Prefix
+	}
+
+	prefix, err := ip.Prefix(bits)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	return prefix.Addr()
+}
+
+// ReferrerHost keeps the lowercase host of a referrer without its www.
+// prefix, app referrers such as android-app://com.twitter.android included
+func ReferrerHost(referrer string) string {
+	u, err := url.Parse(strings.TrimSpace(referrer))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// PreferredLanguage returns the base language with the highest weight in an
+// Accept-Language header, i.e: es for es-AR,en;q=0.8
+func PreferredLanguage(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil {
+		return ""
+	}
+
+	// REF: the * wildcard parses as mul, multiple languages
+	for _, tag := range tags {
+		base, confidence := tag.Base()
+		if confidence != language.No && base.String() != "mul" {
+			return base.String()
+		}
+	}
+
+	return ""
+}
+
+// NewVisitorHasher keys hashes with the configured salt. Without one, a
+// random salt is drawn and hashes only match within this process
+func NewVisitorHasher(cfg config.AppConfig) (*VisitorHasher, error) {
+	h := &VisitorHasher{salt: []byte(cfg.Clicks.Salt)}
+	if len(h.salt) == 0 {
+		h.salt = make([]byte, 32)
+		if _, err := rand.Read(h.salt); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// NewEnricher loads the GeoIP database when configured
+func NewEnricher(cfg config.AppConfig) (*Enricher, error) {
+	hasher, err := NewVisitorHasher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Enricher{VisitorHasher: hasher}
+	if cfg.Clicks.GeoipFile != "" {
+		geo, err := OpenGeoDB(cfg.Clicks.GeoipFile)
+		if err != nil {
+			return nil, err
+		}
+		e.geo = geo
+	}
+
+	return e, nil
+}
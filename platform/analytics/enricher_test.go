@@ -0,0 +1,109 @@
+package analytics
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func enricherConfig(t *testing.T) config.AppConfig {
+	path := filepath.Join(t.TempDir(), "geo.csv")
+	assert.NoError(t, os.WriteFile(path, []byte(testGeoDB), 0o600))
+
+	cfg := config.Load()
+	cfg.Clicks.GeoipFile = path
+	cfg.Clicks.Salt = "pepper"
+	return cfg
+}
+
+func TestEnrich(t *testing.T) {
+	e, err := NewEnricher(enricherConfig(t))
+	assert.NoError(t, err)
+
+	at := time.Date(2025, 2, 5, 10, 30, 0, 0, time.FixedZone("ART", -3*3600))
+	event := e.Enrich("abc", at, domain.Visit{
+		Referrer:       "https://www.Facebook.com/some/post?id=1",
+		UserAgent:      "Mozilla/5.0 (iPhone; CPU iPhone OS 18_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/22B83 [FBAN/FBIOS;FBAV/494.0.0.41.109]",
+		AcceptLanguage: "es-AR,es;q=0.9,en;q=0.8",
+		ClientIP:       netip.MustParseAddr("190.0.12.34"),
+	})
+
+	assert.Equal(t, "abc", event.URLId)
+	assert.Equal(t, at.UTC(), event.At)
+	assert.Equal(t, "facebook.com", event.ReferrerHost)
+	assert.Equal(t, "Facebook", event.Browser)
+	assert.Equal(t, domain.DeviceMobile, event.Device)
+	assert.Equal(t, "AR", event.Country)
+	assert.Equal(t, "es", event.Language)
+	assert.Len(t, event.VisitorHash, 32)
+	assert.False(t, event.Bot)
+
+	event = e.Enrich("abc", at, domain.Visit{UserAgent: "WhatsApp/2.23.20.0 A", Bot: true})
+	assert.True(t, event.Bot)
+
+	// REF: nothing known about the visitor is fine
+	event = e.Enrich("abc", at, domain.Visit{})
+	assert.Equal(t, "", event.ReferrerHost)
+	assert.Equal(t, "", event.Country)
+	assert.Equal(t, "", event.Language)
+	assert.Equal(t, domain.DeviceOther, event.Device)
+}
+
+func TestVisitorHash(t *testing.T) {
+	cfg := enricherConfig(t)
+	e, err := NewEnricher(cfg)
+	assert.NoError(t, err)
+	visit := domain.Visit{UserAgent: "curl/8.5.0", ClientIP: netip.MustParseAddr("190.0.12.34")}
+
+	// REF: same network and user agent are the same visitor, on every replica sharing the salt
+	neighbour := visit
+	neighbour.ClientIP = netip.MustParseAddr("190.0.12.200")
+	assert.Equal(t, e.VisitorHash(visit), e.VisitorHash(neighbour))
+
+	other, err := NewEnricher(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, e.VisitorHash(visit), other.VisitorHash(visit))
+
+	// REF: the address never shows up in the hash input untruncated
+	assert.NotContains(t, e.VisitorHash(visit), "190.0.12.34")
+
+	elsewhere := visit
+	elsewhere.ClientIP = netip.MustParseAddr("190.0.13.34")
+	assert.NotEqual(t, e.VisitorHash(visit), e.VisitorHash(elsewhere))
+
+	browser := visit
+	browser.UserAgent = "Wget/1.21"
+	assert.NotEqual(t, e.VisitorHash(visit), e.VisitorHash(browser))
+
+	cfg.Clicks.Salt = ""
+	random, err := NewEnricher(cfg)
+	assert.NoError(t, err)
+	assert.NotEqual(t, e.VisitorHash(visit), random.VisitorHash(visit))
+}
+
+func TestTruncateIP(t *testing.T) {
+	assert.Equal(t, netip.MustParseAddr("190.0.12.0"), TruncateIP(netip.MustParseAddr("190.0.12.34")))
+	assert.Equal(t, netip.MustParseAddr("190.0.12.0"), TruncateIP(netip.MustParseAddr("::ffff:190.0.12.34")))
+	assert.Equal(t, netip.MustParseAddr("2800:40:1::"), TruncateIP(netip.MustParseAddr("2800:40:1:2:3::4")))
+	assert.False(t, TruncateIP(netip.Addr{}).IsValid())
+}
+
+func TestReferrerAndLanguage(t *testing.T) {
+	assert.Equal(t, "t.co", ReferrerHost("https://t.co/abc"))
+	assert.Equal(t, "com.twitter.android", ReferrerHost("android-app://com.twitter.android/"))
+	assert.Equal(t, "", ReferrerHost(""))
+	assert.Equal(t, "", ReferrerHost("://bad"))
+
+	assert.Equal(t, "en", PreferredLanguage("es;q=0.5, en-US"))
+	assert.Equal(t, "pt", PreferredLanguage("pt-BR"))
+	assert.Equal(t, "", PreferredLanguage("*"))
+	assert.Equal(t, "fr", PreferredLanguage("*, fr;q=0.5"))
+	assert.Equal(t, "", PreferredLanguage(""))
+	assert.Equal(t, "", PreferredLanguage("not a header;;;"))
+}
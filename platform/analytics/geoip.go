@@ -0,0 +1,111 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+var ErrGeoDatabase = errors.New("invalid geoip database")
+
+type geoRange struct {
+	start   netip.Addr
+	end     netip.Addr
+	country string
+}
+
+// GeoDB resolves IP addresses to countries from a list of address ranges
+type GeoDB struct {
+	ranges []geoRange
+}
+
+// LoadGeoDB reads CSV rows of start_ip,end_ip,country_code, the format of the
+// free DB-IP and IP2Location country databases. Rows may come in any order
+// but must not overlap, IPv4 and IPv6 ranges can be mixed
+func LoadGeoDB(r io.Reader) (*GeoDB, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	db := &GeoDB{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Join(ErrGeoDatabase, err)
+		}
+
+		if len(record) < 3 {
+			return nil, fmt.Errorf("%w: line %d has %d fields", ErrGeoDatabase, line, len(record))
+		}
+
+		start, errStart := netip.ParseAddr(strings.TrimSpace(record[0]))
+		end, errEnd := netip.ParseAddr(strings.TrimSpace(record[1]))
+		if errStart != nil || errEnd != nil {
+			// REF: tolerate a header row
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("%w: line %d has invalid addresses", ErrGeoDatabase, line)
+		}
+
+		start, end = start.Unmap(), end.Unmap()
+		if start.Is4() != end.Is4() || end.Less(start) {
+			return nil, fmt.Errorf("%w: line %d has an invalid range", ErrGeoDatabase, line)
+		}
+
+		country := strings.ToUpper(strings.TrimSpace(record[2]))
+		if country == "" || country == "-" || country == "ZZ" {
+			continue
+		}
+
+		db.ranges = append(db.ranges, geoRange{start: start, end: end, country: country})
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool { return db.ranges[i].start.Less(db.ranges[j].start) })
+	for i := 1; i < len(db.ranges); i++ {
+		if !db.ranges[i-1].end.Less(db.ranges[i].start) {
+			return nil, fmt.Errorf("%w: %s overlaps %s", ErrGeoDatabase, db.ranges[i].start, db.ranges[i-1].start)
+		}
+	}
+
+	return db, nil
+}
+
+// OpenGeoDB loads a database file written in the format LoadGeoDB expects
+func OpenGeoDB(path string) (*GeoDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadGeoDB(f)
+}
+
+// Country returns the ISO country code of ip, empty when unknown
+func (db *GeoDB) Country(ip netip.Addr) string {
+	if db == nil || !ip.IsValid() {
+		return ""
+	}
+
+	ip = ip.Unmap()
+	i := sort.Search(len(db.ranges), func(i int) bool { return ip.Less(db.ranges[i].start) })
+	if i == 0 {
+		return ""
+	}
+
+	candidate := db.ranges[i-1]
+	if candidate.start.Is4() != ip.Is4() || candidate.end.Less(ip) {
+		return ""
+	}
+
+	return candidate.country
+}
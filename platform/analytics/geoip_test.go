@@ -0,0 +1,70 @@
+package analytics
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGeoDB = `start_ip,end_ip,country
+"190.0.0.0","190.0.255.255","AR"
+1.0.0.0,1.0.0.255,au
+2800:40::,2800:40:ffff:ffff:ffff:ffff:ffff:ffff,AR
+8.8.8.0,8.8.8.255,ZZ
+`
+
+func TestGeoDBCountry(t *testing.T) {
+	db, err := LoadGeoDB(strings.NewReader(testGeoDB))
+	assert.NoError(t, err)
+
+	cases := map[string]string{
+		"190.0.12.1":         "AR",
+		"1.0.0.255":          "AU",
+		"::ffff:190.0.0.1":   "AR",
+		"2800:40:1::1":       "AR",
+		"1.0.1.0":            "",
+		"0.0.0.1":            "",
+		"8.8.8.8":            "",
+		"2800:41::1":         "",
+		"::1":                "",
+		"255.255.255.255":    "",
+		"2001:db8::ffff:1:1": "",
+	}
+
+	for ip, country := range cases {
+		assert.Equal(t, country, db.Country(netip.MustParseAddr(ip)), ip)
+	}
+
+	// REF: no database and no address are unknown countries
+	assert.Equal(t, "", (*GeoDB)(nil).Country(netip.MustParseAddr("190.0.0.1")))
+	assert.Equal(t, "", db.Country(netip.Addr{}))
+}
+
+func TestGeoDBInvalid(t *testing.T) {
+	for _, content := range []string{
+		"1.0.0.0,1.0.0.255\n",
+		"1.0.0.0,1.0.0.255,AU\nnot,an,address\n",
+		"1.0.0.255,1.0.0.0,AU\n",
+		"1.0.0.0,2800::,AU\n",
+		"1.0.0.0,1.0.0.255,AU\n1.0.0.128,1.0.1.0,AR\n",
+	} {
+		_, err := LoadGeoDB(strings.NewReader(content))
+		assert.ErrorIs(t, err, ErrGeoDatabase, content)
+	}
+}
+
+func TestOpenGeoDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geo.csv")
+	assert.NoError(t, os.WriteFile(path, []byte(testGeoDB), 0o600))
+
+	db, err := OpenGeoDB(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "AR", db.Country(netip.MustParseAddr("190.0.0.1")))
+
+	_, err = OpenGeoDB(filepath.Join(t.TempDir(), "missing.csv"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
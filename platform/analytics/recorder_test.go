@@ -0,0 +1,165 @@
+package analytics
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories"
+	"github.com/neonmei/challenge_urlshortener/platform/sketch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorderAggregates(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	repo := repositories.NewMemoryHits()
+	recorder, err := NewBatchRecorder(cfg, repo)
+	assert.NoError(t, err)
+
+	at := time.Date(2025, 2, 5, 10, 30, 15, 0, time.UTC)
+	recorder.Record("abc", at, domain.Visit{})
+	recorder.Record("abc", at.Add(time.Minute), domain.Visit{})
+	recorder.Record("abc", at.Add(time.Hour), domain.Visit{})
+	recorder.Record("other", at, domain.Visit{})
+
+	// REF: Close flushes whatever is still pending
+	recorder.Close()
+
+	minutes, err := repo.Series(ctx, "abc", domain.GranularityMinute, at.Add(-time.Hour), at.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, minutes, 3)
+
+	hours, err := repo.Series(ctx, "abc", domain.GranularityHour, at.Add(-time.Hour), at.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, hours, 2)
+	assert.Equal(t, int64(2), hours[0].Hits)
+	assert.Equal(t, int64(1), hours[1].Hits)
+
+	days, err := repo.Series(ctx, "abc", domain.GranularityDay, at.Add(-24*time.Hour), at)
+	assert.NoError(t, err)
+	assert.Len(t, days, 1)
+	assert.Equal(t, int64(3), days[0].Hits)
+}
+
+func TestRecorderFlushesPeriodically(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	cfg.Analytics.FlushInterval = 10 * time.Millisecond
+	repo := repositories.NewMemoryHits()
+	recorder, err := NewBatchRecorder(cfg, repo)
+	assert.NoError(t, err)
+	defer recorder.Close()
+
+	now := time.Now()
+	recorder.Record("abc", now, domain.Visit{})
+
+	assert.Eventually(t, func() bool {
+		days, err := repo.Series(ctx, "abc", domain.GranularityDay, now.Add(-24*time.Hour), now)
+		return err == nil && len(days) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRecorderDropsWhenFull(t *testing.T) {
+	cfg := config.Load()
+	cfg.Analytics.BufferSize = 0
+	recorder, err := NewBatchRecorder(cfg, repositories.NewMemoryHits())
+	assert.NoError(t, err)
+	defer recorder.Close()
+
+	// REF: an unbuffered channel with a busy consumer must never block callers
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			recorder.Record("abc", time.Now(), domain.Visit{})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record blocked")
+	}
+}
+
+func TestRecorderAfterShutdown(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	repo := repositories.NewMemoryHits()
+	recorder, err := NewBatchRecorder(config.Load(), repo)
+	assert.NoError(t, err)
+
+	recorder.Record("abc", now, domain.Visit{})
+	assert.NoError(t, recorder.Shutdown(ctx))
+
+	// REF: redirects still in flight once the drain timed out are dropped, not a panic
+	recorder.Record("abc", now, domain.Visit{})
+	assert.NoError(t, recorder.Shutdown(ctx))
+
+	days, err := repo.Series(ctx, "abc", domain.GranularityDay, domain.GranularityDay.Bucket(now), now)
+	assert.NoError(t, err)
+	if assert.Len(t, days, 1) {
+		assert.Equal(t, int64(1), days[0].Hits)
+	}
+}
+
+func TestRecorderCountsUniqueVisitors(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Load()
+	cfg.Clicks.Salt = "shared-salt"
+	repo := repositories.NewMemoryHits()
+	at := time.Date(2025, 2, 5, 10, 30, 15, 0, time.UTC)
+
+	// REF: two replicas sharing the salt see the same visitor once
+	for _, addresses := range [][]string{{"192.0.2.10", "198.51.100.7"}, {"192.0.2.10", "203.0.113.9"}} {
+		recorder, err := NewBatchRecorder(cfg, repo)
+		assert.NoError(t, err)
+
+		for _, address := range addresses {
+			visit := domain.Visit{UserAgent: "curl/8.5.0", ClientIP: netip.MustParseAddr(address)}
+			recorder.Record("abc", at, visit)
+			recorder.Record("abc", at.Add(time.Minute), visit)
+		}
+		recorder.Close()
+	}
+
+	sketches, err := repo.Visitors(ctx, "abc", domain.GranularityDay.Bucket(at), domain.GranularityDay.Bucket(at))
+	assert.NoError(t, err)
+	assert.Len(t, sketches, 1)
+
+	visitors, err := sketch.UnmarshalHyperLogLog(sketches[0].Sketch)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), visitors.Estimate())
+}
+
+func TestRecorderCountsBotsApart(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewMemoryHits()
+	recorder, err := NewBatchRecorder(config.Load(), repo)
+	assert.NoError(t, err)
+
+	at := time.Date(2025, 2, 5, 10, 30, 15, 0, time.UTC)
+	recorder.Record("abc", at, domain.Visit{UserAgent: "WhatsApp/2.23.20.0 A", Bot: true})
+	recorder.Record("abc", at, domain.Visit{UserAgent: "facebookexternalhit/1.1", Bot: true})
+	recorder.Record("abc", at, domain.Visit{UserAgent: chromeUA})
+	recorder.Close()
+
+	hours, err := repo.Series(ctx, "abc", domain.GranularityHour, domain.GranularityHour.Bucket(at), at)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.HitCount{
+		{URLId: "abc", Granularity: domain.GranularityHour, Start: domain.GranularityHour.Bucket(at), Hits: 3, Bots: 2},
+	}, hours)
+
+	// REF: bots are never visitors
+	sketches, err := repo.Visitors(ctx, "abc", domain.GranularityDay.Bucket(at), domain.GranularityDay.Bucket(at))
+	assert.NoError(t, err)
+	assert.Len(t, sketches, 1)
+
+	visitors, err := sketch.UnmarshalHyperLogLog(sketches[0].Sketch)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), visitors.Estimate())
+}
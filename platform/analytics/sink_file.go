@@ -0,0 +1,215 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+)
+
+// rotatedLayout sorts lexically in rotation order
+const rotatedLayout = "20060102T150405.000"
+
+// FileSink appends events as JSON lines, one per event. The file is rotated
+// by size and age, rotated files are renamed after the time they were
+// rotated at, i.e: clicks-20250102T150405.000.jsonl
+type FileSink struct {
+	mu         sync.Mutex
+	path       string
+	maxBytes   int64
+	maxAge     time.Duration
+	maxBackups int
+	now        func() time.Time
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func (s *FileSink) Write(ctx context.Context, events []domain.ClickEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	lines, err := encodeLines(events)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return os.ErrClosed
+	}
+
+	if s.mustRotate(int64(len(lines))) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(lines)
+	s.size += int64(n)
+	return err
+}
+
+// Close closes the current file, it is not rotated
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *FileSink) mustRotate(incoming int64) bool {
+	if s.size == 0 {
+		return false
+	}
+
+	if s.maxBytes > 0 && s.size+incoming > s.maxBytes {
+		return true
+	}
+
+	return s.maxAge > 0 && s.now().Sub(s.openedAt) >= s.maxAge
+}
+
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	base, ext := s.split()
+	rotated := base + "-" + s.now().UTC().Format(rotatedLayout) + ext
+	if err := os.Rename(s.path, rotated); err != nil {
+		return err
+	}
+
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	return s.prune()
+}
+
+func (s *FileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	s.file, s.size, s.openedAt = f, info.Size(), s.now()
+	return nil
+}
+
+// prune removes the oldest rotated files beyond maxBackups
+func (s *FileSink) prune() error {
+	if s.maxBackups <= 0 {
+		return nil
+	}
+
+	rotated, err := s.Rotated()
+	if err != nil {
+		return err
+	}
+
+	for len(rotated) > s.maxBackups {
+		if err := os.Remove(rotated[0]); err != nil {
+			return err
+		}
+		rotated = rotated[1:]
+	}
+
+	return nil
+}
+
+// Rotated lists the rotated files, oldest first
+func (s *FileSink) Rotated() ([]string, error) {
+	base, ext := s.split()
+	matches, err := filepath.Glob(escapeGlob(base) + "-*" + escapeGlob(ext))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func (s *FileSink) split() (string, string) {
+	ext := filepath.Ext(s.path)
+	return strings.TrimSuffix(s.path, ext), ext
+}
+
+func escapeGlob(path string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`).Replace(path)
+}
+
+func NewFileSink(cfg config.AppConfig) (*FileSink, error) {
+	s := &FileSink{
+		path:       cfg.Clicks.FilePath,
+		maxBytes:   cfg.Clicks.FileMaxBytes,
+		maxAge:     cfg.Clicks.FileMaxAge,
+		maxBackups: cfg.Clicks.FileMaxBackups,
+		now:        time.Now,
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// encodeLines renders events as JSON lines, the format of files and spills
+func encodeLines(events []domain.ClickEvent) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	for _, e := range events {
+		if err := encoder.Encode(dtos.FromClickEvent(e)); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeLines reads what encodeLines wrote
+func decodeLines(lines []byte) ([]domain.ClickEvent, error) {
+	events := []domain.ClickEvent{}
+	decoder := json.NewDecoder(bytes.NewReader(lines))
+	for decoder.More() {
+		var e dtos.ClickEvent
+		if err := decoder.Decode(&e); err != nil {
+			return nil, err
+		}
+		events = append(events, e.ToDomain())
+	}
+
+	return events, nil
+}
@@ -0,0 +1,126 @@
+package analytics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvents(n int) []domain.ClickEvent {
+	at := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	events := make([]domain.ClickEvent, 0, n)
+	for i := 0; i < n; i++ {
+		events = append(events, domain.ClickEvent{
+			At:          at.Add(time.Duration(i) * time.Second),
+			URLId:       "abc",
+			Browser:     "Firefox",
+			Device:      domain.DeviceDesktop,
+			Country:     "AR",
+			VisitorHash: "0123456789abcdef",
+		})
+	}
+
+	return events
+}
+
+func newTestFileSink(t *testing.T, maxBytes int64, maxAge time.Duration, maxBackups int) (*FileSink, *time.Time) {
+	cfg := config.Load()
+	cfg.Clicks.FilePath = filepath.Join(t.TempDir(), "clicks", "clicks.jsonl")
+	cfg.Clicks.FileMaxBytes = maxBytes
+	cfg.Clicks.FileMaxAge = maxAge
+	cfg.Clicks.FileMaxBackups = maxBackups
+
+	sink, err := NewFileSink(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sink.Close() })
+
+	now := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	sink.now = func() time.Time { return now }
+	sink.openedAt = now
+	return sink, &now
+}
+
+func TestFileSinkWritesLines(t *testing.T) {
+	sink, _ := newTestFileSink(t, 0, 0, 0)
+	events := testEvents(3)
+	assert.NoError(t, sink.Write(context.Background(), events[:2]))
+	assert.NoError(t, sink.Write(context.Background(), events[2:]))
+
+	lines, err := os.ReadFile(sink.path)
+	assert.NoError(t, err)
+
+	decoded, err := decodeLines(lines)
+	assert.NoError(t, err)
+	assert.Equal(t, events, decoded)
+}
+
+func TestFileSinkRotatesBySize(t *testing.T) {
+	line, _ := encodeLines(testEvents(1))
+	sink, now := newTestFileSink(t, int64(2*len(line)), 0, 1)
+
+	for i := 0; i < 5; i++ {
+		*now = now.Add(time.Second)
+		assert.NoError(t, sink.Write(context.Background(), testEvents(1)))
+	}
+
+	// REF: 5 events in files of 2 rotate twice, the first rotated file is pruned
+	rotated, err := sink.Rotated()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(filepath.Dir(sink.path), "clicks-20250102T000005.000.jsonl")}, rotated)
+
+	current, err := os.ReadFile(sink.path)
+	assert.NoError(t, err)
+	assert.Len(t, current, len(line))
+}
+
+func TestFileSinkRotatesByAge(t *testing.T) {
+	sink, now := newTestFileSink(t, 0, time.Hour, 0)
+	assert.NoError(t, sink.Write(context.Background(), testEvents(1)))
+
+	*now = now.Add(59 * time.Minute)
+	assert.NoError(t, sink.Write(context.Background(), testEvents(1)))
+
+	rotated, err := sink.Rotated()
+	assert.NoError(t, err)
+	assert.Empty(t, rotated)
+
+	*now = now.Add(time.Minute)
+	assert.NoError(t, sink.Write(context.Background(), testEvents(1)))
+
+	rotated, err = sink.Rotated()
+	assert.NoError(t, err)
+	assert.Len(t, rotated, 1)
+}
+
+func TestFileSinkAppendsAcrossRestarts(t *testing.T) {
+	sink, _ := newTestFileSink(t, 0, 0, 0)
+	assert.NoError(t, sink.Write(context.Background(), testEvents(1)))
+	assert.NoError(t, sink.Close())
+
+	cfg := config.Load()
+	cfg.Clicks.FilePath = sink.path
+	reopened, err := NewFileSink(cfg)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	assert.NoError(t, reopened.Write(context.Background(), testEvents(1)))
+
+	lines, err := os.ReadFile(sink.path)
+	assert.NoError(t, err)
+	decoded, err := decodeLines(lines)
+	assert.NoError(t, err)
+	assert.Len(t, decoded, 2)
+}
+
+func TestFileSinkClosed(t *testing.T) {
+	sink, _ := newTestFileSink(t, 0, 0, 0)
+	assert.NoError(t, sink.Close())
+	assert.ErrorIs(t, sink.Write(context.Background(), testEvents(1)), os.ErrClosed)
+}
@@ -0,0 +1,147 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/o11y"
+	"github.com/neonmei/challenge_urlshortener/platform/o11y/semconv"
+	"go.opentelemetry.io/otel/attribute"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// clickEventName names click log records, so backends can tell them apart
+// from regular logs
+const clickEventName = "short_url.click"
+
+// OTLPSink exports events as OpenTelemetry log records over OTLP/HTTP. It
+// sends them to the collector, with the headers and resource otelconfig set
+// up for traces and metrics, so clicks land next to the rest of the telemetry
+type OTLPSink struct {
+	collector collector
+	resource  *resourcepb.Resource
+	scope     *commonpb.InstrumentationScope
+}
+
+func (s *OTLPSink) Write(ctx context.Context, events []domain.ClickEvent) error {
+	observed := uint64(time.Now().UnixNano())
+	records := make([]*logspb.LogRecord, 0, len(events))
+	for _, e := range events {
+		records = append(records, &logspb.LogRecord{
+			TimeUnixNano:         uint64(e.At.UnixNano()),
+			ObservedTimeUnixNano: observed,
+			SeverityNumber:       logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+			SeverityText:         "INFO",
+			EventName:            clickEventName,
+			Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "click"}},
+			Attributes:           clickAttributes(e),
+		})
+	}
+
+	body, err := proto.Marshal(&collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource:  s.resource,
+			ScopeLogs: []*logspb.ScopeLogs{{Scope: s.scope, LogRecords: records}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.collector.post(ctx, "application/x-protobuf", body)
+}
+
+// clickAttributes leaves out what is unknown instead of sending empty strings
+func clickAttributes(e domain.ClickEvent) []*commonpb.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String(semconv.UrlId, e.URLId),
+		attribute.String(semconv.ClickBrowser, e.Browser),
+		attribute.String(semconv.ClickDevice, e.Device),
+		attribute.String(semconv.ClickVisitor, e.VisitorHash),
+		attribute.Bool(semconv.ClickIsBot, e.Bot),
+	}
+
+	for _, optional := range []attribute.KeyValue{
+		attribute.String(semconv.ClickReferrer, e.ReferrerHost),
+		attribute.String(semconv.ClickCountry, e.Country),
+		attribute.String(semconv.ClickLanguage, e.Language),
+	} {
+		if optional.Value.AsString() != "" {
+			attrs = append(attrs, optional)
+		}
+	}
+
+	return keyValues(attrs)
+}
+
+func keyValues(attrs []attribute.KeyValue) []*commonpb.KeyValue {
+	result := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		result = append(result, &commonpb.KeyValue{Key: string(kv.Key), Value: anyValue(kv.Value)})
+	}
+
+	return result
+}
+
+func anyValue(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.BOOLSLICE, attribute.INT64SLICE, attribute.FLOAT64SLICE, attribute.STRINGSLICE:
+		values := []*commonpb.AnyValue{}
+		for _, item := range sliceValues(v) {
+			values = append(values, anyValue(item))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+	}
+}
+
+func sliceValues(v attribute.Value) []attribute.Value {
+	values := []attribute.Value{}
+	switch v.Type() {
+	case attribute.BOOLSLICE:
+		for _, item := range v.AsBoolSlice() {
+			values = append(values, attribute.BoolValue(item))
+		}
+	case attribute.INT64SLICE:
+		for _, item := range v.AsInt64Slice() {
+			values = append(values, attribute.Int64Value(item))
+		}
+	case attribute.FLOAT64SLICE:
+		for _, item := range v.AsFloat64Slice() {
+			values = append(values, attribute.Float64Value(item))
+		}
+	case attribute.STRINGSLICE:
+		for _, item := range v.AsStringSlice() {
+			values = append(values, attribute.StringValue(item))
+		}
+	}
+
+	return values
+}
+
+func NewOTLPSink(cfg config.AppConfig, target o11y.OTLPTarget) *OTLPSink {
+	s := &OTLPSink{
+		collector: newCollector(cfg, target.Endpoint, target.Headers),
+		resource:  &resourcepb.Resource{},
+		scope:     &commonpb.InstrumentationScope{Name: "github.com/neonmei/challenge_urlshortener/platform/analytics"},
+	}
+
+	if target.Resource != nil {
+		s.resource.Attributes = keyValues(target.Resource.Attributes())
+	}
+
+	return s
+}
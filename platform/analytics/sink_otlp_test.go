@@ -0,0 +1,82 @@
+package analytics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/o11y"
+	"github.com/neonmei/challenge_urlshortener/platform/o11y/semconv"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func attributeMap(kvs []*commonpb.KeyValue) map[string]string {
+	result := map[string]string{}
+	for _, kv := range kvs {
+		result[kv.Key] = kv.Value.GetStringValue()
+	}
+
+	return result
+}
+
+func TestOTLPSinkExportsLogRecords(t *testing.T) {
+	requests := make(chan *collogspb.ExportLogsServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "team-key", r.Header.Get("x-honeycomb-team"))
+
+		body, _ := io.ReadAll(r.Body)
+		request := &collogspb.ExportLogsServiceRequest{}
+		assert.NoError(t, proto.Unmarshal(body, request))
+		requests <- request
+	}))
+	defer server.Close()
+
+	sink := NewOTLPSink(config.Load(), o11y.OTLPTarget{
+		Endpoint: server.URL + "/v1/logs",
+		Headers:  map[string]string{"x-honeycomb-team": "team-key"},
+		Resource: resource.NewSchemaless(attribute.String("service.name", "shortener")),
+	})
+
+	events := testEvents(2)
+	events[0].ReferrerHost = "news.ycombinator.com"
+	assert.NoError(t, sink.Write(context.Background(), events))
+
+	request := <-requests
+	assert.Len(t, request.ResourceLogs, 1)
+	assert.Equal(t, "shortener", attributeMap(request.ResourceLogs[0].Resource.Attributes)["service.name"])
+
+	records := request.ResourceLogs[0].ScopeLogs[0].LogRecords
+	assert.Len(t, records, 2)
+	assert.Equal(t, uint64(events[0].At.UnixNano()), records[0].TimeUnixNano)
+	assert.Equal(t, clickEventName, records[0].EventName)
+
+	first := attributeMap(records[0].Attributes)
+	assert.Equal(t, "abc", first[semconv.UrlId])
+	assert.Equal(t, "news.ycombinator.com", first[semconv.ClickReferrer])
+	assert.Equal(t, "AR", first[semconv.ClickCountry])
+
+	// REF: unknown values are left out rather than sent empty
+	_, found := attributeMap(records[1].Attributes)[semconv.ClickReferrer]
+	assert.False(t, found)
+	_, found = first[semconv.ClickLanguage]
+	assert.False(t, found)
+}
+
+func TestOTLPSinkRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	sink := NewOTLPSink(config.Load(), o11y.OTLPTarget{Endpoint: server.URL})
+	assert.ErrorIs(t, sink.Write(context.Background(), testEvents(1)), ErrCollectorRejected)
+}
@@ -0,0 +1,166 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+	"github.com/neonmei/challenge_urlshortener/platform/o11y/semconv"
+	"github.com/neonmei/challenge_urlshortener/platform/resilience"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	ErrCollectorRejected = errors.New("collector rejected click events")
+	ErrSinkNotConfigured = errors.New("click sink is missing settings")
+	errCollectorBusy     = errors.New("collector is temporarily unavailable")
+)
+
+// collector posts export payloads, retrying network errors and the answers
+// that ask to try again later: 429, 502, 503 and 504
+type collector struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+	retry   resilience.RetryConfig
+}
+
+func (c collector) post(ctx context.Context, contentType string, body []byte) error {
+	return resilience.Retry(ctx, c.retry, retryableExport, func() error {
+		return c.send(ctx, contentType, body)
+	})
+}
+
+func (c collector) send(ctx context.Context, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+		return errors.Join(ErrCollectorRejected, errCollectorBusy, fmt.Errorf("%s answered %d", c.url, resp.StatusCode))
+	default:
+		return errors.Join(ErrCollectorRejected, fmt.Errorf("%s answered %d", c.url, resp.StatusCode))
+	}
+}
+
+func retryableExport(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	return !errors.Is(err, ErrCollectorRejected) || errors.Is(err, errCollectorBusy)
+}
+
+func newCollector(cfg config.AppConfig, url string, headers map[string]string) collector {
+	return collector{
+		client:  &http.Client{},
+		url:     url,
+		headers: headers,
+		retry: resilience.RetryConfig{
+			Retries:    cfg.Clicks.Retries,
+			Backoff:    cfg.Clicks.RetryBackoff,
+			MaxBackoff: cfg.Clicks.RetryMaxBackoff,
+		},
+	}
+}
+
+// WebhookSink posts batches as a JSON object with an events list. Batches
+// that cannot be delivered are spilled to disk, when configured, and
+// delivered before new ones once the collector recovers
+type WebhookSink struct {
+	collector collector
+	spill     *spill
+	spilled   metric.Int64Counter
+}
+
+func (s *WebhookSink) Write(ctx context.Context, events []domain.ClickEvent) error {
+	err := s.deliver(ctx, events)
+	if s.spill == nil {
+		return err
+	}
+
+	if err != nil {
+		if spillErr := s.spill.Put(events); spillErr != nil {
+			return errors.Join(err, spillErr)
+		}
+
+		s.spilled.Add(context.Background(), int64(len(events)))
+		slog.Warn("click webhook unavailable, events spilled to disk", "events", len(events), "error", err)
+		return nil
+	}
+
+	if delivered, err := s.spill.Drain(ctx, s.deliver); err != nil {
+		slog.Warn("cannot deliver spilled click events", "delivered", delivered, "error", err)
+	}
+
+	return nil
+}
+
+func (s *WebhookSink) deliver(ctx context.Context, events []domain.ClickEvent) error {
+	body, err := json.Marshal(dtos.FromClickEvents(events))
+	if err != nil {
+		return err
+	}
+
+	return s.collector.post(ctx, "application/json", body)
+}
+
+func NewWebhookSink(cfg config.AppConfig) (*WebhookSink, error) {
+	if cfg.Clicks.WebhookUrl == "" {
+		return nil, fmt.Errorf("%w: webhook requires SHORTENER_CLICKS_WEBHOOK_URL", ErrSinkNotConfigured)
+	}
+
+	m := otel.GetMeterProvider().Meter("analytics")
+	c, err := m.Int64Counter(
+		semconv.MetricClicksSpilled,
+		metric.WithDescription("Number of click events spilled to disk because the webhook was unavailable."),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{}
+	if cfg.Clicks.WebhookToken != "" {
+		headers["Authorization"] = cfg.Clicks.WebhookToken
+	}
+
+	s := &WebhookSink{
+		collector: newCollector(cfg, cfg.Clicks.WebhookUrl, headers),
+		spilled:   c,
+	}
+
+	if cfg.Clicks.SpillDir != "" {
+		if s.spill, err = newSpill(cfg.Clicks.SpillDir, cfg.Clicks.SpillMaxBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
@@ -0,0 +1,135 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+	"github.com/stretchr/testify/assert"
+)
+
+type webhookCollector struct {
+	mu       sync.Mutex
+	status   atomic.Int32
+	requests atomic.Int32
+	events   []dtos.ClickEvent
+	token    string
+}
+
+func (c *webhookCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.requests.Add(1)
+	if status := int(c.status.Load()); status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+
+	var batch dtos.ClickBatch
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, batch.Events...)
+	c.token = r.Header.Get("Authorization")
+}
+
+func (c *webhookCollector) received() []dtos.ClickEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]dtos.ClickEvent{}, c.events...)
+}
+
+func newWebhookTest(t *testing.T, spillDir string) (*WebhookSink, *webhookCollector) {
+	collector := &webhookCollector{}
+	collector.status.Store(http.StatusOK)
+	server := httptest.NewServer(collector)
+	t.Cleanup(server.Close)
+
+	cfg := config.Load()
+	cfg.Clicks.WebhookUrl = server.URL
+	cfg.Clicks.WebhookToken = "collector-secret"
+	cfg.Clicks.Retries = 2
+	cfg.Clicks.RetryBackoff = time.Millisecond
+	cfg.Clicks.RetryMaxBackoff = time.Millisecond
+	cfg.Clicks.SpillDir = spillDir
+
+	sink, err := NewWebhookSink(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sink, collector
+}
+
+func TestWebhookSinkPostsBatches(t *testing.T) {
+	sink, collector := newWebhookTest(t, "")
+	assert.NoError(t, sink.Write(context.Background(), testEvents(2)))
+
+	received := collector.received()
+	assert.Len(t, received, 2)
+	assert.Equal(t, "abc", received[0].URLId)
+	assert.Equal(t, "collector-secret", collector.token)
+}
+
+func TestWebhookSinkRetries(t *testing.T) {
+	sink, collector := newWebhookTest(t, "")
+
+	collector.status.Store(http.StatusServiceUnavailable)
+	assert.ErrorIs(t, sink.Write(context.Background(), testEvents(1)), ErrCollectorRejected)
+	assert.Equal(t, int32(3), collector.requests.Load())
+
+	// REF: client errors will not get better by retrying
+	collector.requests.Store(0)
+	collector.status.Store(http.StatusBadRequest)
+	assert.ErrorIs(t, sink.Write(context.Background(), testEvents(1)), ErrCollectorRejected)
+	assert.Equal(t, int32(1), collector.requests.Load())
+}
+
+func TestWebhookSinkSpillsUntilRecovery(t *testing.T) {
+	sink, collector := newWebhookTest(t, t.TempDir())
+	events := testEvents(3)
+
+	collector.status.Store(http.StatusBadGateway)
+	assert.NoError(t, sink.Write(context.Background(), events[:1]))
+	assert.NoError(t, sink.Write(context.Background(), events[1:2]))
+	assert.Empty(t, collector.received())
+
+	// REF: spilled batches follow the first successful delivery
+	collector.status.Store(http.StatusOK)
+	assert.NoError(t, sink.Write(context.Background(), events[2:]))
+
+	received := collector.received()
+	assert.Len(t, received, 3)
+	assert.Equal(t, events[2].At, received[0].At)
+	assert.Equal(t, events[0].At, received[1].At)
+	assert.Equal(t, events[1].At, received[2].At)
+
+	files, _, err := sink.spill.files()
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestWebhookSinkFailsWhenSpillIsFull(t *testing.T) {
+	sink, collector := newWebhookTest(t, t.TempDir())
+	sink.spill.maxBytes = 1
+
+	collector.status.Store(http.StatusServiceUnavailable)
+	err := sink.Write(context.Background(), testEvents(1))
+	assert.ErrorIs(t, err, ErrCollectorRejected)
+	assert.ErrorIs(t, err, ErrSpillFull)
+}
+
+func TestWebhookSinkRequiresURL(t *testing.T) {
+	_, err := NewWebhookSink(config.Load())
+	assert.ErrorIs(t, err, ErrSinkNotConfigured)
+}
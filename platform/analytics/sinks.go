@@ -0,0 +1,85 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/o11y"
+)
+
+// SinkNames lists what cfg.Clicks.Sinks accepts
+var SinkNames = []string{"log", "file", "webhook", "otlp"}
+
+// NewClickSink builds the sinks listed in cfg.Clicks.Sinks, writing to every
+// one of them when there are several. otlp is only used by the otlp sink
+func NewClickSink(cfg config.AppConfig, otlp o11y.OTLPTarget) (domain.ClickSink, error) {
+	sinks := MultiSink{}
+	for _, name := range cfg.Clicks.Sinks {
+		sink, err := newSink(cfg, strings.TrimSpace(name), otlp)
+		if err != nil {
+			sinks.Close()
+			return nil, err
+		}
+
+		sinks = append(sinks, sink)
+	}
+
+	if len(sinks) == 1 {
+		return sinks[0], nil
+	}
+
+	return sinks, nil
+}
+
+func newSink(cfg config.AppConfig, name string, otlp o11y.OTLPTarget) (domain.ClickSink, error) {
+	switch name {
+	case "log":
+		return NewLogSink(slog.Default()), nil
+	case "file":
+		return NewFileSink(cfg)
+	case "webhook":
+		return NewWebhookSink(cfg)
+	case "otlp":
+		return NewOTLPSink(cfg, otlp), nil
+	default:
+		return nil, fmt.Errorf("%w: %s, expected any of %v", domain.ErrUnknownClickSink, name, SinkNames)
+	}
+}
+
+// MultiSink writes every batch to all of its sinks concurrently. A batch
+// fails when any sink fails, even if the others took it
+type MultiSink []domain.ClickSink
+
+func (m MultiSink) Write(ctx context.Context, events []domain.ClickEvent) error {
+	wg := sync.WaitGroup{}
+	errs := make([]error, len(m))
+	for i, sink := range m {
+		wg.Add(1)
+		go func(i int, sink domain.ClickSink) {
+			defer wg.Done()
+			errs[i] = sink.Write(ctx, events)
+		}(i, sink)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Close closes the sinks holding resources, such as open files
+func (m MultiSink) Close() error {
+	errs := []error{}
+	for _, sink := range m {
+		if closer, ok := sink.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
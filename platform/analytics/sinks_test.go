@@ -0,0 +1,55 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/o11y"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingSink struct {
+	err error
+}
+
+func (s failingSink) Write(context.Context, []domain.ClickEvent) error {
+	return s.err
+}
+
+func TestNewClickSink(t *testing.T) {
+	cfg := config.Load()
+	sink, err := NewClickSink(cfg, o11y.OTLPTarget{})
+	assert.NoError(t, err)
+	assert.IsType(t, LogSink{}, sink)
+
+	cfg.Clicks.Sinks = []string{"file", " otlp"}
+	cfg.Clicks.FilePath = filepath.Join(t.TempDir(), "clicks.jsonl")
+	sink, err = NewClickSink(cfg, o11y.OTLPTarget{})
+	assert.NoError(t, err)
+	assert.Len(t, sink, 2)
+	assert.NoError(t, sink.(MultiSink).Close())
+
+	cfg.Clicks.Sinks = []string{"file", "kafka"}
+	_, err = NewClickSink(cfg, o11y.OTLPTarget{})
+	assert.ErrorIs(t, err, domain.ErrUnknownClickSink)
+
+	cfg.Clicks.Sinks = []string{"webhook"}
+	_, err = NewClickSink(cfg, o11y.OTLPTarget{})
+	assert.ErrorIs(t, err, ErrSinkNotConfigured)
+}
+
+func TestMultiSinkWritesEverywhere(t *testing.T) {
+	first, second := &memorySink{}, &memorySink{}
+	assert.NoError(t, MultiSink{first, second}.Write(context.Background(), testEvents(2)))
+	assert.Equal(t, 2, first.count())
+	assert.Equal(t, 2, second.count())
+
+	// REF: one failing sink does not keep events from the others
+	broken := errors.New("broken")
+	assert.ErrorIs(t, MultiSink{failingSink{broken}, first}.Write(context.Background(), testEvents(1)), broken)
+	assert.Equal(t, 3, first.count())
+}
@@ -0,0 +1,125 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+var ErrSpillFull = errors.New("click spill directory is full")
+
+// spill keeps undelivered batches on disk, a JSONL file each, until they can
+// be delivered. It never takes more than maxBytes of disk
+type spill struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	seq      uint64
+}
+
+// Put stores a batch, failing with ErrSpillFull rather than growing past maxBytes
+func (s *spill) Put(events []domain.ClickEvent) error {
+	lines, err := encodeLines(events)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, used, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	if used+int64(len(lines)) > s.maxBytes {
+		return fmt.Errorf("%w: %d batches take %d bytes", ErrSpillFull, len(files), used)
+	}
+
+	// REF: written aside and renamed, so Drain never reads a partial batch
+	s.seq++
+	name := filepath.Join(s.dir, fmt.Sprintf("spill-%020d-%06d.jsonl", time.Now().UnixNano(), s.seq%1_000_000))
+	if err := os.WriteFile(name+".tmp", lines, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(name+".tmp", name)
+}
+
+// Drain delivers spilled batches oldest first and removes them, it stops at
+// the first batch that cannot be delivered
+func (s *spill) Drain(ctx context.Context, deliver func(context.Context, []domain.ClickEvent) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, _, err := s.files()
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, name := range files {
+		if err := ctx.Err(); err != nil {
+			return delivered, err
+		}
+
+		lines, err := os.ReadFile(name)
+		if err != nil {
+			return delivered, err
+		}
+
+		events, err := decodeLines(lines)
+		if err != nil {
+			slog.Warn("discarding corrupt click spill", "file", name, "error", err)
+			os.Remove(name)
+			continue
+		}
+
+		if err := deliver(ctx, events); err != nil {
+			return delivered, err
+		}
+
+		if err := os.Remove(name); err != nil {
+			return delivered, err
+		}
+		delivered += len(events)
+	}
+
+	return delivered, nil
+}
+
+// files lists spilled batches oldest first along with the disk they take
+func (s *spill) files() ([]string, int64, error) {
+	files, err := filepath.Glob(filepath.Join(escapeGlob(s.dir), "spill-*.jsonl"))
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Strings(files)
+
+	used := int64(0)
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, 0, err
+		}
+		used += info.Size()
+	}
+
+	return files, used, nil
+}
+
+func newSpill(dir string, maxBytes int64) (*spill, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &spill{dir: dir, maxBytes: maxBytes}, nil
+}
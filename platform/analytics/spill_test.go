@@ -0,0 +1,80 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpillDrainsInOrder(t *testing.T) {
+	s, err := newSpill(t.TempDir(), 1<<20)
+	assert.NoError(t, err)
+
+	events := testEvents(3)
+	assert.NoError(t, s.Put(events[:1]))
+	assert.NoError(t, s.Put(events[1:]))
+
+	delivered := []domain.ClickEvent{}
+	n, err := s.Drain(context.Background(), func(_ context.Context, batch []domain.ClickEvent) error {
+		delivered = append(delivered, batch...)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, events, delivered)
+
+	files, used, err := s.files()
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+	assert.Zero(t, used)
+}
+
+func TestSpillStopsAtFirstFailure(t *testing.T) {
+	s, err := newSpill(t.TempDir(), 1<<20)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Put(testEvents(1)))
+	assert.NoError(t, s.Put(testEvents(2)))
+
+	unavailable := errors.New("unavailable")
+	calls := 0
+	n, err := s.Drain(context.Background(), func(context.Context, []domain.ClickEvent) error {
+		calls++
+		return unavailable
+	})
+	assert.ErrorIs(t, err, unavailable)
+	assert.Zero(t, n)
+	assert.Equal(t, 1, calls)
+
+	// REF: undelivered batches are kept for the next attempt
+	files, _, err := s.files()
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestSpillIsBounded(t *testing.T) {
+	line, _ := encodeLines(testEvents(1))
+	s, err := newSpill(t.TempDir(), int64(2*len(line)))
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Put(testEvents(1)))
+	assert.NoError(t, s.Put(testEvents(1)))
+	assert.ErrorIs(t, s.Put(testEvents(1)), ErrSpillFull)
+}
+
+func TestSpillDiscardsCorruptBatches(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSpill(dir, 1<<20)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "spill-0.jsonl"), []byte("{not json\n"), 0o644))
+	assert.NoError(t, s.Put(testEvents(1)))
+
+	n, err := s.Drain(context.Background(), func(context.Context, []domain.ClickEvent) error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
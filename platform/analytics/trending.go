@@ -0,0 +1,88 @@
+package analytics
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/sketch"
+)
+
+var ErrTrendingConfig = errors.New("trending needs a positive capacity and a resolution within the max window")
+
+// trendingSlot counts the redirects of one resolution wide slot, numbered
+// from the unix epoch
+type trendingSlot struct {
+	number int64
+	topK   *sketch.TopK
+}
+
+// Trending keeps the most redirected links over a sliding window in a ring of
+// fixed width slots, each one a space-saving summary. Queries merge the slots
+// the window covers, the current one included, so the window is rounded up
+// to whole slots
+type Trending struct {
+	mu         sync.Mutex
+	capacity   int
+	resolution time.Duration
+	slots      []trendingSlot
+}
+
+// Hit counts a redirect, it only takes a short lock
+func (t *Trending) Hit(urlID string, at time.Time) {
+	number := t.number(at)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	slot := &t.slots[number%int64(len(t.slots))]
+	if slot.topK == nil || slot.number != number {
+		// REF: the slot held an older one, which already left every window.
+		// The capacity was validated, this cannot fail
+		topK, _ := sketch.NewTopK(t.capacity)
+		*slot = trendingSlot{number: number, topK: topK}
+	}
+
+	slot.topK.Add(urlID, 1)
+}
+
+// Top returns the limit most redirected links within window before now
+func (t *Trending) Top(now time.Time, window time.Duration, limit int) []domain.TrendingURL {
+	current := t.number(now)
+	oldest := current - int64((window+t.resolution-1)/t.resolution) + 1
+	merged, _ := sketch.NewTopK(t.capacity)
+
+	t.mu.Lock()
+	for _, slot := range t.slots {
+		if slot.topK != nil && slot.number >= oldest && slot.number <= current {
+			merged.Merge(slot.topK)
+		}
+	}
+	t.mu.Unlock()
+
+	top := merged.Top(limit)
+	result := make([]domain.TrendingURL, 0, len(top))
+	for _, c := range top {
+		result = append(result, domain.TrendingURL{URLId: c.Key, Hits: c.Count, Error: c.Error})
+	}
+
+	return result
+}
+
+func (t *Trending) number(at time.Time) int64 {
+	return at.UnixNano() / int64(t.resolution)
+}
+
+// NewTrending sizes the ring to cover MaxWindow plus the slot in progress
+func NewTrending(cfg config.AppConfig) (*Trending, error) {
+	if cfg.Trending.Capacity <= 0 || cfg.Trending.Resolution <= 0 || cfg.Trending.MaxWindow < cfg.Trending.Resolution {
+		return nil, ErrTrendingConfig
+	}
+
+	return &Trending{
+		capacity:   cfg.Trending.Capacity,
+		resolution: cfg.Trending.Resolution,
+		slots:      make([]trendingSlot, int(cfg.Trending.MaxWindow/cfg.Trending.Resolution)+1),
+	}, nil
+}
@@ -0,0 +1,95 @@
+package analytics
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTrending(t *testing.T) *Trending {
+	cfg := config.Load()
+	cfg.Trending.Resolution = time.Minute
+	cfg.Trending.MaxWindow = 10 * time.Minute
+	trending, err := NewTrending(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return trending
+}
+
+func TestTrendingSlidingWindow(t *testing.T) {
+	trending := newTestTrending(t)
+	now := time.Date(2025, 2, 5, 10, 30, 30, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		trending.Hit("old", now.Add(-8*time.Minute))
+	}
+	trending.Hit("recent", now.Add(-4*time.Minute))
+	trending.Hit("recent", now.Add(-time.Minute))
+	trending.Hit("now", now)
+
+	assert.Equal(t, []domain.TrendingURL{
+		{URLId: "recent", Hits: 2},
+		{URLId: "now", Hits: 1},
+	}, trending.Top(now, 5*time.Minute, 20))
+
+	// REF: windows are rounded up to whole slots, the current one included
+	assert.Equal(t, []domain.TrendingURL{{URLId: "now", Hits: 1}}, trending.Top(now, time.Second, 20))
+
+	assert.Equal(t, []domain.TrendingURL{
+		{URLId: "old", Hits: 3},
+		{URLId: "recent", Hits: 2},
+	}, trending.Top(now, 10*time.Minute, 2))
+}
+
+func TestTrendingReusesSlots(t *testing.T) {
+	trending := newTestTrending(t)
+	now := time.Date(2025, 2, 5, 10, 30, 0, 0, time.UTC)
+
+	// REF: the ring has eleven slots, eleven minutes ago shares the slot of now
+	trending.Hit("stale", now.Add(-11*time.Minute))
+	trending.Hit("fresh", now)
+
+	assert.Equal(t, []domain.TrendingURL{{URLId: "fresh", Hits: 1}}, trending.Top(now, 10*time.Minute, 20))
+	assert.Empty(t, trending.Top(now.Add(time.Hour), 10*time.Minute, 20))
+}
+
+func TestTrendingConcurrentHits(t *testing.T) {
+	trending := newTestTrending(t)
+	now := time.Now()
+
+	wg := sync.WaitGroup{}
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				trending.Hit(fmt.Sprintf("link-%d", worker%2), now)
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	assert.Equal(t, []domain.TrendingURL{
+		{URLId: "link-0", Hits: 400},
+		{URLId: "link-1", Hits: 400},
+	}, trending.Top(now, time.Minute, 20))
+}
+
+func TestNewTrendingRejectsInvalid(t *testing.T) {
+	cfg := config.Load()
+	cfg.Trending.Capacity = 0
+	_, err := NewTrending(cfg)
+	assert.ErrorIs(t, err, ErrTrendingConfig)
+
+	cfg = config.Load()
+	cfg.Trending.MaxWindow = time.Second
+	_, err = NewTrending(cfg)
+	assert.ErrorIs(t, err, ErrTrendingConfig)
+}
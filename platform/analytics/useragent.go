@@ -0,0 +1,64 @@
+package analytics
+
+import (
+	"strings"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+// browserTokens are checked in order, as most browsers also claim to be
+// Chrome or Safari in their user agent
+var browserTokens = []struct {
+	token  string
+	family string
+}{
+	{"FBAN/", "Facebook"},
+	{"FBAV/", "Facebook"},
+	{"Instagram", "Instagram"},
+	{"Twitter", "Twitter"},
+	{"Line/", "Line"},
+	{"Edg", "Edge"},
+	{"OPR/", "Opera"},
+	{"Opera", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"YaBrowser/", "Yandex"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Chromium/", "Chrome"},
+	{"FxiOS/", "Firefox"},
+	{"Firefox/", "Firefox"},
+	{"MSIE ", "Internet Explorer"},
+	{"Trident/", "Internet Explorer"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"Wget/", "Wget"},
+}
+
+// ParseUserAgent returns the browser family and device class of a user agent,
+// it only knows the most common ones and says Other for the rest
+func ParseUserAgent(ua string) (browser string, device string) {
+	browser = "Other"
+	for _, b := range browserTokens {
+		if strings.Contains(ua, b.token) {
+			browser = b.family
+			break
+		}
+	}
+
+	return browser, deviceClass(ua)
+}
+
+func deviceClass(ua string) string {
+	switch {
+	case strings.Contains(ua, "iPad") || strings.Contains(ua, "Tablet") ||
+		(strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile")):
+		return domain.DeviceTablet
+	case strings.Contains(ua, "Mobi") || strings.Contains(ua, "iPhone") || strings.Contains(ua, "Android"):
+		return domain.DeviceMobile
+	case strings.Contains(ua, "Windows") || strings.Contains(ua, "Macintosh") ||
+		strings.Contains(ua, "X11") || strings.Contains(ua, "CrOS"):
+		return domain.DeviceDesktop
+	default:
+		return domain.DeviceOther
+	}
+}
@@ -0,0 +1,10 @@
+package clients
+
+// Storage backends, the value of config.AppConfig.Storage.Backend
+const (
+	BackendMemory   = "memory"
+	BackendDynamo   = "dynamodb"
+	BackendSQLite   = "sqlite"
+	BackendPostgres = "postgres"
+	BackendBolt     = "bolt"
+)
@@ -0,0 +1,13 @@
+package clients
+
+import (
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+)
+
+// NewBoltClient opens or creates the embedded database file. Only one process
+// can hold it, others wait up to OpenTimeout for the file lock
+func NewBoltClient(appCfg config.AppConfig) (*bolt.DB, error) {
+	return bolt.Open(appCfg.Bolt.Path, 0o600, &bolt.Options{Timeout: appCfg.Bolt.OpenTimeout})
+}
@@ -0,0 +1,909 @@
+package dynamotest
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type item = map[string]types.AttributeValue
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenName
+	tokenValue
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits an expression, #name and :value placeholders are kept whole
+func tokenize(expression string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || c == ':' || isIdentByte(c):
+			start := i
+			i++
+			for i < len(expression) && isIdentByte(expression[i]) {
+				i++
+			}
+
+			kind := tokenIdent
+			if c == '#' {
+				kind = tokenName
+			} else if c == ':' {
+				kind = tokenValue
+			}
+
+			if i-start == 1 && kind != tokenIdent {
+				return nil, validationError("empty placeholder in expression " + expression)
+			}
+			tokens = append(tokens, token{kind: kind, text: expression[start:i]})
+		case strings.HasPrefix(expression[i:], "<>") || strings.HasPrefix(expression[i:], "<=") || strings.HasPrefix(expression[i:], ">="):
+			tokens = append(tokens, token{kind: tokenSymbol, text: expression[i : i+2]})
+			i += 2
+		case strings.ContainsRune("()=<>,+-.[]", rune(c)):
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(c)})
+			i++
+		default:
+			return nil, validationError(fmt.Sprintf("invalid character %q in expression %s", c, expression))
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+func isIdentByte(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_'
+}
+
+// placeholders resolves #name and :value tokens for every expression of a
+// request and remembers which were used, DynamoDB rejects unused ones
+type placeholders struct {
+	names      map[string]string
+	values     map[string]types.AttributeValue
+	usedNames  map[string]bool
+	usedValues map[string]bool
+}
+
+func newPlaceholders(names map[string]string, values map[string]types.AttributeValue) *placeholders {
+	return &placeholders{names: names, values: values, usedNames: map[string]bool{}, usedValues: map[string]bool{}}
+}
+
+func (p *placeholders) name(placeholder string) (string, error) {
+	name, found := p.names[placeholder]
+	if !found {
+		return "", validationError("undefined expression attribute name " + placeholder)
+	}
+
+	p.usedNames[placeholder] = true
+	return name, nil
+}
+
+func (p *placeholders) value(placeholder string) (types.AttributeValue, error) {
+	value, found := p.values[placeholder]
+	if !found {
+		return nil, validationError("undefined expression attribute value " + placeholder)
+	}
+
+	p.usedValues[placeholder] = true
+	return value, nil
+}
+
+// unused fails when the request provided placeholders no expression refers to
+func (p *placeholders) unused() error {
+	for placeholder := range p.names {
+		if !p.usedNames[placeholder] {
+			return validationError("unused expression attribute name " + placeholder)
+		}
+	}
+
+	for placeholder := range p.values {
+		if !p.usedValues[placeholder] {
+			return validationError("unused expression attribute value " + placeholder)
+		}
+	}
+
+	return nil
+}
+
+type parser struct {
+	tokens       []token
+	pos          int
+	placeholders *placeholders
+}
+
+func newParser(expression string, ph *placeholders) (*parser, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parser{tokens: tokens, placeholders: ph}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isSymbol(symbol string) bool {
+	t := p.peek()
+	return t.kind == tokenSymbol && t.text == symbol
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenIdent && strings.EqualFold(t.text, keyword)
+}
+
+// isCall is true when an identifier is immediately followed by an opening parenthesis
+func (p *parser) isCall(function string) bool {
+	next := p.tokens[min(p.pos+1, len(p.tokens)-1)]
+	return p.isKeyword(function) && next.kind == tokenSymbol && next.text == "("
+}
+
+func (p *parser) expect(symbol string) error {
+	if t := p.next(); t.kind != tokenSymbol || t.text != symbol {
+		return validationError(fmt.Sprintf("expected %q, found %q", symbol, t.text))
+	}
+	return nil
+}
+
+func (p *parser) end() error {
+	if t := p.peek(); t.kind != tokenEOF {
+		return validationError(fmt.Sprintf("unexpected %q in expression", t.text))
+	}
+	return nil
+}
+
+// path reads a top level attribute name, nested documents are not supported
+func (p *parser) path() (string, error) {
+	var name string
+	switch t := p.next(); t.kind {
+	case tokenIdent:
+		name = t.text
+	case tokenName:
+		resolved, err := p.placeholders.name(t.text)
+		if err != nil {
+			return "", err
+		}
+		name = resolved
+	default:
+		return "", validationError(fmt.Sprintf("expected an attribute name, found %q", t.text))
+	}
+
+	if p.isSymbol(".") || p.isSymbol("[") {
+		return "", validationError("nested attribute paths are not supported by the fake")
+	}
+
+	return name, nil
+}
+
+// operand is a value computed from an item, present is false for missing attributes
+type operand func(it item) (value types.AttributeValue, present bool, err error)
+
+func pathOperand(name string) operand {
+	return func(it item) (types.AttributeValue, bool, error) {
+		value, found := it[name]
+		return value, found, nil
+	}
+}
+
+func (p *parser) operand() (operand, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokenValue:
+		p.next()
+		value, err := p.placeholders.value(t.text)
+		if err != nil {
+			return nil, err
+		}
+		return func(item) (types.AttributeValue, bool, error) { return value, true, nil }, nil
+	case p.isCall("size"):
+		p.pos += 2
+		name, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return sizeOperand(name), nil
+	}
+
+	name, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	return pathOperand(name), nil
+}
+
+func sizeOperand(name string) operand {
+	return func(it item) (types.AttributeValue, bool, error) {
+		var size int
+		switch v := it[name].(type) {
+		case nil:
+			return nil, false, nil
+		case *types.AttributeValueMemberS:
+			size = len(v.Value)
+		case *types.AttributeValueMemberB:
+			size = len(v.Value)
+		case *types.AttributeValueMemberL:
+			size = len(v.Value)
+		case *types.AttributeValueMemberM:
+			size = len(v.Value)
+		default:
+			if members := setMembers(v); members != nil {
+				size = len(members)
+			} else {
+				return nil, false, validationError("size is not defined for attribute " + name)
+			}
+		}
+		return &types.AttributeValueMemberN{Value: fmt.Sprint(size)}, true, nil
+	}
+}
+
+// condition decides whether an item matches a condition, filter or key condition expression
+type condition func(it item) (bool, error)
+
+func parseCondition(expression string, ph *placeholders) (condition, error) {
+	p, err := newParser(expression, ph)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, p.end()
+}
+
+func (p *parser) or() (condition, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("OR") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		a, b := left, right
+		left = func(it item) (bool, error) {
+			if ok, err := a(it); ok || err != nil {
+				return ok, err
+			}
+			return b(it)
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) and() (condition, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("AND") {
+		p.next()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		a, b := left, right
+		left = func(it item) (bool, error) {
+			if ok, err := a(it); !ok || err != nil {
+				return ok, err
+			}
+			return b(it)
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) not() (condition, error) {
+	if !p.isKeyword("NOT") {
+		return p.primary()
+	}
+
+	p.next()
+	c, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(it item) (bool, error) {
+		ok, err := c(it)
+		return !ok, err
+	}, nil
+}
+
+func (p *parser) primary() (condition, error) {
+	if p.isSymbol("(") {
+		p.next()
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(")")
+	}
+
+	for _, function := range []string{"attribute_exists", "attribute_not_exists", "begins_with", "contains"} {
+		if p.isCall(function) {
+			p.pos += 2
+			return p.function(function)
+		}
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isKeyword("BETWEEN") {
+		p.next()
+		low, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.isKeyword("AND") {
+			return nil, validationError("BETWEEN requires AND")
+		}
+		p.next()
+
+		high, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(it item) (bool, error) {
+			above, err := compareOperands(it, left, ">=", low)
+			if !above || err != nil {
+				return false, err
+			}
+			return compareOperands(it, left, "<=", high)
+		}, nil
+	}
+
+	if p.isKeyword("IN") {
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+
+		candidates := []operand{}
+		for {
+			candidate, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, candidate)
+
+			if !p.isSymbol(",") {
+				break
+			}
+			p.next()
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return func(it item) (bool, error) {
+			for _, candidate := range candidates {
+				if ok, err := compareOperands(it, left, "=", candidate); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}, nil
+	}
+
+	t := p.next()
+	switch t.text {
+	case "=", "<>", "<", "<=", ">", ">=":
+	default:
+		return nil, validationError(fmt.Sprintf("expected a comparator, found %q", t.text))
+	}
+
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(it item) (bool, error) {
+		return compareOperands(it, left, t.text, right)
+	}, nil
+}
+
+func (p *parser) function(function string) (condition, error) {
+	name, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+
+	var argument operand
+	if function == "begins_with" || function == "contains" {
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+
+		if argument, err = p.operand(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	return func(it item) (bool, error) {
+		value, found := it[name]
+		switch function {
+		case "attribute_exists":
+			return found, nil
+		case "attribute_not_exists":
+			return !found, nil
+		}
+
+		arg, present, err := argument(it)
+		if !found || !present || err != nil {
+			return false, err
+		}
+
+		if function == "begins_with" {
+			switch v := value.(type) {
+			case *types.AttributeValueMemberS:
+				prefix, ok := arg.(*types.AttributeValueMemberS)
+				return ok && strings.HasPrefix(v.Value, prefix.Value), nil
+			case *types.AttributeValueMemberB:
+				prefix, ok := arg.(*types.AttributeValueMemberB)
+				return ok && strings.HasPrefix(string(v.Value), string(prefix.Value)), nil
+			}
+			return false, nil
+		}
+
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			sub, ok := arg.(*types.AttributeValueMemberS)
+			return ok && strings.Contains(v.Value, sub.Value), nil
+		case *types.AttributeValueMemberL:
+			for _, element := range v.Value {
+				if equalValues(element, arg) {
+					return true, nil
+				}
+			}
+		default:
+			for _, member := range setMembers(v) {
+				if equalValues(member, arg) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}, nil
+}
+
+// compareOperands is false when either side is missing or their types differ, like DynamoDB
+func compareOperands(it item, left operand, comparator string, right operand) (bool, error) {
+	a, presentA, err := left(it)
+	if err != nil {
+		return false, err
+	}
+
+	b, presentB, err := right(it)
+	if err != nil || !presentA || !presentB {
+		return false, err
+	}
+
+	switch comparator {
+	case "=":
+		return equalValues(a, b), nil
+	case "<>":
+		return !equalValues(a, b), nil
+	}
+
+	c, ok := compareValues(a, b)
+	if !ok {
+		return false, nil
+	}
+
+	switch comparator {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+// parseProjection returns the attribute names to keep
+func parseProjection(expression string, ph *placeholders) ([]string, error) {
+	p, err := newParser(expression, ph)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for {
+		name, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+
+		if !p.isSymbol(",") {
+			break
+		}
+		p.next()
+	}
+
+	return names, p.end()
+}
+
+func project(it item, names []string) item {
+	if names == nil {
+		return it
+	}
+
+	result := item{}
+	for _, name := range names {
+		if value, found := it[name]; found {
+			result[name] = value
+		}
+	}
+
+	return result
+}
+
+type updateAction struct {
+	clause string
+	path   string
+	value  operand
+}
+
+// parseUpdate reads SET, REMOVE, ADD and DELETE clauses of an update expression
+func parseUpdate(expression string, ph *placeholders) ([]updateAction, error) {
+	p, err := newParser(expression, ph)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []updateAction{}
+	clauses := map[string]bool{}
+	paths := map[string]bool{}
+	for p.peek().kind != tokenEOF {
+		t := p.next()
+		clause := strings.ToUpper(t.text)
+		if t.kind != tokenIdent || (clause != "SET" && clause != "REMOVE" && clause != "ADD" && clause != "DELETE") {
+			return nil, validationError(fmt.Sprintf("expected SET, REMOVE, ADD or DELETE, found %q", t.text))
+		}
+
+		if clauses[clause] {
+			return nil, validationError("the " + clause + " clause can only be used once")
+		}
+		clauses[clause] = true
+
+		for {
+			action := updateAction{clause: clause}
+			if action.path, err = p.path(); err != nil {
+				return nil, err
+			}
+
+			if paths[action.path] {
+				return nil, validationError("two document paths overlap: " + action.path)
+			}
+			paths[action.path] = true
+
+			switch clause {
+			case "SET":
+				if err := p.expect("="); err != nil {
+					return nil, err
+				}
+				action.value, err = p.setValue()
+			case "ADD", "DELETE":
+				action.value, err = p.operand()
+			}
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, action)
+
+			if !p.isSymbol(",") {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if len(actions) == 0 {
+		return nil, validationError("empty update expression")
+	}
+
+	return actions, nil
+}
+
+// setValue is an operand, optionally added to or subtracted from another
+func (p *parser) setValue() (operand, error) {
+	left, err := p.setOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.isSymbol("+") && !p.isSymbol("-") {
+		return left, nil
+	}
+
+	sign := p.next().text
+	right, err := p.setOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(it item) (types.AttributeValue, bool, error) {
+		a, presentA, err := left(it)
+		if err != nil {
+			return nil, false, err
+		}
+
+		b, presentB, err := right(it)
+		if err != nil {
+			return nil, false, err
+		}
+
+		x, errA := numberOf(a, presentA)
+		y, errB := numberOf(b, presentB)
+		if errA != nil || errB != nil {
+			return nil, false, validationError("incorrect operand type for operator " + sign)
+		}
+
+		if sign == "+" {
+			return &types.AttributeValueMemberN{Value: formatNumber(new(big.Rat).Add(x, y))}, true, nil
+		}
+		return &types.AttributeValueMemberN{Value: formatNumber(new(big.Rat).Sub(x, y))}, true, nil
+	}, nil
+}
+
+func (p *parser) setOperand() (operand, error) {
+	switch {
+	case p.isCall("if_not_exists"):
+		p.pos += 2
+		name, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+
+		fallback, err := p.setValue()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return func(it item) (types.AttributeValue, bool, error) {
+			if value, found := it[name]; found {
+				return value, true, nil
+			}
+			return fallback(it)
+		}, nil
+	case p.isCall("list_append"):
+		p.pos += 2
+		first, err := p.setValue()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+
+		second, err := p.setValue()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return func(it item) (types.AttributeValue, bool, error) {
+			result := []types.AttributeValue{}
+			for _, o := range []operand{first, second} {
+				value, present, err := o(it)
+				if err != nil {
+					return nil, false, err
+				}
+
+				list, ok := value.(*types.AttributeValueMemberL)
+				if !present || !ok {
+					return nil, false, validationError("list_append requires two lists")
+				}
+				result = append(result, list.Value...)
+			}
+			return &types.AttributeValueMemberL{Value: result}, true, nil
+		}, nil
+	}
+
+	return p.operand()
+}
+
+func numberOf(v types.AttributeValue, present bool) (*big.Rat, error) {
+	n, ok := v.(*types.AttributeValueMemberN)
+	if !present || !ok {
+		return nil, validationError("not a number")
+	}
+
+	return parseNumber(n.Value)
+}
+
+// applyUpdate evaluates every action against the original item, as DynamoDB
+// does, then writes them. It returns the names of the attributes written
+func applyUpdate(original item, actions []updateAction) (item, []string, error) {
+	result := copyItem(original)
+	updated := []string{}
+
+	for _, action := range actions {
+		switch action.clause {
+		case "REMOVE":
+			delete(result, action.path)
+			continue
+		case "SET":
+			value, present, err := action.value(original)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !present {
+				return nil, nil, validationError("the provided expression refers to an attribute that does not exist: " + action.path)
+			}
+			result[action.path] = copyValue(value)
+		case "ADD", "DELETE":
+			value, _, err := action.value(original)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			current, found := original[action.path]
+			merged, err := mergeValue(action.clause, current, found, value)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if merged == nil {
+				delete(result, action.path)
+				continue
+			}
+			result[action.path] = merged
+		}
+
+		updated = append(updated, action.path)
+	}
+
+	return result, updated, nil
+}
+
+// mergeValue implements ADD on numbers and sets and DELETE on sets, nil
+// means the attribute is left empty and must be removed
+func mergeValue(clause string, current types.AttributeValue, found bool, value types.AttributeValue) (types.AttributeValue, error) {
+	if n, ok := value.(*types.AttributeValueMemberN); ok && clause == "ADD" {
+		y, err := parseNumber(n.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		x := new(big.Rat)
+		if found {
+			if x, err = numberOf(current, true); err != nil {
+				return nil, validationError("ADD requires a number attribute")
+			}
+		}
+
+		return &types.AttributeValueMemberN{Value: formatNumber(x.Add(x, y))}, nil
+	}
+
+	members := setMembers(value)
+	if members == nil {
+		return nil, validationError(clause + " requires a number or a set")
+	}
+
+	existing := []types.AttributeValue{}
+	if found {
+		existing = setMembers(current)
+		if existing == nil || fmt.Sprintf("%T", current) != fmt.Sprintf("%T", value) {
+			return nil, validationError(clause + " requires an attribute of the same set type")
+		}
+	}
+
+	result := []types.AttributeValue{}
+	for _, member := range existing {
+		if clause == "DELETE" && containsValue(members, member) {
+			continue
+		}
+		result = append(result, member)
+	}
+
+	if clause == "ADD" {
+		for _, member := range members {
+			if !containsValue(result, member) {
+				result = append(result, member)
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	return buildSet(value, result), nil
+}
+
+func containsValue(values []types.AttributeValue, v types.AttributeValue) bool {
+	for _, other := range values {
+		if equalValues(other, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildSet makes a set of the same type as like out of scalar members
+func buildSet(like types.AttributeValue, members []types.AttributeValue) types.AttributeValue {
+	switch like.(type) {
+	case *types.AttributeValueMemberSS:
+		result := &types.AttributeValueMemberSS{}
+		for _, m := range members {
+			result.Value = append(result.Value, m.(*types.AttributeValueMemberS).Value)
+		}
+		return result
+	case *types.AttributeValueMemberNS:
+		result := &types.AttributeValueMemberNS{}
+		for _, m := range members {
+			result.Value = append(result.Value, m.(*types.AttributeValueMemberN).Value)
+		}
+		return result
+	default:
+		result := &types.AttributeValueMemberBS{}
+		for _, m := range members {
+			result.Value = append(result.Value, m.(*types.AttributeValueMemberB).Value)
+		}
+		return result
+	}
+}
@@ -0,0 +1,114 @@
+package dynamotest
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditionExpressions(t *testing.T) {
+	it := item{
+		"url_id":  s("asd"),
+		"version": n("2"),
+		"enabled": &types.AttributeValueMemberBOOL{Value: true},
+		"tags":    &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+	}
+	values := item{
+		":one":   n("1"),
+		":two":   n("2.0"),
+		":three": n("3"),
+		":asd":   s("asd"),
+		":a":     s("a"),
+		":true":  &types.AttributeValueMemberBOOL{Value: true},
+	}
+
+	cases := map[string]bool{
+		"attribute_exists(url_id)":                          true,
+		"attribute_not_exists(url_id)":                      false,
+		"attribute_not_exists(#version) OR #version = :two": true,
+		"#version = :one":                                   false,
+		"#version <> :one AND enabled = :true":              true,
+		"NOT (#version > :one)":                             false,
+		"#version BETWEEN :one AND :three":                  true,
+		"#version IN (:one, :three)":                        false,
+		"begins_with(url_id, :a)":                           true,
+		"contains(tags, :a)":                                true,
+		"size(url_id) = :three":                             true,
+		"missing = :one":                                    false,
+		"missing <> :one":                                   false,
+
+		// REF: values of different types are never equal nor ordered
+		"url_id < :one": false,
+	}
+
+	for expression, expected := range cases {
+		c, err := parseCondition(expression, newPlaceholders(map[string]string{"#version": "version"}, values))
+		if !assert.NoError(t, err, expression) {
+			continue
+		}
+
+		result, err := c(it)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+}
+
+func TestInvalidExpressions(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"url_id =",
+		"url_id = :missing",
+		"#missing = :one",
+		"(url_id = :one",
+		"url_id = :one extra",
+		"info.nested = :one",
+		"url_id ! :one",
+	} {
+		_, err := parseCondition(expression, newPlaceholders(nil, item{":one": n("1")}))
+		assert.True(t, isValidation(err), expression)
+	}
+
+	for _, expression := range []string{
+		"SET",
+		"SET a = :one SET b = :one",
+		"SET a = :one, a = :one",
+		"UPSERT a = :one",
+	} {
+		_, err := parseUpdate(expression, newPlaceholders(nil, item{":one": n("1")}))
+		assert.True(t, isValidation(err), expression)
+	}
+}
+
+func TestApplyUpdate(t *testing.T) {
+	original := item{
+		"counter": n("1.5"),
+		"swap":    s("old"),
+		"tags":    &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+	}
+	values := item{
+		":one":  n("1"),
+		":tags": &types.AttributeValueMemberSS{Value: []string{"b"}},
+		":new":  s("new"),
+	}
+
+	actions, err := parseUpdate("SET counter = counter + :one, other = swap, swap = :new DELETE tags :tags", newPlaceholders(nil, values))
+	assert.NoError(t, err)
+
+	updated, names, err := applyUpdate(original, actions)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"counter", "other", "swap", "tags"}, names)
+	assert.Equal(t, n("2.5"), updated["counter"])
+
+	// REF: every SET reads the item as it was before the update
+	assert.Equal(t, s("old"), updated["other"])
+	assert.Equal(t, s("new"), updated["swap"])
+	assert.Equal(t, &types.AttributeValueMemberSS{Value: []string{"a"}}, updated["tags"])
+	assert.Equal(t, s("old"), original["swap"])
+
+	// REF: arithmetic on missing attributes fails
+	actions, err = parseUpdate("SET missing = missing + :one", newPlaceholders(nil, values))
+	assert.NoError(t, err)
+	_, _, err = applyUpdate(original, actions)
+	assert.True(t, isValidation(err))
+}
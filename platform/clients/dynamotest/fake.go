@@ -0,0 +1,541 @@
+// Package dynamotest is a stateful in-memory fake of clients.DynamoDbClient,
+// so DynamoDB repositories can be tested end to end without a network.
+//
+// It understands condition, key condition, filter, projection and update
+// expressions on top level attributes, and fails like DynamoDB does: with
+// *types.ConditionalCheckFailedException, *types.ResourceNotFoundException or
+// a ValidationException wrapped in a *smithy.OperationError. Scans and queries
+// walk items ordered by key, ignoring the 1MB page size
+package dynamotest
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	awsDynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/smithy-go"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+)
+
+// KeySchema names the partition key and the optional sort key
+type KeySchema struct {
+	HashKey  string
+	RangeKey string
+}
+
+// Table describes a table and its global secondary indexes
+type Table struct {
+	Name    string
+	Key     KeySchema
+	Indexes map[string]KeySchema
+}
+
+// AppTables are the tables the repositories expect, named after cfg
+func AppTables(cfg config.AppConfig) []Table {
+	return []Table{
+		{
+			Name: cfg.Dynamo.TableName,
+			Key:  KeySchema{HashKey: "url_id"},
+			Indexes: map[string]KeySchema{
+				cfg.Dynamo.CreatedByIndex: {HashKey: "created_by", RangeKey: "created_at"},
+			},
+		},
+		{
+			Name: cfg.Dynamo.HitsTableName,
+			Key:  KeySchema{HashKey: "hit_key", RangeKey: "bucket_start"},
+		},
+		{
+			Name: cfg.Dynamo.SequencesTableName,
+			Key:  KeySchema{HashKey: "sequence_name"},
+		},
+	}
+}
+
+type table struct {
+	Table
+	items map[string]item
+}
+
+// Fake is safe for concurrent use, every request is applied atomically
+type Fake struct {
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+var _ clients.DynamoDbClient = (*Fake)(nil)
+
+// NewFake creates the given tables, empty
+func NewFake(tables ...Table) *Fake {
+	f := &Fake{tables: map[string]*table{}}
+	for _, t := range tables {
+		f.tables[t.Name] = &table{Table: t, items: map[string]item{}}
+	}
+
+	return f
+}
+
+func validationError(message string) error {
+	return &smithy.GenericAPIError{Code: "ValidationException", Message: message, Fault: smithy.FaultClient}
+}
+
+// operationError wraps errors the way the SDK does, so errors.As and errors.Is still see through
+func operationError(operation string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &smithy.OperationError{ServiceID: "DynamoDB", OperationName: operation, Err: err}
+}
+
+func conditionFailed(old item, returnOld types.ReturnValuesOnConditionCheckFailure) error {
+	err := &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	if returnOld == types.ReturnValuesOnConditionCheckFailureAllOld {
+		err.Item = copyItem(old)
+	}
+
+	return err
+}
+
+// begin checks the context and takes the lock, the caller must unlock
+func (f *Fake) begin(ctx context.Context, tableName *string) (*table, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	t, found := f.tables[aws.StringValue(tableName)]
+	if !found {
+		f.mu.Unlock()
+		return nil, &types.ResourceNotFoundException{Message: aws.String("Requested resource not found")}
+	}
+
+	return t, nil
+}
+
+// keyOf builds the storage key of an item or key map, which must hold the
+// key attributes with a scalar type. exact also rejects any other attribute
+func (t *table) keyOf(it item, exact bool) (string, error) {
+	names := []string{t.Key.HashKey}
+	if t.Key.RangeKey != "" {
+		names = append(names, t.Key.RangeKey)
+	}
+
+	if exact && len(it) != len(names) {
+		return "", validationError("the provided key element does not match the schema")
+	}
+
+	key := ""
+	for _, name := range names {
+		value, found := it[name]
+		if !found {
+			return "", validationError("missing the key " + name + " in the item")
+		}
+
+		s, ok := keyString(value)
+		if !ok {
+			return "", validationError("invalid type for the key " + name)
+		}
+		key += s + "\x00"
+	}
+
+	return key, nil
+}
+
+// keyAttributes is the subset of it naming its position in a table or index
+func keyAttributes(it item, schemas ...KeySchema) item {
+	result := item{}
+	for _, schema := range schemas {
+		for _, name := range []string{schema.HashKey, schema.RangeKey} {
+			if value, found := it[name]; found && name != "" {
+				result[name] = copyValue(value)
+			}
+		}
+	}
+
+	return result
+}
+
+func hasKeys(it item, schema KeySchema) bool {
+	if _, found := it[schema.HashKey]; !found {
+		return false
+	}
+
+	_, found := it[schema.RangeKey]
+	return schema.RangeKey == "" || found
+}
+
+// checkCondition parses and evaluates an optional condition expression
+func checkCondition(expression *string, ph *placeholders, it item) (bool, error) {
+	if expression == nil {
+		return true, nil
+	}
+
+	c, err := parseCondition(*expression, ph)
+	if err != nil {
+		return false, err
+	}
+
+	return c(it)
+}
+
+func (f *Fake) PutItem(ctx context.Context, params *awsDynamodb.PutItemInput, _ ...func(options *awsDynamodb.Options)) (*awsDynamodb.PutItemOutput, error) {
+	t, err := f.begin(ctx, params.TableName)
+	if err != nil {
+		return nil, operationError("PutItem", err)
+	}
+	defer f.mu.Unlock()
+
+	key, err := t.keyOf(params.Item, false)
+	if err != nil {
+		return nil, operationError("PutItem", err)
+	}
+
+	old := t.items[key]
+	ph := newPlaceholders(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	ok, err := checkCondition(params.ConditionExpression, ph, old)
+	if err == nil {
+		err = ph.unused()
+	}
+	if err != nil {
+		return nil, operationError("PutItem", err)
+	}
+
+	if !ok {
+		return nil, operationError("PutItem", conditionFailed(old, params.ReturnValuesOnConditionCheckFailure))
+	}
+
+	t.items[key] = copyItem(params.Item)
+
+	output := &awsDynamodb.PutItemOutput{}
+	if params.ReturnValues == types.ReturnValueAllOld {
+		output.Attributes = copyItem(old)
+	}
+
+	return output, nil
+}
+
+func (f *Fake) GetItem(ctx context.Context, params *awsDynamodb.GetItemInput, _ ...func(*awsDynamodb.Options)) (*awsDynamodb.GetItemOutput, error) {
+	t, err := f.begin(ctx, params.TableName)
+	if err != nil {
+		return nil, operationError("GetItem", err)
+	}
+	defer f.mu.Unlock()
+
+	key, err := t.keyOf(params.Key, true)
+	if err != nil {
+		return nil, operationError("GetItem", err)
+	}
+
+	ph := newPlaceholders(params.ExpressionAttributeNames, nil)
+	var projection []string
+	if params.ProjectionExpression != nil {
+		if projection, err = parseProjection(*params.ProjectionExpression, ph); err != nil {
+			return nil, operationError("GetItem", err)
+		}
+	}
+
+	if err := ph.unused(); err != nil {
+		return nil, operationError("GetItem", err)
+	}
+
+	output := &awsDynamodb.GetItemOutput{}
+	if it, found := t.items[key]; found {
+		output.Item = copyItem(project(it, projection))
+	}
+
+	return output, nil
+}
+
+// UpdateItem creates the item when missing, unless the condition prevents it
+func (f *Fake) UpdateItem(ctx context.Context, params *awsDynamodb.UpdateItemInput, _ ...func(options *awsDynamodb.Options)) (*awsDynamodb.UpdateItemOutput, error) {
+	t, err := f.begin(ctx, params.TableName)
+	if err != nil {
+		return nil, operationError("UpdateItem", err)
+	}
+	defer f.mu.Unlock()
+
+	key, err := t.keyOf(params.Key, true)
+	if err != nil {
+		return nil, operationError("UpdateItem", err)
+	}
+
+	ph := newPlaceholders(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	var actions []updateAction
+	if params.UpdateExpression != nil {
+		if actions, err = parseUpdate(*params.UpdateExpression, ph); err != nil {
+			return nil, operationError("UpdateItem", err)
+		}
+	}
+
+	old, found := t.items[key]
+	ok, err := checkCondition(params.ConditionExpression, ph, old)
+	if err == nil {
+		err = ph.unused()
+	}
+	if err != nil {
+		return nil, operationError("UpdateItem", err)
+	}
+
+	if !ok {
+		return nil, operationError("UpdateItem", conditionFailed(old, params.ReturnValuesOnConditionCheckFailure))
+	}
+
+	base := old
+	if !found {
+		base = copyItem(params.Key)
+	}
+
+	updated, names, err := applyUpdate(base, actions)
+	if err != nil {
+		return nil, operationError("UpdateItem", err)
+	}
+
+	for _, name := range names {
+		if name == t.Key.HashKey || name == t.Key.RangeKey {
+			return nil, operationError("UpdateItem", validationError("cannot update attribute "+name+", it is part of the key"))
+		}
+	}
+
+	t.items[key] = updated
+
+	output := &awsDynamodb.UpdateItemOutput{}
+	switch params.ReturnValues {
+	case types.ReturnValueAllOld:
+		output.Attributes = copyItem(old)
+	case types.ReturnValueAllNew:
+		output.Attributes = copyItem(updated)
+	case types.ReturnValueUpdatedOld:
+		output.Attributes = copyItem(project(old, names))
+	case types.ReturnValueUpdatedNew:
+		output.Attributes = copyItem(project(updated, names))
+	}
+
+	return output, nil
+}
+
+func (f *Fake) DeleteItem(ctx context.Context, params *awsDynamodb.DeleteItemInput, _ ...func(options *awsDynamodb.Options)) (*awsDynamodb.DeleteItemOutput, error) {
+	t, err := f.begin(ctx, params.TableName)
+	if err != nil {
+		return nil, operationError("DeleteItem", err)
+	}
+	defer f.mu.Unlock()
+
+	key, err := t.keyOf(params.Key, true)
+	if err != nil {
+		return nil, operationError("DeleteItem", err)
+	}
+
+	old := t.items[key]
+	ph := newPlaceholders(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	ok, err := checkCondition(params.ConditionExpression, ph, old)
+	if err == nil {
+		err = ph.unused()
+	}
+	if err != nil {
+		return nil, operationError("DeleteItem", err)
+	}
+
+	if !ok {
+		return nil, operationError("DeleteItem", conditionFailed(old, params.ReturnValuesOnConditionCheckFailure))
+	}
+
+	delete(t.items, key)
+
+	output := &awsDynamodb.DeleteItemOutput{}
+	if params.ReturnValues == types.ReturnValueAllOld {
+		output.Attributes = copyItem(old)
+	}
+
+	return output, nil
+}
+
+// readRequest is what Scan and Query have in common
+type readRequest struct {
+	index      *string
+	condition  condition
+	filter     *string
+	projection *string
+	startKey   item
+	limit      *int32
+	forward    bool
+}
+
+func (f *Fake) Scan(ctx context.Context, params *awsDynamodb.ScanInput, _ ...func(options *awsDynamodb.Options)) (*awsDynamodb.ScanOutput, error) {
+	t, err := f.begin(ctx, params.TableName)
+	if err != nil {
+		return nil, operationError("Scan", err)
+	}
+	defer f.mu.Unlock()
+
+	ph := newPlaceholders(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	items, lastKey, scanned, err := t.read(ph, readRequest{
+		index:      params.IndexName,
+		filter:     params.FilterExpression,
+		projection: params.ProjectionExpression,
+		startKey:   params.ExclusiveStartKey,
+		limit:      params.Limit,
+		forward:    true,
+	})
+	if err != nil {
+		return nil, operationError("Scan", err)
+	}
+
+	return &awsDynamodb.ScanOutput{
+		Items:            items,
+		Count:            int32(len(items)),
+		ScannedCount:     scanned,
+		LastEvaluatedKey: lastKey,
+	}, nil
+}
+
+func (f *Fake) Query(ctx context.Context, params *awsDynamodb.QueryInput, _ ...func(options *awsDynamodb.Options)) (*awsDynamodb.QueryOutput, error) {
+	t, err := f.begin(ctx, params.TableName)
+	if err != nil {
+		return nil, operationError("Query", err)
+	}
+	defer f.mu.Unlock()
+
+	if params.KeyConditionExpression == nil {
+		return nil, operationError("Query", validationError("KeyConditionExpression is required"))
+	}
+
+	ph := newPlaceholders(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	keyCondition, err := parseCondition(*params.KeyConditionExpression, ph)
+	if err != nil {
+		return nil, operationError("Query", err)
+	}
+
+	items, lastKey, scanned, err := t.read(ph, readRequest{
+		index:      params.IndexName,
+		condition:  keyCondition,
+		filter:     params.FilterExpression,
+		projection: params.ProjectionExpression,
+		startKey:   params.ExclusiveStartKey,
+		limit:      params.Limit,
+		forward:    params.ScanIndexForward == nil || *params.ScanIndexForward,
+	})
+	if err != nil {
+		return nil, operationError("Query", err)
+	}
+
+	return &awsDynamodb.QueryOutput{
+		Items:            items,
+		Count:            int32(len(items)),
+		ScannedCount:     scanned,
+		LastEvaluatedKey: lastKey,
+	}, nil
+}
+
+// read walks the table or one of its indexes in key order. Limit bounds the
+// items evaluated before filtering and, when reached, sets the last evaluated key
+func (t *table) read(ph *placeholders, r readRequest) ([]item, item, int32, error) {
+	schemas := []KeySchema{t.Key}
+	if r.index != nil {
+		index, found := t.Indexes[*r.index]
+		if !found {
+			return nil, nil, 0, validationError("the table does not have the specified index: " + *r.index)
+		}
+		schemas = []KeySchema{index, t.Key}
+	}
+
+	var err error
+	filter := func(item) (bool, error) { return true, nil }
+	if r.filter != nil {
+		if filter, err = parseCondition(*r.filter, ph); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+
+	var projection []string
+	if r.projection != nil {
+		if projection, err = parseProjection(*r.projection, ph); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+
+	if err := ph.unused(); err != nil {
+		return nil, nil, 0, err
+	}
+
+	if r.limit != nil && *r.limit < 1 {
+		return nil, nil, 0, validationError("limit must be greater than or equal to 1")
+	}
+
+	// REF: indexes are sparse, items without the index keys are not in them
+	candidates := []item{}
+	for _, it := range t.items {
+		if !hasKeys(it, schemas[0]) {
+			continue
+		}
+
+		if r.condition != nil {
+			ok, err := r.condition(it)
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		candidates = append(candidates, it)
+	}
+
+	compare := func(a item, b item) int {
+		for _, schema := range schemas {
+			for _, name := range []string{schema.HashKey, schema.RangeKey} {
+				if name == "" {
+					continue
+				}
+				if c, _ := compareValues(a[name], b[name]); c != 0 {
+					return c
+				}
+			}
+		}
+		return 0
+	}
+
+	slices.SortFunc(candidates, compare)
+	if !r.forward {
+		slices.Reverse(candidates)
+	}
+
+	if r.startKey != nil {
+		if len(keyAttributes(r.startKey, schemas...)) != len(r.startKey) {
+			return nil, nil, 0, validationError("the provided starting key is invalid")
+		}
+
+		after := slices.IndexFunc(candidates, func(it item) bool {
+			c := compare(it, r.startKey)
+			return (r.forward && c > 0) || (!r.forward && c < 0)
+		})
+		if after < 0 {
+			after = len(candidates)
+		}
+		candidates = candidates[after:]
+	}
+
+	var lastKey item
+	if r.limit != nil && int(*r.limit) <= len(candidates) {
+		candidates = candidates[:*r.limit]
+		lastKey = keyAttributes(candidates[len(candidates)-1], schemas...)
+	}
+
+	result := []item{}
+	for _, it := range candidates {
+		ok, err := filter(it)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		if ok {
+			result = append(result, copyItem(project(it, projection)))
+		}
+	}
+
+	return result, lastKey, int32(len(candidates)), nil
+}
@@ -0,0 +1,369 @@
+package dynamotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	awsDynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testTable = Table{
+		Name: "urls",
+		Key:  KeySchema{HashKey: "url_id"},
+		Indexes: map[string]KeySchema{
+			"by_author": {HashKey: "created_by", RangeKey: "created_at"},
+		},
+	}
+	rangeTable = Table{
+		Name: "hits",
+		Key:  KeySchema{HashKey: "hit_key", RangeKey: "bucket_start"},
+	}
+)
+
+func s(value string) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: value}
+}
+
+func n(value string) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: value}
+}
+
+func put(t *testing.T, f *Fake, tableName string, it item) {
+	t.Helper()
+
+	_, err := f.PutItem(context.Background(), &awsDynamodb.PutItemInput{TableName: aws.String(tableName), Item: it})
+	assert.NoError(t, err)
+}
+
+func get(t *testing.T, f *Fake, tableName string, key item) item {
+	t.Helper()
+
+	output, err := f.GetItem(context.Background(), &awsDynamodb.GetItemInput{TableName: aws.String(tableName), Key: key})
+	assert.NoError(t, err)
+	return output.Item
+}
+
+func isValidation(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationException"
+}
+
+func TestFakePutItemCondition(t *testing.T) {
+	f := NewFake(testTable)
+	ctx := context.Background()
+
+	input := &awsDynamodb.PutItemInput{
+		TableName:           aws.String("urls"),
+		Item:                item{"url_id": s("asd"), "full_url": s("https://opentelemetry.io")},
+		ConditionExpression: aws.String("attribute_not_exists(url_id)"),
+	}
+	_, err := f.PutItem(ctx, input)
+	assert.NoError(t, err)
+
+	// REF: the real exception type, wrapped like the SDK does
+	_, err = f.PutItem(ctx, input)
+	var conditionErr *types.ConditionalCheckFailedException
+	assert.ErrorAs(t, err, &conditionErr)
+	var operationErr *smithy.OperationError
+	assert.ErrorAs(t, err, &operationErr)
+
+	input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+	_, err = f.PutItem(ctx, input)
+	if assert.ErrorAs(t, err, &conditionErr) {
+		assert.Equal(t, s("https://opentelemetry.io"), conditionErr.Item["full_url"])
+	}
+
+	// REF: stored items are copies
+	input.Item["full_url"].(*types.AttributeValueMemberS).Value = "mutated"
+	assert.Equal(t, s("https://opentelemetry.io"), get(t, f, "urls", item{"url_id": s("asd")})["full_url"])
+}
+
+func TestFakeGetItem(t *testing.T) {
+	f := NewFake(testTable)
+	ctx := context.Background()
+	put(t, f, "urls", item{"url_id": s("asd"), "version": n("2"), "enabled": &types.AttributeValueMemberBOOL{Value: true}})
+
+	// REF: missing items are an empty output, not an error
+	assert.Nil(t, get(t, f, "urls", item{"url_id": s("missing")}))
+
+	output, err := f.GetItem(ctx, &awsDynamodb.GetItemInput{
+		TableName:                aws.String("urls"),
+		Key:                      item{"url_id": s("asd")},
+		ProjectionExpression:     aws.String("url_id, #version"),
+		ExpressionAttributeNames: map[string]string{"#version": "version"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, item{"url_id": s("asd"), "version": n("2")}, output.Item)
+
+	// REF: keys must match the schema exactly
+	_, err = f.GetItem(ctx, &awsDynamodb.GetItemInput{TableName: aws.String("urls"), Key: item{"url_id": s("asd"), "enabled": s("x")}})
+	assert.True(t, isValidation(err))
+
+	_, err = f.GetItem(ctx, &awsDynamodb.GetItemInput{TableName: aws.String("missing"), Key: item{"url_id": s("asd")}})
+	var notFound *types.ResourceNotFoundException
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestFakeUpdateItem(t *testing.T) {
+	f := NewFake(testTable)
+	ctx := context.Background()
+	key := item{"url_id": s("asd")}
+
+	// REF: conditions on existence prevent the upsert
+	_, err := f.UpdateItem(ctx, &awsDynamodb.UpdateItemInput{
+		TableName:                 aws.String("urls"),
+		Key:                       key,
+		UpdateExpression:          aws.String("SET enabled = :enabled"),
+		ConditionExpression:       aws.String("attribute_exists(url_id)"),
+		ExpressionAttributeValues: item{":enabled": &types.AttributeValueMemberBOOL{Value: false}},
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	assert.ErrorAs(t, err, &conditionErr)
+	assert.Nil(t, get(t, f, "urls", key))
+
+	// REF: without condition it is an upsert
+	update := &awsDynamodb.UpdateItemInput{
+		TableName:        aws.String("urls"),
+		Key:              key,
+		UpdateExpression: aws.String("SET #version = if_not_exists(#version, :zero) + :one, revisions = list_append(if_not_exists(revisions, :empty), :revision) ADD hits :one"),
+		ExpressionAttributeNames: map[string]string{
+			"#version": "version",
+		},
+		ExpressionAttributeValues: item{
+			":zero":     n("0"),
+			":one":      n("1"),
+			":empty":    &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+			":revision": &types.AttributeValueMemberL{Value: []types.AttributeValue{s("r")}},
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	}
+	output, err := f.UpdateItem(ctx, update)
+	assert.NoError(t, err)
+	assert.Equal(t, n("1"), output.Attributes["version"])
+	assert.NotContains(t, output.Attributes, "url_id")
+
+	_, err = f.UpdateItem(ctx, update)
+	assert.NoError(t, err)
+
+	stored := get(t, f, "urls", key)
+	assert.Equal(t, n("2"), stored["version"])
+	assert.Equal(t, n("2"), stored["hits"])
+	assert.Len(t, stored["revisions"].(*types.AttributeValueMemberL).Value, 2)
+
+	// REF: REMOVE drops attributes, keys cannot be updated
+	_, err = f.UpdateItem(ctx, &awsDynamodb.UpdateItemInput{TableName: aws.String("urls"), Key: key, UpdateExpression: aws.String("REMOVE hits")})
+	assert.NoError(t, err)
+	assert.NotContains(t, get(t, f, "urls", key), "hits")
+
+	_, err = f.UpdateItem(ctx, &awsDynamodb.UpdateItemInput{
+		TableName:                 aws.String("urls"),
+		Key:                       key,
+		UpdateExpression:          aws.String("SET url_id = :id"),
+		ExpressionAttributeValues: item{":id": s("other")},
+	})
+	assert.True(t, isValidation(err))
+}
+
+func TestFakeUpdateItemConcurrentAdd(t *testing.T) {
+	f := NewFake(Table{Name: "sequences", Key: KeySchema{HashKey: "sequence_name"}})
+
+	// REF: atomic counters never lose increments
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := f.UpdateItem(context.Background(), &awsDynamodb.UpdateItemInput{
+				TableName:                 aws.String("sequences"),
+				Key:                       item{"sequence_name": s("ids")},
+				UpdateExpression:          aws.String("ADD next_value :size"),
+				ExpressionAttributeValues: item{":size": n("10")},
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, n("500"), get(t, f, "sequences", item{"sequence_name": s("ids")})["next_value"])
+}
+
+func TestFakeDeleteItem(t *testing.T) {
+	f := NewFake(testTable)
+	ctx := context.Background()
+	key := item{"url_id": s("asd")}
+	input := &awsDynamodb.DeleteItemInput{
+		TableName:           aws.String("urls"),
+		Key:                 key,
+		ConditionExpression: aws.String("attribute_exists(url_id)"),
+	}
+
+	_, err := f.DeleteItem(ctx, input)
+	var conditionErr *types.ConditionalCheckFailedException
+	assert.ErrorAs(t, err, &conditionErr)
+
+	put(t, f, "urls", item{"url_id": s("asd")})
+	_, err = f.DeleteItem(ctx, input)
+	assert.NoError(t, err)
+	assert.Nil(t, get(t, f, "urls", key))
+}
+
+func TestFakeQuery(t *testing.T) {
+	f := NewFake(rangeTable)
+	ctx := context.Background()
+	for i := 1; i <= 5; i++ {
+		put(t, f, "hits", item{"hit_key": s("asd#day"), "bucket_start": n(fmt.Sprint(i * 10)), "hits": n("1")})
+	}
+	put(t, f, "hits", item{"hit_key": s("other#day"), "bucket_start": n("20"), "hits": n("1")})
+
+	query := &awsDynamodb.QueryInput{
+		TableName:              aws.String("hits"),
+		KeyConditionExpression: aws.String("hit_key = :key AND bucket_start BETWEEN :from AND :to"),
+		ExpressionAttributeValues: item{
+			":key":  s("asd#day"),
+			":from": n("20"),
+			":to":   n("40"),
+		},
+	}
+	output, err := f.Query(ctx, query)
+	assert.NoError(t, err)
+	if assert.Len(t, output.Items, 3) {
+		assert.Equal(t, n("20"), output.Items[0]["bucket_start"])
+		assert.Equal(t, n("40"), output.Items[2]["bucket_start"])
+	}
+	assert.Empty(t, output.LastEvaluatedKey)
+
+	// REF: limits paginate through the last evaluated key
+	query.Limit = aws.Int32(2)
+	output, err = f.Query(ctx, query)
+	assert.NoError(t, err)
+	assert.Len(t, output.Items, 2)
+	assert.Equal(t, item{"hit_key": s("asd#day"), "bucket_start": n("30")}, output.LastEvaluatedKey)
+
+	query.ExclusiveStartKey = output.LastEvaluatedKey
+	output, err = f.Query(ctx, query)
+	assert.NoError(t, err)
+	if assert.Len(t, output.Items, 1) {
+		assert.Equal(t, n("40"), output.Items[0]["bucket_start"])
+	}
+
+	// REF: descending order
+	output, err = f.Query(ctx, &awsDynamodb.QueryInput{
+		TableName:                 aws.String("hits"),
+		KeyConditionExpression:    aws.String("hit_key = :key"),
+		ExpressionAttributeValues: item{":key": s("asd#day")},
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int32(1),
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, output.Items, 1) {
+		assert.Equal(t, n("50"), output.Items[0]["bucket_start"])
+	}
+}
+
+func TestFakeQueryIndex(t *testing.T) {
+	f := NewFake(testTable)
+	ctx := context.Background()
+	put(t, f, "urls", item{"url_id": s("b"), "created_by": s("root"), "created_at": s("2025-01-02")})
+	put(t, f, "urls", item{"url_id": s("a"), "created_by": s("root"), "created_at": s("2025-01-03")})
+	put(t, f, "urls", item{"url_id": s("c"), "created_by": s("other"), "created_at": s("2025-01-01")})
+
+	// REF: indexes are sparse
+	put(t, f, "urls", item{"url_id": s("d"), "created_by": s("root")})
+
+	output, err := f.Query(ctx, &awsDynamodb.QueryInput{
+		TableName:                 aws.String("urls"),
+		IndexName:                 aws.String("by_author"),
+		KeyConditionExpression:    aws.String("created_by = :created_by"),
+		ExpressionAttributeValues: item{":created_by": s("root")},
+		Limit:                     aws.Int32(1),
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, output.Items, 1) {
+		assert.Equal(t, s("b"), output.Items[0]["url_id"])
+	}
+	assert.Equal(t, item{"url_id": s("b"), "created_by": s("root"), "created_at": s("2025-01-02")}, output.LastEvaluatedKey)
+
+	output, err = f.Query(ctx, &awsDynamodb.QueryInput{
+		TableName:                 aws.String("urls"),
+		IndexName:                 aws.String("by_author"),
+		KeyConditionExpression:    aws.String("created_by = :created_by"),
+		ExpressionAttributeValues: item{":created_by": s("root")},
+		ExclusiveStartKey:         output.LastEvaluatedKey,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, output.Items, 1) {
+		assert.Equal(t, s("a"), output.Items[0]["url_id"])
+	}
+
+	_, err = f.Query(ctx, &awsDynamodb.QueryInput{
+		TableName:                 aws.String("urls"),
+		IndexName:                 aws.String("missing"),
+		KeyConditionExpression:    aws.String("created_by = :created_by"),
+		ExpressionAttributeValues: item{":created_by": s("root")},
+	})
+	assert.True(t, isValidation(err))
+}
+
+func TestFakeScan(t *testing.T) {
+	f := NewFake(testTable)
+	ctx := context.Background()
+	for _, id := range []string{"c", "a", "d", "b"} {
+		put(t, f, "urls", item{"url_id": s(id), "enabled": &types.AttributeValueMemberBOOL{Value: id != "b"}})
+	}
+
+	// REF: limit applies before the filter, like DynamoDB
+	output, err := f.Scan(ctx, &awsDynamodb.ScanInput{
+		TableName:                 aws.String("urls"),
+		FilterExpression:          aws.String("enabled = :enabled"),
+		ExpressionAttributeValues: item{":enabled": &types.AttributeValueMemberBOOL{Value: true}},
+		Limit:                     aws.Int32(2),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, output.Items, 1)
+	assert.Equal(t, int32(2), output.ScannedCount)
+	assert.Equal(t, item{"url_id": s("b")}, output.LastEvaluatedKey)
+
+	output, err = f.Scan(ctx, &awsDynamodb.ScanInput{TableName: aws.String("urls"), ExclusiveStartKey: output.LastEvaluatedKey})
+	assert.NoError(t, err)
+	assert.Len(t, output.Items, 2)
+	assert.Empty(t, output.LastEvaluatedKey)
+}
+
+func TestFakeValidation(t *testing.T) {
+	f := NewFake(testTable)
+	ctx := context.Background()
+
+	// REF: DynamoDB rejects placeholders no expression uses
+	_, err := f.PutItem(ctx, &awsDynamodb.PutItemInput{
+		TableName:                 aws.String("urls"),
+		Item:                      item{"url_id": s("asd")},
+		ConditionExpression:       aws.String("attribute_not_exists(url_id)"),
+		ExpressionAttributeValues: item{":unused": s("x")},
+	})
+	assert.True(t, isValidation(err))
+
+	_, err = f.PutItem(ctx, &awsDynamodb.PutItemInput{
+		TableName:           aws.String("urls"),
+		Item:                item{"url_id": s("asd")},
+		ConditionExpression: aws.String("#missing = :missing"),
+	})
+	assert.True(t, isValidation(err))
+
+	// REF: keys are mandatory
+	_, err = f.PutItem(ctx, &awsDynamodb.PutItemInput{TableName: aws.String("urls"), Item: item{"full_url": s("x")}})
+	assert.True(t, isValidation(err))
+
+	cancelled, cancelFunc := context.WithCancel(ctx)
+	cancelFunc()
+	_, err = f.PutItem(cancelled, &awsDynamodb.PutItemInput{TableName: aws.String("urls"), Item: item{"url_id": s("asd")}})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, get(t, f, "urls", item{"url_id": s("asd")}))
+}
@@ -0,0 +1,193 @@
+package dynamotest
+
+import (
+	"bytes"
+	"math/big"
+	"slices"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// parseNumber reads a DynamoDB number exactly, they are decimal strings
+func parseNumber(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, validationError("invalid number " + strconv.Quote(s))
+	}
+
+	return r, nil
+}
+
+func formatNumber(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+
+	f, _ := r.Float64()
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// compareValues orders scalars of the same type, ok is false otherwise
+func compareValues(a types.AttributeValue, b types.AttributeValue) (result int, ok bool) {
+	switch a := a.(type) {
+	case *types.AttributeValueMemberS:
+		if b, isS := b.(*types.AttributeValueMemberS); isS {
+			if a.Value < b.Value {
+				return -1, true
+			} else if a.Value > b.Value {
+				return 1, true
+			}
+			return 0, true
+		}
+	case *types.AttributeValueMemberN:
+		if b, isN := b.(*types.AttributeValueMemberN); isN {
+			x, errA := parseNumber(a.Value)
+			y, errB := parseNumber(b.Value)
+			if errA != nil || errB != nil {
+				return 0, false
+			}
+			return x.Cmp(y), true
+		}
+	case *types.AttributeValueMemberB:
+		if b, isB := b.(*types.AttributeValueMemberB); isB {
+			return bytes.Compare(a.Value, b.Value), true
+		}
+	}
+
+	return 0, false
+}
+
+// equalValues compares any two values, sets regardless of their order
+func equalValues(a types.AttributeValue, b types.AttributeValue) bool {
+	if c, ok := compareValues(a, b); ok {
+		return c == 0
+	}
+
+	switch a := a.(type) {
+	case *types.AttributeValueMemberBOOL:
+		b, ok := b.(*types.AttributeValueMemberBOOL)
+		return ok && a.Value == b.Value
+	case *types.AttributeValueMemberNULL:
+		_, ok := b.(*types.AttributeValueMemberNULL)
+		return ok
+	case *types.AttributeValueMemberL:
+		b, ok := b.(*types.AttributeValueMemberL)
+		return ok && slices.EqualFunc(a.Value, b.Value, equalValues)
+	case *types.AttributeValueMemberM:
+		b, ok := b.(*types.AttributeValueMemberM)
+		if !ok || len(a.Value) != len(b.Value) {
+			return false
+		}
+		for name, value := range a.Value {
+			other, found := b.Value[name]
+			if !found || !equalValues(value, other) {
+				return false
+			}
+		}
+		return true
+	case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+		x, y := setMembers(a), setMembers(b)
+		if x == nil || y == nil || len(x) != len(y) {
+			return false
+		}
+		for _, member := range x {
+			if !slices.ContainsFunc(y, func(other types.AttributeValue) bool { return equalValues(member, other) }) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// setMembers returns the elements of a set as scalars, nil for anything else
+func setMembers(v types.AttributeValue) []types.AttributeValue {
+	result := []types.AttributeValue{}
+	switch v := v.(type) {
+	case *types.AttributeValueMemberSS:
+		for _, s := range v.Value {
+			result = append(result, &types.AttributeValueMemberS{Value: s})
+		}
+	case *types.AttributeValueMemberNS:
+		for _, n := range v.Value {
+			result = append(result, &types.AttributeValueMemberN{Value: n})
+		}
+	case *types.AttributeValueMemberBS:
+		for _, b := range v.Value {
+			result = append(result, &types.AttributeValueMemberB{Value: b})
+		}
+	default:
+		return nil
+	}
+
+	return result
+}
+
+// copyValue deep copies v, so callers never share memory with stored items
+func copyValue(v types.AttributeValue) types.AttributeValue {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		return &types.AttributeValueMemberS{Value: v.Value}
+	case *types.AttributeValueMemberN:
+		return &types.AttributeValueMemberN{Value: v.Value}
+	case *types.AttributeValueMemberB:
+		return &types.AttributeValueMemberB{Value: bytes.Clone(v.Value)}
+	case *types.AttributeValueMemberBOOL:
+		return &types.AttributeValueMemberBOOL{Value: v.Value}
+	case *types.AttributeValueMemberNULL:
+		return &types.AttributeValueMemberNULL{Value: v.Value}
+	case *types.AttributeValueMemberL:
+		result := make([]types.AttributeValue, len(v.Value))
+		for i, element := range v.Value {
+			result[i] = copyValue(element)
+		}
+		return &types.AttributeValueMemberL{Value: result}
+	case *types.AttributeValueMemberM:
+		return &types.AttributeValueMemberM{Value: copyItem(v.Value)}
+	case *types.AttributeValueMemberSS:
+		return &types.AttributeValueMemberSS{Value: slices.Clone(v.Value)}
+	case *types.AttributeValueMemberNS:
+		return &types.AttributeValueMemberNS{Value: slices.Clone(v.Value)}
+	case *types.AttributeValueMemberBS:
+		result := make([][]byte, len(v.Value))
+		for i, b := range v.Value {
+			result[i] = bytes.Clone(b)
+		}
+		return &types.AttributeValueMemberBS{Value: result}
+	}
+
+	return v
+}
+
+func copyItem(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	if item == nil {
+		return nil
+	}
+
+	result := make(map[string]types.AttributeValue, len(item))
+	for name, value := range item {
+		result[name] = copyValue(value)
+	}
+
+	return result
+}
+
+// keyString identifies a key value, numbers are normalized so 1 and 1.0 match
+func keyString(v types.AttributeValue) (string, bool) {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		return "S:" + v.Value, true
+	case *types.AttributeValueMemberN:
+		r, err := parseNumber(v.Value)
+		if err != nil {
+			return "", false
+		}
+		return "N:" + r.RatString(), true
+	case *types.AttributeValueMemberB:
+		return "B:" + string(v.Value), true
+	}
+
+	return "", false
+}
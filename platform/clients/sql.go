@@ -0,0 +1,50 @@
+package clients
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+const defaultSQLiteDsn = "file:shortener.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+// NewSQLClient opens the pool of the configured SQL backend, it does not connect until used
+func NewSQLClient(appCfg config.AppConfig) (*sql.DB, error) {
+	switch appCfg.Storage.Backend {
+	case BackendSQLite:
+		dsn := appCfg.Sql.Dsn
+		if dsn == "" {
+			dsn = defaultSQLiteDsn
+		}
+
+		db, err := sql.Open("sqlite", dsn)
+		if err != nil {
+			return nil, err
+		}
+
+		// REF: SQLite allows a single writer, sharing one connection avoids SQLITE_BUSY
+		db.SetMaxOpenConns(1)
+		return db, nil
+
+	case BackendPostgres:
+		if appCfg.Sql.Dsn == "" {
+			return nil, fmt.Errorf("%s backend requires a dsn", BackendPostgres)
+		}
+
+		db, err := sql.Open("postgres", appCfg.Sql.Dsn)
+		if err != nil {
+			return nil, err
+		}
+
+		db.SetMaxOpenConns(appCfg.Sql.MaxOpenConns)
+		return db, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %s", domain.ErrUnknownBackend, appCfg.Storage.Backend)
+	}
+}
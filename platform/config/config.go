@@ -22,19 +22,73 @@ type AppConfig struct {
 	// ApiUser for demo purposes
 	ApiUser string `split_words:"true" default:"root@neonmei.cloud"`
 
-	// MaxLength is the HTTP server port
+	// MaxLength caps the length of upstream URLs
 	MaxLength int `split_words:"true" default:"1024" `
 
+	// ReservedIds are identifiers that cannot be requested as custom aliases
+	ReservedIds []string `split_words:"true" default:"platform,v1,v2,healthz,api,admin,static,assets"`
+
 	// ShutdownTimeout how much to wait for pending operations
 	ShutdownTimeout time.Duration `split_words:"true" default:"5s" `
 
 	// ShutdownWait how much to wait before initiating shutdown
 	ShutdownWait time.Duration `split_words:"true" default:"60s" `
 
+	Storage struct {
+		// Backend is where URLs, hits and sequences are stored: memory, dynamodb, sqlite, postgres or bolt
+		Backend string `split_words:"true" default:"dynamodb" `
+
+		// Fixtures is a JSON file of URLs saved at startup, existing ones are kept
+		Fixtures string `split_words:"true" default:"" `
+	}
+
+	Sql struct {
+		// Dsn is the data source name, a file path for sqlite or a postgres:// URL.
+		// Empty uses shortener.db in the working directory for sqlite
+		Dsn string `split_words:"true" default:"" `
+
+		// MaxOpenConns bounds the connection pool, sqlite always uses a single connection
+		MaxOpenConns int `split_words:"true" default:"10" `
+
+		// ReadTimeout how much to wait for SQL read operations
+		ReadTimeout time.Duration `split_words:"true" default:"100ms" `
+
+		// WriteTimeout how much to wait for SQL write operations
+		WriteTimeout time.Duration `split_words:"true" default:"900ms" `
+	}
+
+	Memory struct {
+		// SnapshotFile is loaded at startup, if present, and rewritten on shutdown. Empty keeps nothing
+		SnapshotFile string `split_words:"true" default:"" `
+	}
+
+	Bolt struct {
+		// Path of the embedded database file
+		Path string `split_words:"true" default:"shortener.bolt" `
+
+		// OpenTimeout is how long to wait for another process to release the file
+		OpenTimeout time.Duration `split_words:"true" default:"5s" `
+
+		// BackupPath is where online backups are written, empty disables them
+		BackupPath string `split_words:"true" default:"" `
+
+		// BackupInterval is how often the backup is replaced with a fresh copy
+		BackupInterval time.Duration `split_words:"true" default:"1h" `
+	}
+
 	Dynamo struct {
 		// DynamoTableName sets where the storage backend will search for url data
 		TableName string `split_words:"true" default:"url_shortener" `
 
+		// CreatedByIndex is a GSI partitioned by created_by and sorted by created_at
+		CreatedByIndex string `split_words:"true" default:"created_by-created_at-index" `
+
+		// HitsTableName is where aggregated hit counters are stored
+		HitsTableName string `split_words:"true" default:"url_shortener_hits" `
+
+		// SequencesTableName is where counter based generators lease identifier blocks
+		SequencesTableName string `split_words:"true" default:"url_shortener_sequences" `
+
 		// ReadTimeout how much to wait for DynamoDB read operations
 		ReadTimeout time.Duration `split_words:"true" default:"50ms" `
 
@@ -43,14 +97,91 @@ type AppConfig struct {
 	}
 
 	Hasher struct {
+		// Strategy picks the identifier generator: random, counter, snowflake or hashids
+		Strategy string `split_words:"true" default:"random" `
+
 		// RandomMaxValue sets the maximum value cap for the random hasher
 		RandomMaxValue uint64 `split_words:"true" default:"3521614606207" `
 
 		// MaxRounds indicates how many time to try hashing before giving up
 		MaxRounds uint64 `split_words:"true" default:"4" `
+
+		// BlockSize is how many identifiers counter and hashids lease at once
+		BlockSize uint64 `split_words:"true" default:"1000" `
+
+		// NodeId distinguishes replicas using snowflake, from 0 to 1023
+		NodeId uint16 `split_words:"true" default:"0" `
+
+		// Salt obfuscates hashids sequences, changing it changes every future identifier
+		Salt string `split_words:"true" default:"" `
+
+		// Alphabet of generated identifiers, a preset (base62, nolookalikes) or
+		// literal characters. Lookups and aliases accept base62 on top of it
+		Alphabet string `split_words:"true" default:"base62" `
+
+		// Length pads generated identifiers to this many characters, random ones
+		// are drawn with exactly this length. Zero keeps their natural length
+		Length int `split_words:"true" default:"0" `
+
+		// BlocklistFile has one word per line generated identifiers cannot contain
+		BlocklistFile string `split_words:"true" default:"" `
+	}
+
+	Policy struct {
+		// Shorteners are other URL shorteners, rejected to prevent redirect chains
+		Shorteners []string `split_words:"true" default:"bit.ly,tinyurl.com,t.co,goo.gl,ow.ly,is.gd,buff.ly,rebrand.ly,cutt.ly,shorturl.at"`
+
+		// AllowHosts restricts upstream hosts, i.e: example.com,*.example.com. Empty allows any host
+		AllowHosts []string `split_words:"true"`
+
+		// DenyHosts rejects upstream hosts, same syntax as AllowHosts
+		DenyHosts []string `split_words:"true"`
+	}
+
+	Resilience struct {
+		// Enabled guards DynamoDB with a circuit breaker and read retries
+		Enabled bool `split_words:"true" default:"true" `
+
+		// FailureThreshold is how many consecutive failures open the circuit
+		FailureThreshold int `split_words:"true" default:"5" `
+
+		// OpenTimeout is how long calls fail fast before probing DynamoDB again
+		OpenTimeout time.Duration `split_words:"true" default:"5s" `
+
+		// HalfOpenSuccesses is how many probes must succeed to close the circuit
+		HalfOpenSuccesses int `split_words:"true" default:"2" `
+
+		// ReadRetries is how many times a failed read is repeated, all within the backend ReadTimeout
+		ReadRetries int `split_words:"true" default:"2" `
+
+		// RetryBackoff is the base delay between read retries, jittered and doubled every time
+		RetryBackoff time.Duration `split_words:"true" default:"5ms" `
+
+		// RetryMaxBackoff caps the delay between read retries
+		RetryMaxBackoff time.Duration `split_words:"true" default:"20ms" `
+	}
+
+	Hedging struct {
+		// Enabled fires a second DynamoDB read when the first one is slow
+		Enabled bool `split_words:"true" default:"false" `
+
+		// Percentile of recent read latencies after which the second read fires
+		Percentile float64 `split_words:"true" default:"0.95" `
+
+		// Window is how many recent read latencies are considered
+		Window int `split_words:"true" default:"1024" `
+
+		// MinDelay keeps hedges from firing on every read when DynamoDB is fast
+		MinDelay time.Duration `split_words:"true" default:"2ms" `
+
+		// MaxDelay caps the delay, it is also used until Window latencies were observed
+		MaxDelay time.Duration `split_words:"true" default:"10ms" `
 	}
 
 	Cache struct {
+		// Enabled puts the in-memory cache in front of the storage backend
+		Enabled bool `split_words:"true" default:"true" `
+
 		// Counter is the number of keys to track frequency of
 		NumCounters int64 `split_words:"true" default:"100000" `
 
@@ -60,9 +191,164 @@ type AppConfig struct {
 		// BufferItems is number of keys per Get buffer
 		BufferItems int64 `split_words:"true" default:"64" `
 
+		// NegativeTtl is how long a not found lookup is remembered, zero disables it
+		NegativeTtl time.Duration `split_words:"true" default:"5s" `
+
+		// NegativeCost is the cost of a remembered not found lookup, in MaxCost units
+		NegativeCost int64 `split_words:"true" default:"1" `
+
+		// SoftTtl is the age after which entries are refreshed in the background
+		// while still being served, zero never refreshes them
+		SoftTtl time.Duration `split_words:"true" default:"1m" `
+
+		// StaleEntries bounds the last known good copies served while DynamoDB is
+		// unavailable, zero disables stale-if-error
+		StaleEntries int `split_words:"true" default:"10000" `
+
 		// MetricsEnabled optionally enables metrics
 		MetricsEnabled bool `split_words:"true" default:"false" `
 	}
+
+	List struct {
+		// DefaultLimit is the page size when none is requested
+		DefaultLimit int `split_words:"true" default:"50" `
+
+		// MaxLimit caps the requested page size
+		MaxLimit int `split_words:"true" default:"200" `
+	}
+
+	Analytics struct {
+		// Enabled turns on per-link hit aggregation. Off by default, as DynamoDB
+		// deployments need the hits table created first
+		Enabled bool `split_words:"true" default:"false" `
+
+		// BufferSize is how many hits can be queued before new ones are dropped
+		BufferSize int `split_words:"true" default:"65536" `
+
+		// FlushInterval is how often aggregated hits are written
+		FlushInterval time.Duration `split_words:"true" default:"5s" `
+
+		// MaxBatch forces a flush once this many distinct counters are pending
+		MaxBatch int `split_words:"true" default:"1000" `
+
+		// WriteTimeout bounds each flush, backends bound every write within it
+		WriteTimeout time.Duration `split_words:"true" default:"10s" `
+
+		// MaxBuckets caps how many buckets a stats query can return
+		MaxBuckets int `split_words:"true" default:"1500" `
+
+		// BotRulesFile adds user-agent substrings to the built-in crawler ones,
+		// one per line. Lines starting with ! exempt user agents instead
+		BotRulesFile string `split_words:"true" default:"" `
+	}
+
+	Trending struct {
+		// Enabled tracks the most redirected links over a sliding window
+		Enabled bool `split_words:"true" default:"true" `
+
+		// Capacity is how many links each slot tracks, counts are exact until
+		// more distinct links than this are redirected within a slot
+		Capacity int `split_words:"true" default:"1000" `
+
+		// Resolution is the width of the slots the window slides by
+		Resolution time.Duration `split_words:"true" default:"1m" `
+
+		// MaxWindow is the widest window that can be queried, longer ones are capped
+		MaxWindow time.Duration `split_words:"true" default:"1h" `
+
+		// DefaultWindow is the window when none is requested
+		DefaultWindow time.Duration `split_words:"true" default:"5m" `
+
+		// DefaultLimit is how many links are returned when no limit is requested
+		DefaultLimit int `split_words:"true" default:"20" `
+
+		// MaxLimit caps the requested limit
+		MaxLimit int `split_words:"true" default:"200" `
+
+		// WarmupLimit is how many trending links a new replica loads into its
+		// cache from its peers on start, up to their MaxLimit. Zero disables it
+		WarmupLimit int `split_words:"true" default:"200" `
+
+		// WarmupWindow is the window peers are asked for trending links
+		WarmupWindow time.Duration `split_words:"true" default:"15m" `
+
+		// WarmupTimeout bounds the whole warmup, start is delayed by up to this
+		WarmupTimeout time.Duration `split_words:"true" default:"5s" `
+	}
+
+	Clicks struct {
+		// Enabled captures an anonymized event for every redirect
+		Enabled bool `split_words:"true" default:"false" `
+
+		// BufferSize is how many clicks can be queued before new ones are dropped
+		BufferSize int `split_words:"true" default:"65536" `
+
+		// FlushInterval is how often pending events are written to the sink
+		FlushInterval time.Duration `split_words:"true" default:"5s" `
+
+		// MaxBatch forces a write once this many events are pending
+		MaxBatch int `split_words:"true" default:"500" `
+
+		// WriteTimeout bounds each write to the sink
+		WriteTimeout time.Duration `split_words:"true" default:"5s" `
+
+		// GeoipFile is a CSV of start_ip,end_ip,country_code ranges, empty leaves countries unknown
+		GeoipFile string `split_words:"true" default:""`
+
+		// Salt keys visitor hashes, replicas must share it for hashes to match.
+		// Empty draws a random one on every start
+		Salt string `split_words:"true" default:""`
+
+		// Sinks is where events are written, any of log, file, webhook and otlp
+		Sinks []string `split_words:"true" default:"log"`
+
+		// FilePath is the JSONL file events are appended to, rotated files are kept next to it
+		FilePath string `split_words:"true" default:"clicks.jsonl"`
+
+		// FileMaxBytes rotates the file once it grows past this size
+		FileMaxBytes int64 `split_words:"true" default:"104857600"`
+
+		// FileMaxAge rotates the file once it is this old, zero only rotates by size
+		FileMaxAge time.Duration `split_words:"true" default:"24h"`
+
+		// FileMaxBackups is how many rotated files are kept, zero keeps them all
+		FileMaxBackups int `split_words:"true" default:"7"`
+
+		// WebhookUrl receives batches of events as JSON POST requests
+		WebhookUrl string `split_words:"true" default:""`
+
+		// WebhookToken is sent as the Authorization header, empty sends none
+		WebhookToken string `split_words:"true" default:""`
+
+		// Retries is how many times a failed webhook or OTLP export is repeated
+		Retries int `split_words:"true" default:"3"`
+
+		// RetryBackoff is the base delay between exports, jittered and doubled every time
+		RetryBackoff time.Duration `split_words:"true" default:"200ms"`
+
+		// RetryMaxBackoff caps the delay between exports
+		RetryMaxBackoff time.Duration `split_words:"true" default:"2s"`
+
+		// SpillDir keeps batches the webhook could not take until it recovers, empty drops them
+		SpillDir string `split_words:"true" default:""`
+
+		// SpillMaxBytes bounds the disk used by spilled batches
+		SpillMaxBytes int64 `split_words:"true" default:"67108864"`
+	}
+
+	Invalidation struct {
+		// Token authenticates invalidations between replicas, empty disables them
+		Token string `split_words:"true" default:""`
+
+		// Peers is a static list of replica base URLs, i.e: http://10.0.0.1:8080
+		Peers []string `split_words:"true"`
+
+		// PeersDns is a headless service name resolving to every replica, takes precedence over Peers
+		PeersDns string `split_words:"true"`
+
+		// Timeout how much to wait for each peer to acknowledge
+		Timeout time.Duration `split_words:"true" default:"250ms" `
+	}
 }
 
 func Load() AppConfig {
@@ -0,0 +1,61 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+type ClickEvent struct {
+	At           time.Time `json:"at"`
+	URLId        string    `json:"url_id"`
+	ReferrerHost string    `json:"referrer_host,omitempty"`
+	Browser      string    `json:"browser"`
+	Device       string    `json:"device"`
+	Country      string    `json:"country,omitempty"`
+	Language     string    `json:"language,omitempty"`
+	VisitorHash  string    `json:"visitor_hash"`
+	IsBot        bool      `json:"is_bot"`
+}
+
+// ClickBatch is the body of webhook deliveries
+type ClickBatch struct {
+	Events []ClickEvent `json:"events"`
+}
+
+func FromClickEvent(e domain.ClickEvent) ClickEvent {
+	return ClickEvent{
+		At:           e.At,
+		URLId:        e.URLId,
+		ReferrerHost: e.ReferrerHost,
+		Browser:      e.Browser,
+		Device:       e.Device,
+		Country:      e.Country,
+		Language:     e.Language,
+		VisitorHash:  e.VisitorHash,
+		IsBot:        e.Bot,
+	}
+}
+
+func FromClickEvents(events []domain.ClickEvent) ClickBatch {
+	batch := ClickBatch{Events: make([]ClickEvent, 0, len(events))}
+	for _, e := range events {
+		batch.Events = append(batch.Events, FromClickEvent(e))
+	}
+
+	return batch
+}
+
+func (e ClickEvent) ToDomain() domain.ClickEvent {
+	return domain.ClickEvent{
+		At:           e.At,
+		URLId:        e.URLId,
+		ReferrerHost: e.ReferrerHost,
+		Browser:      e.Browser,
+		Device:       e.Device,
+		Country:      e.Country,
+		Language:     e.Language,
+		VisitorHash:  e.VisitorHash,
+		Bot:          e.IsBot,
+	}
+}
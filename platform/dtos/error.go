@@ -2,4 +2,7 @@ package dtos
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+
+	// Rule names the URL policy rule that rejected the request, if any
+	Rule string `json:"rule,omitempty"`
 }
@@ -0,0 +1,6 @@
+package dtos
+
+type InvalidationRequest struct {
+	URLId  string `json:"url_id"`
+	Action string `json:"action"`
+}
@@ -1,9 +1,22 @@
 package dtos
 
+import "time"
+
 type URLCreateRequest struct {
-	Upstream string `json:"full_url"`
+	Upstream  string `json:"full_url"`
+	Alias     string `json:"alias,omitempty"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
 }
 
 type URLCreateResponse struct {
 	ShortURL string `json:"short_url"`
 }
+
+// Expiration converts the optional unix timestamp, zero meaning no expiration
+func (r URLCreateRequest) Expiration() time.Time {
+	if r.ExpiresAt == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(r.ExpiresAt, 0)
+}
@@ -3,17 +3,28 @@ package dtos
 import "github.com/neonmei/challenge_urlshortener/domain"
 
 type URLFetchResponse struct {
+	ID        string `json:"url_id"`
 	URL       string `json:"full_url"`
 	Enabled   bool   `json:"enabled"`
 	CreatedAt int64  `json:"created_at"`
 	CreatedBy string `json:"created_by"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	Version   int64  `json:"version"`
 }
 
 func FromDomain(item domain.ShortURL) URLFetchResponse {
-	return URLFetchResponse{
+	response := URLFetchResponse{
+		ID:        item.ID,
 		URL:       item.Upstream.String(),
 		Enabled:   item.Enabled,
 		CreatedAt: item.CreatedAt.Unix(),
 		CreatedBy: item.CreatedBy,
+		Version:   item.Version,
 	}
+
+	if !item.ExpiresAt.IsZero() {
+		response.ExpiresAt = item.ExpiresAt.Unix()
+	}
+
+	return response
 }
@@ -0,0 +1,21 @@
+package dtos
+
+import "github.com/neonmei/challenge_urlshortener/domain"
+
+type URLListResponse struct {
+	Items      []URLFetchResponse `json:"items"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+func FromPage(page domain.URLPage) URLListResponse {
+	response := URLListResponse{
+		Items:      make([]URLFetchResponse, 0, len(page.Items)),
+		NextCursor: page.Cursor,
+	}
+
+	for _, item := range page.Items {
+		response.Items = append(response.Items, FromDomain(item))
+	}
+
+	return response
+}
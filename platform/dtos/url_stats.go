@@ -0,0 +1,52 @@
+package dtos
+
+import "github.com/neonmei/challenge_urlshortener/domain"
+
+type HitBucket struct {
+	Start int64 `json:"start"`
+	Hits  int64 `json:"hits"`
+	Bots  int64 `json:"bots"`
+
+	// UniqueVisitors is only set with day granularity, visitors are counted per day
+	UniqueVisitors *int64 `json:"unique_visitors,omitempty"`
+}
+
+type URLStatsResponse struct {
+	URLId          string      `json:"url_id"`
+	Granularity    string      `json:"granularity"`
+	From           int64       `json:"from"`
+	To             int64       `json:"to"`
+	ExcludeBots    bool        `json:"exclude_bots"`
+	Total          int64       `json:"total"`
+	TotalBots      int64       `json:"total_bots"`
+	UniqueVisitors int64       `json:"unique_visitors"`
+	Series         []HitBucket `json:"series"`
+}
+
+// FromURLStats reports hits without bots when they were excluded, bots
+// are always reported apart
+func FromURLStats(urlID string, g domain.Granularity, from int64, to int64, excludeBots bool, stats *domain.URLStats) URLStatsResponse {
+	response := URLStatsResponse{
+		URLId:          urlID,
+		Granularity:    string(g),
+		From:           from,
+		To:             to,
+		ExcludeBots:    excludeBots,
+		UniqueVisitors: stats.UniqueVisitors,
+		Series:         make([]HitBucket, 0, len(stats.Series)),
+	}
+
+	for _, c := range stats.Series {
+		response.Total += c.Hits
+		response.TotalBots += c.Bots
+		bucket := HitBucket{Start: c.Start.Unix(), Hits: c.Hits, Bots: c.Bots}
+		if g == domain.GranularityDay {
+			visitors := stats.DailyVisitors[c.Start.Unix()]
+			bucket.UniqueVisitors = &visitors
+		}
+
+		response.Series = append(response.Series, bucket)
+	}
+
+	return response
+}
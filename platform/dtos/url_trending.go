@@ -0,0 +1,33 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+type TrendingURL struct {
+	URLId string `json:"url_id"`
+	Hits  int64  `json:"hits"`
+
+	// Error is how much Hits may overestimate the redirects of the URL
+	Error int64 `json:"error"`
+}
+
+type TrendingResponse struct {
+	Window string        `json:"window"`
+	URLs   []TrendingURL `json:"urls"`
+}
+
+func FromTrending(window time.Duration, urls []domain.TrendingURL) TrendingResponse {
+	response := TrendingResponse{
+		Window: window.String(),
+		URLs:   make([]TrendingURL, 0, len(urls)),
+	}
+
+	for _, u := range urls {
+		response.URLs = append(response.URLs, TrendingURL{URLId: u.URLId, Hits: u.Hits, Error: u.Error})
+	}
+
+	return response
+}
@@ -0,0 +1,36 @@
+package dtos
+
+import "github.com/neonmei/challenge_urlshortener/domain"
+
+type URLUpdateRequest struct {
+	Upstream *string `json:"full_url,omitempty"`
+	Enabled  *bool   `json:"enabled,omitempty"`
+	Version  *int64  `json:"version,omitempty"`
+}
+
+type RevisionResponse struct {
+	Version   int64  `json:"version"`
+	URL       string `json:"full_url"`
+	Enabled   bool   `json:"enabled"`
+	ChangedBy string `json:"changed_by"`
+	ChangedAt int64  `json:"changed_at"`
+}
+
+type RevisionsResponse struct {
+	Revisions []RevisionResponse `json:"revisions"`
+}
+
+func FromRevisions(revisions []domain.Revision) RevisionsResponse {
+	response := RevisionsResponse{Revisions: make([]RevisionResponse, 0, len(revisions))}
+	for _, r := range revisions {
+		response.Revisions = append(response.Revisions, RevisionResponse{
+			Version:   r.Version,
+			URL:       r.Upstream.String(),
+			Enabled:   r.Enabled,
+			ChangedBy: r.ChangedBy,
+			ChangedAt: r.ChangedAt.Unix(),
+		})
+	}
+
+	return response
+}
@@ -0,0 +1,127 @@
+package invalidation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+	"github.com/neonmei/challenge_urlshortener/platform/o11y/semconv"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Path is where replicas listen for invalidations from their peers
+const Path = "/platform/cache/invalidations"
+
+// Broadcaster fans out invalidations to every peer replica. Delivery is best
+// effort, the repository stays the source of truth.
+type Broadcaster struct {
+	peers   PeerSource
+	client  *http.Client
+	token   string
+	timeout time.Duration
+	sent    metric.Int64Counter
+	pending sync.WaitGroup
+}
+
+// Broadcast synchronously sends the invalidation to every peer
+func (b *Broadcaster) Broadcast(ctx context.Context, urlID string, action Action) error {
+	peers, err := b.peers.Peers(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(dtos.InvalidationRequest{URLId: urlID, Action: string(action)})
+	if err != nil {
+		return err
+	}
+
+	wg := sync.WaitGroup{}
+	errs := make([]error, len(peers))
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, peer string) {
+			defer wg.Done()
+			errs[i] = b.send(ctx, peer, body)
+
+			b.sent.Add(ctx, 1, metric.WithAttributes(
+				attribute.String(semconv.InvalidationAction, string(action)),
+				attribute.Bool(semconv.InvalidationFailed, errs[i] != nil),
+			))
+		}(i, peer)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Notify broadcasts in background so callers are not slowed down by peers
+func (b *Broadcaster) Notify(urlID string, action Action) {
+	b.pending.Add(1)
+	go func() {
+		defer b.pending.Done()
+		ctx, cancelFunc := context.WithTimeout(context.Background(), b.timeout)
+		defer cancelFunc()
+
+		if err := b.Broadcast(ctx, urlID, action); err != nil {
+			slog.Warn("cache invalidation broadcast failed", "url_id", urlID, "error", err)
+		}
+	}()
+}
+
+// Wait blocks until background notifications are done, used on shutdown
+func (b *Broadcaster) Wait() {
+	b.pending.Wait()
+}
+
+func (b *Broadcaster) send(ctx context.Context, peer string, body []byte) error {
+	ctx, cancelFunc := context.WithTimeout(ctx, b.timeout)
+	defer cancelFunc()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, peer+Path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", b.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return errors.Join(ErrPeerRejected, fmt.Errorf("%s answered %d", peer, resp.StatusCode))
+	}
+
+	return nil
+}
+
+func NewBroadcaster(peers PeerSource, token string, timeout time.Duration) (*Broadcaster, error) {
+	m := otel.GetMeterProvider().Meter("invalidation")
+	c, err := m.Int64Counter(
+		semconv.MetricInvalidationsSent,
+		metric.WithDescription("Number of invalidations sent to peer replicas."),
+		metric.WithUnit("{call}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Broadcaster{
+		peers:   peers,
+		client:  &http.Client{},
+		token:   token,
+		timeout: timeout,
+		sent:    c,
+	}, nil
+}
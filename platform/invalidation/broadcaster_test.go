@@ -0,0 +1,128 @@
+package invalidation_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2"
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/invalidation"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+const testToken = "replica-secret"
+
+type replica struct {
+	cache  *repositories.URLCache
+	server *httptest.Server
+}
+
+func newReplica(t *testing.T) replica {
+	cache, err := ristretto.NewCache(&repositories.URLCacheConfig{
+		NumCounters: 1000,
+		MaxCost:     100,
+		BufferItems: 64,
+	})
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(invalidation.NewHandler(testToken, validators.IdAlphabet{}, repositories.NewCacheTarget(cache, nil)))
+	t.Cleanup(server.Close)
+	t.Cleanup(cache.Close)
+
+	return replica{cache: cache, server: server}
+}
+
+func validItem() domain.ShortURL {
+	return domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}
+}
+
+func TestDeleteShouldReachEveryReplica(t *testing.T) {
+	ctx := context.Background()
+	shared := repositories.NewMemory()
+	replicas := []replica{newReplica(t), newReplica(t), newReplica(t)}
+
+	peers := invalidation.StaticPeers{}
+	for _, r := range replicas[1:] {
+		peers = append(peers, r.server.URL)
+	}
+
+	broadcaster, err := invalidation.NewBroadcaster(peers, testToken, time.Second)
+	assert.NoError(t, err)
+
+	origin := repositories.NewCached(shared, replicas[0].cache, repositories.WithPeerNotifier(broadcaster))
+	assert.NoError(t, origin.Save(ctx, validItem()))
+	broadcaster.Wait()
+
+	// REF: every other replica has a hot copy of the item
+	for _, r := range replicas[1:] {
+		result, err := repositories.NewCached(shared, r.cache).Get(ctx, validId)
+		assert.NoError(t, err)
+		assert.True(t, result.Enabled)
+	}
+
+	assert.NoError(t, origin.Delete(ctx, validId))
+	broadcaster.Wait()
+
+	for _, r := range replicas {
+		item, found := r.cache.Get(validId)
+		assert.True(t, found)
+		assert.False(t, item.URL.Enabled)
+	}
+}
+
+func TestBroadcastEvict(t *testing.T) {
+	ctx := context.Background()
+	peer := newReplica(t)
+	peer.cache.Set(validId, repositories.CacheEntry{URL: validItem(), FetchedAt: time.Now()}, 1)
+	peer.cache.Wait()
+
+	broadcaster, err := invalidation.NewBroadcaster(invalidation.StaticPeers{peer.server.URL}, testToken, time.Second)
+	assert.NoError(t, err)
+
+	assert.NoError(t, broadcaster.Broadcast(ctx, validId, invalidation.ActionEvict))
+	_, found := peer.cache.Get(validId)
+	assert.False(t, found)
+}
+
+func TestBroadcastBadToken(t *testing.T) {
+	ctx := context.Background()
+	peer := newReplica(t)
+	peer.cache.Set(validId, repositories.CacheEntry{URL: validItem(), FetchedAt: time.Now()}, 1)
+	peer.cache.Wait()
+
+	broadcaster, err := invalidation.NewBroadcaster(invalidation.StaticPeers{peer.server.URL}, "wrong", time.Second)
+	assert.NoError(t, err)
+
+	// REF: unauthenticated invalidations are rejected and reported
+	assert.ErrorIs(t, broadcaster.Broadcast(ctx, validId, invalidation.ActionEvict), invalidation.ErrPeerRejected)
+	_, found := peer.cache.Get(validId)
+	assert.True(t, found)
+}
+
+func TestBroadcastUnreachablePeer(t *testing.T) {
+	ctx := context.Background()
+	peer := newReplica(t)
+	peer.cache.Set(validId, repositories.CacheEntry{URL: validItem(), FetchedAt: time.Now()}, 1)
+	peer.cache.Wait()
+
+	dead := httptest.NewServer(nil)
+	dead.Close()
+
+	broadcaster, err := invalidation.NewBroadcaster(invalidation.StaticPeers{dead.URL, peer.server.URL}, testToken, time.Second)
+	assert.NoError(t, err)
+
+	// REF: a dead peer must not prevent the others from being invalidated
+	assert.Error(t, broadcaster.Broadcast(ctx, validId, invalidation.ActionEvict))
+	_, found := peer.cache.Get(validId)
+	assert.False(t, found)
+}
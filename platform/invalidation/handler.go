@@ -0,0 +1,53 @@
+package invalidation
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+)
+
+// NewHandler receives invalidations from peers and applies them locally.
+// It never re-broadcasts, so replicas cannot loop between each other.
+func NewHandler(token string, ids validators.IdAlphabet, target Target) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		headerToken := r.Header.Get("Authorization")
+		if token == "" || subtle.ConstantTimeCompare([]byte(headerToken), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+
+		request := dtos.InvalidationRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		action := Action(request.Action)
+		if err := action.Valid(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := validators.ValidateId(request.URLId, ids); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		target.Invalidate(request.URLId, action)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(dtos.ErrorResponse{Error: msg})
+}
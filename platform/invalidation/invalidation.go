@@ -0,0 +1,40 @@
+package invalidation
+
+import (
+	"errors"
+)
+
+// Action tells a replica what to do with its cached copy of an url_id
+type Action string
+
+const (
+	// ActionEvict drops the entry, next read goes to the repository
+	ActionEvict Action = "evict"
+
+	// ActionDisable negative-caches the entry so it stops redirecting
+	ActionDisable Action = "disable"
+)
+
+var (
+	ErrUnknownAction = errors.New("unknown invalidation action")
+	ErrPeerRejected  = errors.New("peer rejected invalidation")
+)
+
+// Target is implemented by whatever holds a local cache of short URLs
+type Target interface {
+	Invalidate(urlID string, action Action)
+}
+
+// Notifier propagates an invalidation to other replicas
+type Notifier interface {
+	Notify(urlID string, action Action)
+}
+
+func (a Action) Valid() error {
+	switch a {
+	case ActionEvict, ActionDisable:
+		return nil
+	default:
+		return ErrUnknownAction
+	}
+}
@@ -0,0 +1,9 @@
+package invalidation_test
+
+import "net/url"
+
+var (
+	validURL, _ = url.Parse("https://opentelemetry.io")
+	validAuthor = "root@neonmei.cloud"
+	validId     = "asd"
+)
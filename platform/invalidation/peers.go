@@ -0,0 +1,61 @@
+package invalidation
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// PeerSource lists base URLs of replicas that should receive invalidations
+type PeerSource interface {
+	Peers(ctx context.Context) ([]string, error)
+}
+
+// HostResolver is satisfied by *net.Resolver
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// StaticPeers is a fixed list of base URLs, i.e: http://10.0.0.1:8080
+type StaticPeers []string
+
+func (s StaticPeers) Peers(_ context.Context) ([]string, error) {
+	return s, nil
+}
+
+// DNSPeers resolves a name into every replica address, which is how a
+// kubernetes headless service exposes its pods
+type DNSPeers struct {
+	Name     string
+	Port     int
+	Resolver HostResolver
+}
+
+func (d DNSPeers) Peers(ctx context.Context) ([]string, error) {
+	resolver := d.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	hosts, err := resolver.LookupHost(ctx, d.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		peers = append(peers, fmt.Sprintf("http://%s", net.JoinHostPort(host, fmt.Sprint(d.Port))))
+	}
+
+	return peers, nil
+}
+
+// NewPeerSource prefers DNS discovery when a name is configured
+func NewPeerSource(static []string, dnsName string, port int) PeerSource {
+	if strings.TrimSpace(dnsName) != "" {
+		return DNSPeers{Name: dnsName, Port: port}
+	}
+
+	return StaticPeers(static)
+}
@@ -0,0 +1,47 @@
+package invalidation_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/neonmei/challenge_urlshortener/platform/invalidation"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	hosts, found := f[host]
+	if !found {
+		return nil, errors.New("no such host")
+	}
+
+	return hosts, nil
+}
+
+func TestDNSPeers(t *testing.T) {
+	ctx := context.Background()
+	source := invalidation.DNSPeers{
+		Name: "shortener-headless",
+		Port: 8080,
+		Resolver: fakeResolver{
+			"shortener-headless": {"10.0.0.1", "10.0.0.2", "fd00::1"},
+		},
+	}
+
+	peers, err := source.Peers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://10.0.0.1:8080", "http://10.0.0.2:8080", "http://[fd00::1]:8080"}, peers)
+
+	source.Name = "missing"
+	_, err = source.Peers(ctx)
+	assert.Error(t, err)
+}
+
+func TestNewPeerSource(t *testing.T) {
+	static := []string{"http://10.0.0.1:8080"}
+
+	assert.IsType(t, invalidation.StaticPeers{}, invalidation.NewPeerSource(static, "", 8080))
+	assert.IsType(t, invalidation.DNSPeers{}, invalidation.NewPeerSource(static, "shortener-headless", 8080))
+}
@@ -11,7 +11,14 @@ import (
 	"go.opentelemetry.io/otel/metric"
 )
 
-func InstrumentCacheAsync[K z.Key, V any](c *ristretto.Cache[K, V]) error {
+// CacheCounters are lookup outcomes tracked outside of ristretto
+type CacheCounters interface {
+	NegativeHits() uint64
+	CoalescedLookups() uint64
+	StaleServed() uint64
+}
+
+func InstrumentCacheAsync[K z.Key, V any](c *ristretto.Cache[K, V], counters CacheCounters) error {
 	meter := otel.GetMeterProvider().Meter("ristretto")
 
 	_, err1 := meter.Int64ObservableCounter(
@@ -64,5 +71,35 @@ func InstrumentCacheAsync[K z.Key, V any](c *ristretto.Cache[K, V]) error {
 		}),
 	)
 
-	return errors.Join(err1, err2, err3, err4, err5)
+	_, err6 := meter.Int64ObservableCounter(
+		semconv.CacheNegativeHit,
+		metric.WithDescription("How many cache read operations found a remembered not found."),
+		metric.WithUnit("{call}"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(counters.NegativeHits()))
+			return nil
+		}),
+	)
+
+	_, err7 := meter.Int64ObservableCounter(
+		semconv.CacheCoalesced,
+		metric.WithDescription("How many cache misses shared an in-flight upstream read."),
+		metric.WithUnit("{call}"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(counters.CoalescedLookups()))
+			return nil
+		}),
+	)
+
+	_, err8 := meter.Int64ObservableCounter(
+		semconv.CacheStale,
+		metric.WithDescription("How many cache read operations served a stale entry."),
+		metric.WithUnit("{call}"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(counters.StaleServed()))
+			return nil
+		}),
+	)
+
+	return errors.Join(err1, err2, err3, err4, err5, err6, err7, err8)
 }
@@ -0,0 +1,102 @@
+package o11y
+
+import (
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/honeycombio/otel-config-go/otelconfig"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// OTLPTarget is where and how OTLP/HTTP exports are sent
+type OTLPTarget struct {
+	// Endpoint is the full URL exports are posted to
+	Endpoint string
+
+	// Headers are sent with every export, i.e: x-honeycomb-team
+	Headers map[string]string
+
+	// Resource describes this process, the same one traces and metrics carry
+	Resource *resource.Resource
+}
+
+// CaptureConfig is an otelconfig option that keeps the configuration it is
+// applied to. It is only complete once ConfigureOpenTelemetry returned, as
+// environment variables are processed after options
+func CaptureConfig(dst **otelconfig.Config) otelconfig.Option {
+	return func(c *otelconfig.Config) {
+		*dst = c
+	}
+}
+
+// LogsTarget resolves where log records go from the configuration otelconfig
+// used for traces and metrics. OTEL_EXPORTER_OTLP_LOGS_ENDPOINT is used as
+// is, like the standard exporters do, otherwise /v1/logs is appended to the
+// generic endpoint. otelconfig defaults to gRPC, so its port is swapped for
+// the OTLP/HTTP one
+func LogsTarget(c *otelconfig.Config) OTLPTarget {
+	headers := maps.Clone(c.Headers)
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	maps.Copy(headers, parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_LOGS_HEADERS")))
+
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT")
+	if endpoint == "" {
+		endpoint = httpEndpoint(c.ExporterEndpoint, c.ExporterEndpointInsecure) + "/v1/logs"
+	}
+
+	return OTLPTarget{
+		Endpoint: endpoint,
+		Headers:  headers,
+		Resource: c.Resource,
+	}
+}
+
+func httpEndpoint(endpoint string, insecure bool) string {
+	if !strings.Contains(endpoint, "://") {
+		scheme := "https"
+		if insecure {
+			scheme = "http"
+		}
+		endpoint = scheme + "://" + endpoint
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return strings.TrimRight(endpoint, "/")
+	}
+
+	switch {
+	case u.Port() == otelconfig.GRPCDefaultPort:
+		u.Host = net.JoinHostPort(u.Hostname(), otelconfig.HTTPDefaultPort)
+	case u.Port() == "" && u.Scheme == "http":
+		u.Host = net.JoinHostPort(u.Hostname(), otelconfig.HTTPDefaultPort)
+	}
+
+	return strings.TrimRight(u.String(), "/")
+}
+
+// parseHeaders reads the key1=value1,key2=value2 format of OTEL_EXPORTER_OTLP_HEADERS
+func parseHeaders(raw string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			continue
+		}
+
+		key, errKey := url.PathUnescape(strings.TrimSpace(key))
+		value, errValue := url.PathUnescape(strings.TrimSpace(value))
+		if errKey != nil || errValue != nil || key == "" {
+			continue
+		}
+
+		headers[key] = value
+	}
+
+	return headers
+}
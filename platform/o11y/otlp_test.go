@@ -0,0 +1,42 @@
+package o11y
+
+import (
+	"testing"
+
+	"github.com/honeycombio/otel-config-go/otelconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogsTargetEndpoint(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		insecure bool
+		expected string
+	}{
+		{"localhost", false, "https://localhost/v1/logs"},
+		{"localhost", true, "http://localhost:4318/v1/logs"},
+		{"collector:4317", true, "http://collector:4318/v1/logs"},
+		{"api.honeycomb.io:443", false, "https://api.honeycomb.io:443/v1/logs"},
+		{"http://collector:4318/", false, "http://collector:4318/v1/logs"},
+		{"https://collector.example.com", false, "https://collector.example.com/v1/logs"},
+	}
+
+	for _, c := range cases {
+		target := LogsTarget(&otelconfig.Config{ExporterEndpoint: c.endpoint, ExporterEndpointInsecure: c.insecure})
+		assert.Equal(t, c.expected, target.Endpoint, c.endpoint)
+	}
+}
+
+func TestLogsTargetOverrides(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "http://logs:4318/custom")
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_HEADERS", "x-team=logs%20team, x-extra=1")
+
+	target := LogsTarget(&otelconfig.Config{
+		ExporterEndpoint: "collector:4317",
+		Headers:          map[string]string{"x-team": "generic", "x-dataset": "clicks"},
+	})
+
+	// REF: logs specific settings win over the generic ones
+	assert.Equal(t, "http://logs:4318/custom", target.Endpoint)
+	assert.Equal(t, map[string]string{"x-team": "logs team", "x-extra": "1", "x-dataset": "clicks"}, target.Headers)
+}
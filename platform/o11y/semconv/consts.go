@@ -6,17 +6,56 @@ const (
 	UrlEnabled = "short_url.enabled"
 	UrlFull    = "short_url.full"
 	UrlCreated = "short_url.created"
+	UrlExpires = "short_url.expires"
+	UrlVersion = "short_url.version"
 
-	HasherRounds = "hasher.rounds"
-	HasherLength = "hasher.length"
+	HasherRounds   = "hasher.rounds"
+	HasherLength   = "hasher.length"
+	HasherStrategy = "hasher.strategy"
 
-	CacheHit      = "cache.access.hit"
-	CacheMiss     = "cache.access.miss"
-	CacheAdded    = "cache.keys.added"
-	CacheEvicted  = "cache.keys.evicted"
-	CacheRejected = "cache.keys.rejected"
+	CacheHit         = "cache.access.hit"
+	CacheNegativeHit = "cache.access.negative_hit"
+	CacheCoalesced   = "cache.access.coalesced"
+	CacheStale       = "cache.access.stale"
+	CacheMiss        = "cache.access.miss"
+	CacheAdded       = "cache.keys.added"
+	CacheEvicted     = "cache.keys.evicted"
+	CacheRejected    = "cache.keys.rejected"
+
+	BreakerName = "breaker.name"
+	BreakerFrom = "breaker.from"
+	BreakerTo   = "breaker.to"
+
+	HedgeFired = "hedge.fired"
+	HedgeWon   = "hedge.won"
+
+	RetryAttempt = "retry.attempt"
+	RetryDelay   = "retry.delay_ms"
+	RetryError   = "retry.error"
+
+	InvalidationAction = "invalidation.action"
+	InvalidationFailed = "invalidation.failed"
+
+	HitIsBot = "is_bot"
+
+	ClicksDropReason = "clicks.drop_reason"
+	ClickReferrer    = "click.referrer_host"
+	ClickBrowser     = "click.browser"
+	ClickDevice      = "click.device"
+	ClickCountry     = "click.country"
+	ClickLanguage    = "click.language"
+	ClickVisitor     = "click.visitor_hash"
+	ClickIsBot       = "click.is_bot"
 )
 
 const (
-	MetricURLHits = "meli.shortener.url.hits"
+	MetricURLHits            = "meli.shortener.url.hits"
+	MetricInvalidationsSent  = "meli.shortener.cache.invalidations"
+	MetricHitsDropped        = "meli.shortener.analytics.dropped"
+	MetricClicksDropped      = "meli.shortener.clicks.dropped"
+	MetricClicksSpilled      = "meli.shortener.clicks.spilled"
+	MetricBreakerTransitions = "meli.shortener.breaker.transitions"
+	MetricBreakerState       = "meli.shortener.breaker.state"
+	MetricHedgesFired        = "meli.shortener.hedge.fired"
+	MetricHedgesWon          = "meli.shortener.hedge.won"
 )
@@ -21,7 +21,12 @@ func TraceShortURL(ctx context.Context, u *domain.ShortURL) *domain.ShortURL {
 		attribute.String(semconv.UrlFull, u.Upstream.String()),
 		attribute.Bool(semconv.UrlEnabled, u.Enabled),
 		attribute.Int64(semconv.UrlCreated, u.CreatedAt.Unix()),
+		attribute.Int64(semconv.UrlVersion, u.Version),
 	)
 
+	if !u.ExpiresAt.IsZero() {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int64(semconv.UrlExpires, u.ExpiresAt.Unix()))
+	}
+
 	return u
 }
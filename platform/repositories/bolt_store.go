@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltURLsBucket      = []byte("urls")
+	boltRevisionsBucket = []byte("revisions")
+	boltHitsBucket      = []byte("hits")
+	boltVisitorsBucket  = []byte("visitors")
+	boltSequencesBucket = []byte("sequences")
+)
+
+func ensureBoltBuckets(db *bolt.DB, names ...[]byte) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range names {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return nil
+}
+
+// BackupBolt copies a consistent snapshot of db to path while it keeps serving
+// reads and writes. The copy is synced to a temporary file and renamed over
+// path, so a crash midway never leaves a truncated backup behind
+func BackupBolt(db *bolt.DB, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	err = db.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(tmp)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
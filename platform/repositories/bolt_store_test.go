@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupBolt(t *testing.T) {
+	cfg := boltTestConfig(t)
+	db := openTestBolt(t, cfg)
+	repo, err := NewBoltURLRepository(db, validators.IdAlphabet{})
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	assert.NoError(t, repo.Save(ctx, domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}))
+
+	// REF: backups are taken while the database is open and replace older ones
+	backupPath := filepath.Join(t.TempDir(), "backup.bolt")
+	assert.NoError(t, os.WriteFile(backupPath, []byte("older backup"), 0o600))
+	assert.NoError(t, BackupBolt(db, backupPath))
+
+	// REF: no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(backupPath))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// REF: the backup is a working database
+	restored := cfg
+	restored.Bolt.Path = backupPath
+	restoredRepo, err := NewBoltURLRepository(openTestBolt(t, restored), validators.IdAlphabet{})
+	assert.NoError(t, err)
+
+	retrieved, err := restoredRepo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, validURL.String(), retrieved.Upstream.String())
+
+	assert.Error(t, BackupBolt(db, filepath.Join(t.TempDir(), "missing", "backup.bolt")))
+}
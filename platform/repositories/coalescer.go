@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+// coalescer merges concurrent lookups of the same identifier into a single
+// upstream call, a minimal singleflight
+type coalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done   chan struct{}
+	result *domain.ShortURL
+	err    error
+}
+
+// Do runs fn unless a call for urlID is already in flight, in which case it
+// waits for and shares its outcome. Shared reports the latter
+func (c *coalescer) Do(urlID string, fn func() (*domain.ShortURL, error)) (result *domain.ShortURL, err error, shared bool) {
+	c.mu.Lock()
+	if call, found := c.calls[urlID]; found {
+		c.mu.Unlock()
+		<-call.done
+		return call.result, call.err, true
+	}
+
+	call := &coalescedCall{done: make(chan struct{})}
+	c.calls[urlID] = call
+	c.mu.Unlock()
+
+	c.run(urlID, call, fn)
+	return call.result, call.err, false
+}
+
+// Go runs fn in the background unless a call for urlID is already in
+// flight, so background refreshes never pile up
+func (c *coalescer) Go(urlID string, fn func() (*domain.ShortURL, error)) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, found := c.calls[urlID]; found {
+		return false
+	}
+
+	call := &coalescedCall{done: make(chan struct{})}
+	c.calls[urlID] = call
+	go c.run(urlID, call, fn)
+	return true
+}
+
+func (c *coalescer) run(urlID string, call *coalescedCall, fn func() (*domain.ShortURL, error)) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, urlID)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	// REF: followers of a panicking call must not see a nil result without error
+	call.err = domain.ErrUnavailableRepo
+	call.result, call.err = fn()
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{calls: map[string]*coalescedCall{}}
+}
+
+// CacheCounters tracks lookups the cache answered without ristretto knowing,
+// it is safe for concurrent use
+type CacheCounters struct {
+	negativeHits atomic.Uint64
+	coalesced    atomic.Uint64
+	stale        atomic.Uint64
+}
+
+// NegativeHits is how many lookups were answered by a cached not found
+func (c *CacheCounters) NegativeHits() uint64 {
+	return c.negativeHits.Load()
+}
+
+// CoalescedLookups is how many lookups shared an in-flight upstream call
+func (c *CacheCounters) CoalescedLookups() uint64 {
+	return c.coalesced.Load()
+}
+
+// StaleServed is how many lookups were answered past their soft TTL or from
+// the last known good copy while upstream was unavailable
+func (c *CacheCounters) StaleServed() uint64 {
+	return c.stale.Load()
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoalescerSequentialCallsAreNotShared(t *testing.T) {
+	c := newCoalescer()
+	calls := 0
+	fn := func() (*domain.ShortURL, error) {
+		calls++
+		return &domain.ShortURL{ID: validId}, nil
+	}
+
+	for range 2 {
+		result, err, shared := c.Do(validId, fn)
+		assert.NoError(t, err)
+		assert.False(t, shared)
+		assert.Equal(t, validId, result.ID)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestCoalescerPanicReleasesFollowers(t *testing.T) {
+	c := newCoalescer()
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go func() {
+		defer func() { _ = recover() }()
+		c.Do(validId, func() (*domain.ShortURL, error) {
+			close(started)
+			<-release
+			panic("upstream bug")
+		})
+	}()
+
+	<-started
+	followerDone := make(chan error)
+	go func() {
+		result, err, _ := c.Do(validId, func() (*domain.ShortURL, error) {
+			return &domain.ShortURL{ID: validId}, nil
+		})
+		assert.True(t, result == nil || result.ID == validId)
+		followerDone <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-followerDone:
+		// REF: either it joined the panicking call or ran after it
+		if err != nil {
+			assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("follower never released")
+	}
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/clients/dynamotest"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+// REF: every local backend and decorator behaves like the DynamoDB repository
+func TestURLRepositoryConformance(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		repotest.TestURLRepository(t, func(t *testing.T) domain.URLRepository {
+			return NewMemory()
+		})
+	})
+
+	t.Run("dynamodb", func(t *testing.T) {
+		repotest.TestURLRepository(t, func(t *testing.T) domain.URLRepository {
+			cfg := config.Load()
+			return NewDynamoURLRepository(cfg, validators.IdAlphabet{}, dynamotest.NewFake(dynamotest.AppTables(cfg)...))
+		})
+	})
+
+	for name, cfg := range sqlBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repotest.TestURLRepository(t, func(t *testing.T) domain.URLRepository {
+				// REF: postgres is reset by openTestSQL, sqlite gets a new file
+				cfg := cfg
+				if name == clients.BackendSQLite {
+					cfg.Sql.Dsn = filepath.Join(t.TempDir(), "shortener.db")
+				}
+
+				repo, err := NewSQLURLRepository(cfg, validators.IdAlphabet{}, openTestSQL(t, cfg))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return repo
+			})
+		})
+	}
+
+	t.Run("bolt", func(t *testing.T) {
+		repotest.TestURLRepository(t, func(t *testing.T) domain.URLRepository {
+			repo, err := NewBoltURLRepository(openTestBolt(t, boltTestConfig(t)), validators.IdAlphabet{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return repo
+		})
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		repotest.TestURLRepository(t, func(t *testing.T) domain.URLRepository {
+			cache := makeCache(t)
+			t.Cleanup(cache.Close)
+			return NewCached(NewMemory(), cache)
+		})
+	})
+
+	t.Run("resilient", func(t *testing.T) {
+		repotest.TestURLRepository(t, func(t *testing.T) domain.URLRepository {
+			repo, err := NewResilient(resilienceConfig(), NewMemory())
+			assert.NoError(t, err)
+			return repo
+		})
+	})
+
+	t.Run("hedged", func(t *testing.T) {
+		repotest.TestURLRepository(t, func(t *testing.T) domain.URLRepository {
+			repo, err := NewHedged(hedgingConfig(), NewMemory())
+			assert.NoError(t, err)
+			return repo
+		})
+	})
+}
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+// encodeCursor turns a set of key attributes into an opaque token
+func encodeCursor(key map[string]string) string {
+	if len(key) == 0 {
+		return ""
+	}
+
+	raw, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(cursor string) (map[string]string, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.Join(domain.ErrInvalidCursor, err)
+	}
+
+	key := map[string]string{}
+	if err := json.Unmarshal(raw, &key); err != nil {
+		return nil, errors.Join(domain.ErrInvalidCursor, err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,66 @@
+package dtos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+// HitItem is a counter for one url_id and granularity, sorted by bucket start
+type HitItem struct {
+	Key   string `dynamodbav:"hit_key"`
+	Start int64  `dynamodbav:"bucket_start"`
+	Hits  int64  `dynamodbav:"hits"`
+
+	// Bots is missing from counters written before bots were counted
+	Bots int64 `dynamodbav:"bots"`
+}
+
+// HitKey is the partition key, every granularity of an URL is its own partition
+func HitKey(urlID string, g domain.Granularity) string {
+	return fmt.Sprintf("%s#%s", urlID, g)
+}
+
+func (i HitItem) Domain() (domain.HitCount, error) {
+	urlID, g, found := strings.Cut(i.Key, "#")
+	if !found {
+		return domain.HitCount{}, fmt.Errorf("malformed hit key %q", i.Key)
+	}
+
+	return domain.HitCount{
+		URLId:       urlID,
+		Granularity: domain.Granularity(g),
+		Start:       time.Unix(i.Start, 0).UTC(),
+		Hits:        i.Hits,
+		Bots:        i.Bots,
+	}, nil
+}
+
+// VisitorItem is the visitors sketch of one url_id and day. It lives in the
+// hits table, under a partition of its own next to the counter ones
+type VisitorItem struct {
+	Key     string `dynamodbav:"hit_key"`
+	Day     int64  `dynamodbav:"bucket_start"`
+	Sketch  []byte `dynamodbav:"sketch"`
+	Version int64  `dynamodbav:"version"`
+}
+
+// VisitorsKey is the partition key of the daily visitor sketches of an URL
+func VisitorsKey(urlID string) string {
+	return urlID + "#visitors"
+}
+
+func (i VisitorItem) Domain() (domain.VisitorSketch, error) {
+	urlID, found := strings.CutSuffix(i.Key, "#visitors")
+	if !found {
+		return domain.VisitorSketch{}, fmt.Errorf("malformed visitors key %q", i.Key)
+	}
+
+	return domain.VisitorSketch{
+		URLId:  urlID,
+		Day:    time.Unix(i.Day, 0).UTC(),
+		Sketch: i.Sketch,
+	}, nil
+}
@@ -0,0 +1,7 @@
+package dtos
+
+// SequenceItem holds the next value to lease of a named sequence
+type SequenceItem struct {
+	Name string `dynamodbav:"sequence_name"`
+	Next uint64 `dynamodbav:"next_value"`
+}
@@ -0,0 +1,91 @@
+package dtos
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+)
+
+// URLRecord is the JSON form of an URL, in the bolt urls bucket and memory snapshots
+type URLRecord struct {
+	Id        string    `json:"url_id"`
+	FullURL   string    `json:"full_url"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Enabled   bool      `json:"enabled"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Version   int64     `json:"version"`
+}
+
+// RevisionRecord is the JSON form of a revision, in bolt revisions buckets and memory snapshots
+type RevisionRecord struct {
+	Version   int64     `json:"version"`
+	FullURL   string    `json:"full_url"`
+	Enabled   bool      `json:"enabled"`
+	ChangedBy string    `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+func FromDomainRecord(u domain.ShortURL) URLRecord {
+	return URLRecord{
+		Id:        u.ID,
+		FullURL:   u.Upstream.String(),
+		CreatedBy: u.CreatedBy,
+		CreatedAt: u.CreatedAt,
+		Enabled:   u.Enabled,
+		ExpiresAt: u.ExpiresAt,
+		Version:   u.Version,
+	}
+}
+
+func (r URLRecord) Domain(ids validators.IdAlphabet) (*domain.ShortURL, error) {
+	u, err := url.Parse(r.FullURL)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("cannot parse URL"), err)
+	}
+
+	shortUrl := domain.ShortURL{
+		ID:        r.Id,
+		Upstream:  *u,
+		CreatedBy: r.CreatedBy,
+		CreatedAt: r.CreatedAt,
+		Enabled:   r.Enabled,
+		ExpiresAt: r.ExpiresAt,
+		Version:   r.Version,
+	}
+
+	if err := validators.ValidateShortURL(shortUrl, ids); err != nil {
+		return nil, err
+	}
+
+	return &shortUrl, nil
+}
+
+func FromRevisionRecord(r domain.Revision) RevisionRecord {
+	return RevisionRecord{
+		Version:   r.Version,
+		FullURL:   r.Upstream.String(),
+		Enabled:   r.Enabled,
+		ChangedBy: r.ChangedBy,
+		ChangedAt: r.ChangedAt,
+	}
+}
+
+func (r RevisionRecord) Domain() (*domain.Revision, error) {
+	u, err := url.Parse(r.FullURL)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("cannot parse URL"), err)
+	}
+
+	return &domain.Revision{
+		Version:   r.Version,
+		Upstream:  *u,
+		Enabled:   r.Enabled,
+		ChangedBy: r.ChangedBy,
+		ChangedAt: r.ChangedAt,
+	}, nil
+}
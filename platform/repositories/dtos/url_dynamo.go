@@ -18,19 +18,48 @@ type URLItem struct {
 	Author  string `dynamodbav:"created_by"`
 	Enabled bool   `dynamodbav:"enabled"`
 	FullURL string `dynamodbav:"full_url"`
+	Expires string `dynamodbav:"expires_at,omitempty"`
+
+	// TTL is the native DynamoDB expiration attribute, in epoch seconds
+	TTL int64 `dynamodbav:"ttl,omitempty"`
+
+	Version int64 `dynamodbav:"version"`
+}
+
+// RevisionItem is an element of the revisions list attribute of an URLItem
+type RevisionItem struct {
+	Version   int64  `dynamodbav:"version"`
+	FullURL   string `dynamodbav:"full_url"`
+	Enabled   bool   `dynamodbav:"enabled"`
+	ChangedBy string `dynamodbav:"changed_by"`
+	ChangedAt string `dynamodbav:"changed_at"`
+}
+
+// RevisionsItem is the projection of an URLItem holding only its history
+type RevisionsItem struct {
+	Id        string         `dynamodbav:"url_id"`
+	Revisions []RevisionItem `dynamodbav:"revisions"`
 }
 
 func FromDomain(u domain.ShortURL) URLItem {
-	return URLItem{
+	item := URLItem{
 		Id:      u.ID,
 		Created: u.CreatedAt.Format(DynamoTimeFormat),
 		Author:  u.CreatedBy,
 		Enabled: u.Enabled,
 		FullURL: u.Upstream.String(),
+		Version: u.Version,
+	}
+
+	if !u.ExpiresAt.IsZero() {
+		item.Expires = u.ExpiresAt.Format(DynamoTimeFormat)
+		item.TTL = u.ExpiresAt.Unix()
 	}
+
+	return item
 }
 
-func (i URLItem) Domain() (*domain.ShortURL, error) {
+func (i URLItem) Domain(ids validators.IdAlphabet) (*domain.ShortURL, error) {
 	t, err := time.Parse(DynamoTimeFormat, i.Created)
 	if err != nil {
 		return nil, errors.Join(fmt.Errorf("cannot parse time dynamodb"), err)
@@ -41,17 +70,57 @@ func (i URLItem) Domain() (*domain.ShortURL, error) {
 		return nil, errors.Join(fmt.Errorf("cannot parse URL"), err)
 	}
 
+	expires := time.Time{}
+	if i.Expires != "" {
+		expires, err = time.Parse(DynamoTimeFormat, i.Expires)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("cannot parse expiration dynamodb"), err)
+		}
+	}
+
 	shortUrl := domain.ShortURL{
 		ID:        i.Id,
 		CreatedBy: i.Author,
 		Enabled:   i.Enabled,
 		Upstream:  *u,
 		CreatedAt: t,
+		ExpiresAt: expires,
+		Version:   i.Version,
 	}
 
-	if err := validators.ValidateShortURL(shortUrl); err != nil {
+	if err := validators.ValidateShortURL(shortUrl, ids); err != nil {
 		return nil, err
 	}
 
 	return &shortUrl, err
 }
+
+func FromRevision(r domain.Revision) RevisionItem {
+	return RevisionItem{
+		Version:   r.Version,
+		FullURL:   r.Upstream.String(),
+		Enabled:   r.Enabled,
+		ChangedBy: r.ChangedBy,
+		ChangedAt: r.ChangedAt.Format(DynamoTimeFormat),
+	}
+}
+
+func (i RevisionItem) Domain() (*domain.Revision, error) {
+	t, err := time.Parse(DynamoTimeFormat, i.ChangedAt)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("cannot parse time dynamodb"), err)
+	}
+
+	u, err := url.Parse(i.FullURL)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("cannot parse URL"), err)
+	}
+
+	return &domain.Revision{
+		Version:   i.Version,
+		Upstream:  *u,
+		Enabled:   i.Enabled,
+		ChangedBy: i.ChangedBy,
+		ChangedAt: t,
+	}, nil
+}
@@ -0,0 +1,164 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/sketch"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltHitsRepo keys counters by url_id, granularity and big endian bucket
+// start, so the buckets of a series are contiguous and ordered by time
+type boltHitsRepo struct {
+	db *bolt.DB
+}
+
+// encodeBoltHits stores hits and then bots as big endian counters
+func encodeBoltHits(hits int64, bots int64) []byte {
+	return binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint64(nil, uint64(hits)), uint64(bots))
+}
+
+// decodeBoltHits also reads values written before bots were counted, which
+// only hold hits
+func decodeBoltHits(v []byte) (hits int64, bots int64) {
+	hits = int64(binary.BigEndian.Uint64(v))
+	if len(v) >= 16 {
+		bots = int64(binary.BigEndian.Uint64(v[8:]))
+	}
+
+	return hits, bots
+}
+
+func boltHitsPrefix(urlID string, g domain.Granularity) []byte {
+	return []byte(urlID + "\x00" + string(g) + "\x00")
+}
+
+func (d *boltHitsRepo) AddHits(_ context.Context, counts []domain.HitCount) error {
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		hits := tx.Bucket(boltHitsBucket)
+		for _, c := range counts {
+			key := binary.BigEndian.AppendUint64(boltHitsPrefix(c.URLId, c.Granularity), uint64(c.Granularity.Bucket(c.Start).Unix()))
+
+			total, bots := c.Hits, c.Bots
+			if current := hits.Get(key); current != nil {
+				storedHits, storedBots := decodeBoltHits(current)
+				total, bots = total+storedHits, bots+storedBots
+			}
+
+			if err := hits.Put(key, encodeBoltHits(total, bots)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return nil
+}
+
+func (d *boltHitsRepo) Series(_ context.Context, urlID string, g domain.Granularity, from time.Time, to time.Time) ([]domain.HitCount, error) {
+	prefix := boltHitsPrefix(urlID, g)
+	result := []domain.HitCount{}
+
+	err := d.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltHitsBucket).Cursor()
+		for k, v := c.Seek(binary.BigEndian.AppendUint64(prefix, uint64(from.Unix()))); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			start := int64(binary.BigEndian.Uint64(k[len(prefix):]))
+			if start > to.Unix() {
+				break
+			}
+
+			hits, bots := decodeBoltHits(v)
+			result = append(result, domain.HitCount{
+				URLId:       urlID,
+				Granularity: g,
+				Start:       time.Unix(start, 0).UTC(),
+				Hits:        hits,
+				Bots:        bots,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return result, nil
+}
+
+func boltVisitorsPrefix(urlID string) []byte {
+	return []byte(urlID + "\x00")
+}
+
+// MergeVisitors reads and rewrites sketches within one transaction, bolt
+// serializes them so no merge is lost
+func (d *boltHitsRepo) MergeVisitors(_ context.Context, sketches []domain.VisitorSketch) error {
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		visitors := tx.Bucket(boltVisitorsBucket)
+		for _, s := range sketches {
+			key := binary.BigEndian.AppendUint64(boltVisitorsPrefix(s.URLId), uint64(domain.GranularityDay.Bucket(s.Day).Unix()))
+
+			merged, err := sketch.MergeHyperLogLogs(visitors.Get(key), s.Sketch)
+			if err != nil {
+				return errors.Join(domain.ErrRepoSchema, err)
+			}
+
+			if err := visitors.Put(key, merged); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, domain.ErrRepoSchema) {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return err
+}
+
+func (d *boltHitsRepo) Visitors(_ context.Context, urlID string, from time.Time, to time.Time) ([]domain.VisitorSketch, error) {
+	prefix := boltVisitorsPrefix(urlID)
+	result := []domain.VisitorSketch{}
+
+	err := d.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltVisitorsBucket).Cursor()
+		for k, v := c.Seek(binary.BigEndian.AppendUint64(prefix, uint64(from.Unix()))); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			day := int64(binary.BigEndian.Uint64(k[len(prefix):]))
+			if day > to.Unix() {
+				break
+			}
+
+			// REF: bolt values are only valid within the transaction
+			result = append(result, domain.VisitorSketch{
+				URLId:  urlID,
+				Day:    time.Unix(day, 0).UTC(),
+				Sketch: bytes.Clone(v),
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return result, nil
+}
+
+func NewBoltHitsRepository(db *bolt.DB) (domain.HitsRepository, error) {
+	if err := ensureBoltBuckets(db, boltHitsBucket, boltVisitorsBucket); err != nil {
+		return nil, err
+	}
+
+	return &boltHitsRepo{db: db}, nil
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltHitsAccumulate(t *testing.T) {
+	repo, err := NewBoltHitsRepository(openTestBolt(t, boltTestConfig(t)))
+	assert.NoError(t, err)
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 10, 0, 30, 0, time.UTC)
+
+	// REF: counters of the same bucket add up across calls
+	assert.NoError(t, repo.AddHits(ctx, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityMinute, Start: start, Hits: 2},
+		{URLId: validId, Granularity: domain.GranularityMinute, Start: start.Add(time.Minute), Hits: 1},
+		{URLId: validId + "2", Granularity: domain.GranularityMinute, Start: start, Hits: 7},
+	}))
+	assert.NoError(t, repo.AddHits(ctx, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityMinute, Start: start, Hits: 3},
+	}))
+
+	series, err := repo.Series(ctx, validId, domain.GranularityMinute, start.Add(-time.Hour), start.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityMinute, Start: start.Truncate(time.Minute), Hits: 5},
+		{URLId: validId, Granularity: domain.GranularityMinute, Start: start.Truncate(time.Minute).Add(time.Minute), Hits: 1},
+	}, series)
+
+	// REF: bounds are inclusive and other granularities are apart
+	series, err = repo.Series(ctx, validId, domain.GranularityMinute, start.Truncate(time.Minute), start.Truncate(time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, series, 1)
+
+	series, err = repo.Series(ctx, validId, domain.GranularityHour, start.Add(-time.Hour), start.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, series)
+}
+
+func TestBoltVisitorsMerge(t *testing.T) {
+	repo, err := NewBoltHitsRepository(openTestBolt(t, boltTestConfig(t)))
+	assert.NoError(t, err)
+	assertVisitorsMerge(t, repo)
+}
+
+func TestBoltBotsAccumulate(t *testing.T) {
+	db := openTestBolt(t, boltTestConfig(t))
+	repo, err := NewBoltHitsRepository(db)
+	assert.NoError(t, err)
+	assertBotsAccumulate(t, repo)
+
+	// REF: counters written before bots were counted only hold hits
+	at := time.Date(2025, 2, 6, 10, 0, 0, 0, time.UTC)
+	key := binary.BigEndian.AppendUint64(boltHitsPrefix(validId, domain.GranularityHour), uint64(at.Unix()))
+	assert.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltHitsBucket).Put(key, binary.BigEndian.AppendUint64(nil, 4))
+	}))
+	assert.NoError(t, repo.AddHits(context.Background(), []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at, Hits: 1, Bots: 1},
+	}))
+
+	series, err := repo.Series(context.Background(), validId, domain.GranularityHour, at, at)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at, Hits: 5, Bots: 1},
+	}, series)
+}
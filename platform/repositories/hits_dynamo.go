@@ -0,0 +1,261 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	awsDynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories/dtos"
+	"github.com/neonmei/challenge_urlshortener/platform/sketch"
+)
+
+// visitorMergeAttempts bounds how many times a sketch merge is retried when
+// another replica rewrote the same sketch in between
+const visitorMergeAttempts = 5
+
+// hitWriters bounds how many counters or sketches are written at once
+const hitWriters = 8
+
+type dynaHitsRepo struct {
+	tableName    string
+	client       clients.DynamoDbClient
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+// AddHits uses atomic ADD updates, so concurrent replicas never lose counts.
+// Every update gets its own write timeout, so a slow one does not starve the
+// rest, and failures name the counter they belong to
+func (d *dynaHitsRepo) AddHits(ctx context.Context, counts []domain.HitCount) error {
+	err := d.forEach(ctx, len(counts), func(ctx context.Context, i int) error {
+		c := counts[i]
+		_, err := d.client.UpdateItem(ctx, &awsDynamodb.UpdateItemInput{
+			TableName: aws.String(d.tableName),
+			Key: map[string]types.AttributeValue{
+				"hit_key":      &types.AttributeValueMemberS{Value: dtos.HitKey(c.URLId, c.Granularity)},
+				"bucket_start": &types.AttributeValueMemberN{Value: strconv.FormatInt(c.Granularity.Bucket(c.Start).Unix(), 10)},
+			},
+			UpdateExpression: aws.String("ADD hits :hits, bots :bots"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":hits": &types.AttributeValueMemberN{Value: strconv.FormatInt(c.Hits, 10)},
+				":bots": &types.AttributeValueMemberN{Value: strconv.FormatInt(c.Bots, 10)},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("%s at %s: %w", dtos.HitKey(c.URLId, c.Granularity), c.Granularity.Bucket(c.Start).Format(time.RFC3339), err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return nil
+}
+
+// forEach runs fn for every index with up to hitWriters calls in flight, each
+// one bounded by the write timeout, and joins their errors
+func (d *dynaHitsRepo) forEach(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	errs := make([]error, n)
+	work := make(chan int)
+	wg := sync.WaitGroup{}
+	for range min(hitWriters, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				newCtx, cancelFunc := context.WithTimeout(ctx, d.writeTimeout)
+				errs[i] = fn(newCtx, i)
+				cancelFunc()
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (d *dynaHitsRepo) Series(ctx context.Context, urlID string, g domain.Granularity, from time.Time, to time.Time) ([]domain.HitCount, error) {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.readTimeout)
+	defer cancelFunc()
+
+	result := []domain.HitCount{}
+	var startKey map[string]types.AttributeValue
+	for {
+		output, err := d.client.Query(newCtx, &awsDynamodb.QueryInput{
+			TableName:              aws.String(d.tableName),
+			KeyConditionExpression: aws.String("hit_key = :key AND bucket_start BETWEEN :from AND :to"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":key":  &types.AttributeValueMemberS{Value: dtos.HitKey(urlID, g)},
+				":from": &types.AttributeValueMemberN{Value: strconv.FormatInt(from.Unix(), 10)},
+				":to":   &types.AttributeValueMemberN{Value: strconv.FormatInt(to.Unix(), 10)},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, errors.Join(domain.ErrUnavailableRepo, err)
+		}
+
+		items := []dtos.HitItem{}
+		if err := attributevalue.UnmarshalListOfMaps(output.Items, &items); err != nil {
+			return nil, errors.Join(domain.ErrRepoSchema, err)
+		}
+
+		for _, item := range items {
+			hitCount, err := item.Domain()
+			if err != nil {
+				return nil, errors.Join(domain.ErrRepoSchema, err)
+			}
+			result = append(result, hitCount)
+		}
+
+		if len(output.LastEvaluatedKey) == 0 {
+			return result, nil
+		}
+		startKey = output.LastEvaluatedKey
+	}
+}
+
+// MergeVisitors reads, merges and conditionally writes back every sketch,
+// starting over when the stored version moved meanwhile
+func (d *dynaHitsRepo) MergeVisitors(ctx context.Context, sketches []domain.VisitorSketch) error {
+	return d.forEach(ctx, len(sketches), func(ctx context.Context, i int) error {
+		s := sketches[i]
+		if err := d.mergeVisitors(ctx, s); err != nil {
+			return fmt.Errorf("%s at %s: %w", dtos.VisitorsKey(s.URLId), domain.GranularityDay.Bucket(s.Day).Format(time.RFC3339), err)
+		}
+
+		return nil
+	})
+}
+
+func (d *dynaHitsRepo) mergeVisitors(ctx context.Context, s domain.VisitorSketch) error {
+	key := map[string]types.AttributeValue{
+		"hit_key":      &types.AttributeValueMemberS{Value: dtos.VisitorsKey(s.URLId)},
+		"bucket_start": &types.AttributeValueMemberN{Value: strconv.FormatInt(domain.GranularityDay.Bucket(s.Day).Unix(), 10)},
+	}
+
+	for attempt := 0; attempt < visitorMergeAttempts; attempt++ {
+		output, err := d.client.GetItem(ctx, &awsDynamodb.GetItemInput{
+			TableName:      aws.String(d.tableName),
+			Key:            key,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return errors.Join(domain.ErrUnavailableRepo, err)
+		}
+
+		item := dtos.VisitorItem{
+			Key: dtos.VisitorsKey(s.URLId),
+			Day: domain.GranularityDay.Bucket(s.Day).Unix(),
+		}
+		if err := attributevalue.UnmarshalMap(output.Item, &item); err != nil {
+			return errors.Join(domain.ErrRepoSchema, err)
+		}
+
+		condition := aws.String("attribute_not_exists(hit_key)")
+		values := map[string]types.AttributeValue(nil)
+		if output.Item != nil {
+			condition = aws.String("version = :version")
+			values = map[string]types.AttributeValue{
+				":version": &types.AttributeValueMemberN{Value: strconv.FormatInt(item.Version, 10)},
+			}
+		}
+
+		if item.Sketch, err = sketch.MergeHyperLogLogs(item.Sketch, s.Sketch); err != nil {
+			return errors.Join(domain.ErrRepoSchema, err)
+		}
+		item.Version++
+
+		marshalled, err := attributevalue.MarshalMap(item)
+		if err != nil {
+			return errors.Join(domain.ErrRepoSchema, err)
+		}
+
+		_, err = d.client.PutItem(ctx, &awsDynamodb.PutItemInput{
+			TableName:                 aws.String(d.tableName),
+			Item:                      marshalled,
+			ConditionExpression:       condition,
+			ExpressionAttributeValues: values,
+		})
+
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			continue
+		}
+
+		if err != nil {
+			return errors.Join(domain.ErrUnavailableRepo, err)
+		}
+
+		return nil
+	}
+
+	return errors.Join(domain.ErrUnavailableRepo, domain.ErrVersionConflict)
+}
+
+func (d *dynaHitsRepo) Visitors(ctx context.Context, urlID string, from time.Time, to time.Time) ([]domain.VisitorSketch, error) {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.readTimeout)
+	defer cancelFunc()
+
+	result := []domain.VisitorSketch{}
+	var startKey map[string]types.AttributeValue
+	for {
+		output, err := d.client.Query(newCtx, &awsDynamodb.QueryInput{
+			TableName:              aws.String(d.tableName),
+			KeyConditionExpression: aws.String("hit_key = :key AND bucket_start BETWEEN :from AND :to"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":key":  &types.AttributeValueMemberS{Value: dtos.VisitorsKey(urlID)},
+				":from": &types.AttributeValueMemberN{Value: strconv.FormatInt(from.Unix(), 10)},
+				":to":   &types.AttributeValueMemberN{Value: strconv.FormatInt(to.Unix(), 10)},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, errors.Join(domain.ErrUnavailableRepo, err)
+		}
+
+		items := []dtos.VisitorItem{}
+		if err := attributevalue.UnmarshalListOfMaps(output.Items, &items); err != nil {
+			return nil, errors.Join(domain.ErrRepoSchema, err)
+		}
+
+		for _, item := range items {
+			visitors, err := item.Domain()
+			if err != nil {
+				return nil, errors.Join(domain.ErrRepoSchema, err)
+			}
+			result = append(result, visitors)
+		}
+
+		if len(output.LastEvaluatedKey) == 0 {
+			return result, nil
+		}
+		startKey = output.LastEvaluatedKey
+	}
+}
+
+func NewDynamoHitsRepository(cfg config.AppConfig, client clients.DynamoDbClient) domain.HitsRepository {
+	return &dynaHitsRepo{
+		tableName:    cfg.Dynamo.HitsTableName,
+		client:       client,
+		readTimeout:  cfg.Dynamo.ReadTimeout,
+		writeTimeout: cfg.Dynamo.WriteTimeout,
+	}
+}
@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	awsDynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/neonmei/challenge_urlshortener/domain"
+	clientMock "github.com/neonmei/challenge_urlshortener/mocks/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/clients/dynamotest"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories/dtos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHitsBackendAddUsesAtomicIncrement(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoHitsRepository(cfg, dynamoClient)
+	at := time.Date(2025, 2, 5, 10, 30, 0, 0, time.UTC)
+
+	dynamoClient.On("UpdateItem", mock.Anything, mock.MatchedBy(func(in *awsDynamodb.UpdateItemInput) bool {
+		key := in.Key["hit_key"].(*types.AttributeValueMemberS).Value
+		start := in.Key["bucket_start"].(*types.AttributeValueMemberN).Value
+		hits := in.ExpressionAttributeValues[":hits"].(*types.AttributeValueMemberN).Value
+		bots := in.ExpressionAttributeValues[":bots"].(*types.AttributeValueMemberN).Value
+		return *in.UpdateExpression == "ADD hits :hits, bots :bots" && key == "asd#hour" && start == "1738749600" && hits == "4" && bots == "1"
+	})).Return(&awsDynamodb.UpdateItemOutput{}, nil).Once()
+
+	assert.NoError(t, repo.AddHits(ctx, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at, Hits: 4, Bots: 1},
+	}))
+}
+
+func TestHitsBackendSeries(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoHitsRepository(cfg, dynamoClient)
+	at := time.Date(2025, 2, 5, 10, 0, 0, 0, time.UTC)
+
+	firstPage, err := attributevalue.MarshalMap(dtos.HitItem{Key: "asd#hour", Start: at.Unix(), Hits: 5})
+	assert.NoError(t, err)
+	secondPage, err := attributevalue.MarshalMap(dtos.HitItem{Key: "asd#hour", Start: at.Add(time.Hour).Unix(), Hits: 1})
+	assert.NoError(t, err)
+
+	// REF: pagination is followed until LastEvaluatedKey is empty
+	dynamoClient.On("Query", mock.Anything, mock.MatchedBy(func(in *awsDynamodb.QueryInput) bool {
+		return in.ExclusiveStartKey == nil
+	})).Return(&awsDynamodb.QueryOutput{
+		Items:            []map[string]types.AttributeValue{firstPage},
+		LastEvaluatedKey: firstPage,
+	}, nil).Once()
+	dynamoClient.On("Query", mock.Anything, mock.MatchedBy(func(in *awsDynamodb.QueryInput) bool {
+		return in.ExclusiveStartKey != nil
+	})).Return(&awsDynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{secondPage},
+	}, nil).Once()
+
+	series, err := repo.Series(ctx, validId, domain.GranularityHour, at, at.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at, Hits: 5},
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at.Add(time.Hour), Hits: 1},
+	}, series)
+}
+
+func TestHitsBackendErrorHandling(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoHitsRepository(cfg, dynamoClient)
+
+	dynamoErr := errors.New("dynamo backend failed")
+	dynamoClient.On("UpdateItem", mock.Anything, mock.Anything).Return(nil, dynamoErr)
+	dynamoClient.On("Query", mock.Anything, mock.Anything).Return(nil, dynamoErr)
+
+	err := repo.AddHits(ctx, []domain.HitCount{{URLId: validId, Granularity: domain.GranularityDay, Start: time.Now(), Hits: 1}})
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+
+	_, err = repo.Series(ctx, validId, domain.GranularityDay, time.Now(), time.Now())
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+}
+
+func TestHitsBackendReportsFailedCounters(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoHitsRepository(cfg, dynamoClient)
+	at := time.Date(2025, 2, 5, 10, 30, 0, 0, time.UTC)
+
+	dynamoErr := errors.New("dynamo backend failed")
+	dynamoClient.On("UpdateItem", mock.Anything, mock.MatchedBy(func(in *awsDynamodb.UpdateItemInput) bool {
+		return in.Key["hit_key"].(*types.AttributeValueMemberS).Value == "asd#hour"
+	})).Return(nil, dynamoErr).Once()
+	dynamoClient.On("UpdateItem", mock.Anything, mock.MatchedBy(func(in *awsDynamodb.UpdateItemInput) bool {
+		return in.Key["hit_key"].(*types.AttributeValueMemberS).Value == "asd#day"
+	})).Return(&awsDynamodb.UpdateItemOutput{}, nil).Once()
+
+	// REF: the rest are still written, the error names the one that failed
+	err := repo.AddHits(ctx, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at, Hits: 1},
+		{URLId: validId, Granularity: domain.GranularityDay, Start: at, Hits: 1},
+	})
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+	assert.ErrorIs(t, err, dynamoErr)
+	assert.ErrorContains(t, err, "asd#hour at 2025-02-05T10:00:00Z")
+	assert.NotContains(t, err.Error(), "asd#day")
+}
+
+func TestHitsBackendEndToEnd(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	repo := NewDynamoHitsRepository(cfg, dynamotest.NewFake(dynamotest.AppTables(cfg)...))
+	at := time.Date(2025, 2, 5, 10, 30, 0, 0, time.UTC)
+
+	// REF: counts of the same bucket add up
+	for range 2 {
+		assert.NoError(t, repo.AddHits(ctx, []domain.HitCount{
+			{URLId: validId, Granularity: domain.GranularityHour, Start: at, Hits: 2},
+			{URLId: validId, Granularity: domain.GranularityHour, Start: at.Add(2 * time.Hour), Hits: 1},
+			{URLId: "other", Granularity: domain.GranularityHour, Start: at, Hits: 7},
+		}))
+	}
+
+	series, err := repo.Series(ctx, validId, domain.GranularityHour, at.Add(-time.Hour), at.Add(time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, series, 1) {
+		assert.Equal(t, int64(4), series[0].Hits)
+		assert.True(t, domain.GranularityHour.Bucket(at).Equal(series[0].Start))
+	}
+
+	series, err = repo.Series(ctx, validId, domain.GranularityHour, domain.GranularityHour.Bucket(at), at.Add(3*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, series, 2)
+}
+
+func TestHitsBackendVisitorsMerge(t *testing.T) {
+	cfg := config.Load()
+	assertVisitorsMerge(t, NewDynamoHitsRepository(cfg, dynamotest.NewFake(dynamotest.AppTables(cfg)...)))
+}
+
+func TestHitsBackendVisitorsMergeConflict(t *testing.T) {
+	ctx := context.Background()
+	mockDynamo := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoHitsRepository(config.Load(), mockDynamo)
+
+	// REF: another replica keeps winning the race for the same sketch
+	mockDynamo.EXPECT().GetItem(mock.Anything, mock.Anything).Return(&awsDynamodb.GetItemOutput{}, nil).Times(visitorMergeAttempts)
+	mockDynamo.EXPECT().PutItem(mock.Anything, mock.Anything).
+		Return(nil, &types.ConditionalCheckFailedException{}).Times(visitorMergeAttempts)
+
+	err := repo.MergeVisitors(ctx, []domain.VisitorSketch{
+		{URLId: validId, Day: time.Now(), Sketch: encodedVisitors(t, "alice")},
+	})
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+	assert.ErrorIs(t, err, domain.ErrVersionConflict)
+}
+
+func TestHitsBackendBotsAccumulate(t *testing.T) {
+	cfg := config.Load()
+	assertBotsAccumulate(t, NewDynamoHitsRepository(cfg, dynamotest.NewFake(dynamotest.AppTables(cfg)...)))
+}
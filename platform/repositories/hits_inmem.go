@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/sketch"
+)
+
+type hitKey struct {
+	urlID       string
+	granularity domain.Granularity
+	start       int64
+}
+
+type hitTotals struct {
+	hits int64
+	bots int64
+}
+
+type visitorKey struct {
+	urlID string
+	day   int64
+}
+
+type memoryHitsRepo struct {
+	mu       sync.RWMutex
+	data     map[hitKey]hitTotals
+	visitors map[visitorKey][]byte
+}
+
+func (d *memoryHitsRepo) AddHits(_ context.Context, counts []domain.HitCount) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, c := range counts {
+		key := hitKey{c.URLId, c.Granularity, c.Granularity.Bucket(c.Start).Unix()}
+		totals := d.data[key]
+		totals.hits += c.Hits
+		totals.bots += c.Bots
+		d.data[key] = totals
+	}
+
+	return nil
+}
+
+func (d *memoryHitsRepo) Series(_ context.Context, urlID string, g domain.Granularity, from time.Time, to time.Time) ([]domain.HitCount, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := []domain.HitCount{}
+	for key, totals := range d.data {
+		if key.urlID != urlID || key.granularity != g {
+			continue
+		}
+
+		if key.start < from.Unix() || key.start > to.Unix() {
+			continue
+		}
+
+		result = append(result, domain.HitCount{
+			URLId:       urlID,
+			Granularity: g,
+			Start:       time.Unix(key.start, 0).UTC(),
+			Hits:        totals.hits,
+			Bots:        totals.bots,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Start.Before(result[j].Start) })
+	return result, nil
+}
+
+func (d *memoryHitsRepo) MergeVisitors(_ context.Context, sketches []domain.VisitorSketch) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, s := range sketches {
+		key := visitorKey{s.URLId, domain.GranularityDay.Bucket(s.Day).Unix()}
+		merged, err := sketch.MergeHyperLogLogs(d.visitors[key], s.Sketch)
+		if err != nil {
+			return errors.Join(domain.ErrRepoSchema, err)
+		}
+		d.visitors[key] = merged
+	}
+
+	return nil
+}
+
+func (d *memoryHitsRepo) Visitors(_ context.Context, urlID string, from time.Time, to time.Time) ([]domain.VisitorSketch, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := []domain.VisitorSketch{}
+	for key, data := range d.visitors {
+		if key.urlID != urlID || key.day < from.Unix() || key.day > to.Unix() {
+			continue
+		}
+
+		result = append(result, domain.VisitorSketch{
+			URLId:  urlID,
+			Day:    time.Unix(key.day, 0).UTC(),
+			Sketch: data,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Day.Before(result[j].Day) })
+	return result, nil
+}
+
+// NewMemoryHits is an in-memory hits repository for development and tests
+func NewMemoryHits() domain.HitsRepository {
+	return &memoryHitsRepo{data: map[hitKey]hitTotals{}, visitors: map[visitorKey][]byte{}}
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/sketch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInmemHitsBasic(t *testing.T) {
+	repo := NewMemoryHits()
+	ctx := context.Background()
+	at := time.Date(2025, 2, 5, 10, 30, 0, 0, time.UTC)
+
+	assert.NoError(t, repo.AddHits(ctx, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at, Hits: 2},
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at.Add(time.Hour), Hits: 1},
+		{URLId: validId, Granularity: domain.GranularityDay, Start: at, Hits: 3},
+		{URLId: "other", Granularity: domain.GranularityHour, Start: at, Hits: 7},
+	}))
+
+	// REF: increments add up
+	assert.NoError(t, repo.AddHits(ctx, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at, Hits: 3},
+	}))
+
+	series, err := repo.Series(ctx, validId, domain.GranularityHour, at.Add(-time.Hour), at.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at.Truncate(time.Hour), Hits: 5},
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at.Truncate(time.Hour).Add(time.Hour), Hits: 1},
+	}, series)
+
+	series, err = repo.Series(ctx, validId, domain.GranularityMinute, at.Add(-time.Hour), at.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, series)
+}
+
+// assertBotsAccumulate checks a backend keeps bots apart from, and
+// included in, the hits of every bucket
+func assertBotsAccumulate(t *testing.T, repo domain.HitsRepository) {
+	t.Helper()
+	ctx := context.Background()
+	at := time.Date(2025, 2, 5, 10, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, repo.AddHits(ctx, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at, Hits: 3, Bots: 2},
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at.Add(time.Hour), Hits: 1},
+	}))
+	assert.NoError(t, repo.AddHits(ctx, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at, Hits: 2, Bots: 1},
+	}))
+
+	series, err := repo.Series(ctx, validId, domain.GranularityHour, at, at.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.HitCount{
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at, Hits: 5, Bots: 3},
+		{URLId: validId, Granularity: domain.GranularityHour, Start: at.Add(time.Hour), Hits: 1},
+	}, series)
+}
+
+func TestInmemBotsAccumulate(t *testing.T) {
+	assertBotsAccumulate(t, NewMemoryHits())
+}
+
+func encodedVisitors(t *testing.T, visitors ...string) []byte {
+	h, err := sketch.NewHyperLogLog(12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range visitors {
+		h.AddString(v)
+	}
+
+	data, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// assertVisitorsMerge checks a backend merges sketches of the same link and
+// day instead of overwriting them
+func assertVisitorsMerge(t *testing.T, repo domain.HitsRepository) {
+	t.Helper()
+	ctx := context.Background()
+	day := time.Date(2025, 2, 5, 0, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, repo.MergeVisitors(ctx, []domain.VisitorSketch{
+		{URLId: validId, Day: day, Sketch: encodedVisitors(t, "alice", "bob")},
+		{URLId: validId, Day: day.Add(24 * time.Hour), Sketch: encodedVisitors(t, "carol")},
+		{URLId: "other", Day: day, Sketch: encodedVisitors(t, "dave")},
+	}))
+
+	// REF: a second replica flushing the same day
+	assert.NoError(t, repo.MergeVisitors(ctx, []domain.VisitorSketch{
+		{URLId: validId, Day: day.Add(10 * time.Hour), Sketch: encodedVisitors(t, "bob", "erin")},
+	}))
+
+	sketches, err := repo.Visitors(ctx, validId, day, day.Add(24*time.Hour))
+	assert.NoError(t, err)
+	if !assert.Len(t, sketches, 2) {
+		return
+	}
+
+	estimates := map[int64]uint64{}
+	for _, s := range sketches {
+		assert.Equal(t, validId, s.URLId)
+		decoded, err := sketch.UnmarshalHyperLogLog(s.Sketch)
+		assert.NoError(t, err)
+		estimates[s.Day.Unix()] = decoded.Estimate()
+	}
+	assert.Equal(t, map[int64]uint64{day.Unix(): 3, day.Add(24 * time.Hour).Unix(): 1}, estimates)
+
+	sketches, err = repo.Visitors(ctx, validId, day.Add(-48*time.Hour), day.Add(-24*time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, sketches)
+}
+
+func TestInmemVisitorsMerge(t *testing.T) {
+	assertVisitorsMerge(t, NewMemoryHits())
+}
@@ -0,0 +1,191 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/sketch"
+)
+
+type sqlHitsRepo struct {
+	db           *sql.DB
+	dialect      sqlDialect
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+// AddHits upserts every counter in a single transaction
+func (d *sqlHitsRepo) AddHits(ctx context.Context, counts []domain.HitCount) error {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.writeTimeout)
+	defer cancelFunc()
+
+	tx, err := d.db.BeginTx(newCtx, nil)
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(newCtx, d.dialect.rebind(
+		"INSERT INTO url_hits (url_id, granularity, bucket_start, hits, bots) VALUES (?, ?, ?, ?, ?) "+
+			"ON CONFLICT (url_id, granularity, bucket_start) DO UPDATE SET hits = url_hits.hits + excluded.hits, bots = url_hits.bots + excluded.bots"))
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+	defer stmt.Close()
+
+	for _, c := range counts {
+		if _, err := stmt.ExecContext(newCtx, c.URLId, string(c.Granularity), c.Granularity.Bucket(c.Start).Unix(), c.Hits, c.Bots); err != nil {
+			return errors.Join(domain.ErrUnavailableRepo, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return nil
+}
+
+func (d *sqlHitsRepo) Series(ctx context.Context, urlID string, g domain.Granularity, from time.Time, to time.Time) ([]domain.HitCount, error) {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.readTimeout)
+	defer cancelFunc()
+
+	rows, err := d.db.QueryContext(newCtx, d.dialect.rebind(
+		"SELECT bucket_start, hits, bots FROM url_hits WHERE url_id = ? AND granularity = ? AND bucket_start BETWEEN ? AND ? ORDER BY bucket_start"),
+		urlID, string(g), from.Unix(), to.Unix(),
+	)
+	if err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+	defer rows.Close()
+
+	result := []domain.HitCount{}
+	for rows.Next() {
+		var start, hits, bots int64
+		if err := rows.Scan(&start, &hits, &bots); err != nil {
+			return nil, errors.Join(domain.ErrRepoSchema, err)
+		}
+
+		result = append(result, domain.HitCount{
+			URLId:       urlID,
+			Granularity: g,
+			Start:       time.Unix(start, 0).UTC(),
+			Hits:        hits,
+			Bots:        bots,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return result, nil
+}
+
+// MergeVisitors inserts new sketches as they are and merges the others with
+// the stored ones, locked until the transaction commits
+func (d *sqlHitsRepo) MergeVisitors(ctx context.Context, sketches []domain.VisitorSketch) error {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.writeTimeout)
+	defer cancelFunc()
+
+	tx, err := d.db.BeginTx(newCtx, nil)
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+	defer tx.Rollback()
+
+	for _, s := range sketches {
+		day := domain.GranularityDay.Bucket(s.Day).Unix()
+		inserted, err := tx.ExecContext(newCtx, d.dialect.rebind(
+			"INSERT INTO url_visitors (url_id, day, sketch) VALUES (?, ?, ?) ON CONFLICT (url_id, day) DO NOTHING"),
+			s.URLId, day, s.Sketch)
+		if err != nil {
+			return errors.Join(domain.ErrUnavailableRepo, err)
+		}
+
+		rows, err := inserted.RowsAffected()
+		if err != nil {
+			return errors.Join(domain.ErrUnavailableRepo, err)
+		}
+
+		if rows == 1 {
+			continue
+		}
+
+		var stored []byte
+		err = tx.QueryRowContext(newCtx, d.dialect.rebind(
+			"SELECT sketch FROM url_visitors WHERE url_id = ? AND day = ?"+d.dialect.forUpdate()), s.URLId, day).Scan(&stored)
+		if err != nil {
+			return errors.Join(domain.ErrUnavailableRepo, err)
+		}
+
+		merged, err := sketch.MergeHyperLogLogs(stored, s.Sketch)
+		if err != nil {
+			return errors.Join(domain.ErrRepoSchema, err)
+		}
+
+		if _, err := tx.ExecContext(newCtx, d.dialect.rebind(
+			"UPDATE url_visitors SET sketch = ? WHERE url_id = ? AND day = ?"), merged, s.URLId, day); err != nil {
+			return errors.Join(domain.ErrUnavailableRepo, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return nil
+}
+
+func (d *sqlHitsRepo) Visitors(ctx context.Context, urlID string, from time.Time, to time.Time) ([]domain.VisitorSketch, error) {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.readTimeout)
+	defer cancelFunc()
+
+	rows, err := d.db.QueryContext(newCtx, d.dialect.rebind(
+		"SELECT day, sketch FROM url_visitors WHERE url_id = ? AND day BETWEEN ? AND ? ORDER BY day"),
+		urlID, from.Unix(), to.Unix(),
+	)
+	if err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+	defer rows.Close()
+
+	result := []domain.VisitorSketch{}
+	for rows.Next() {
+		var day int64
+		var data []byte
+		if err := rows.Scan(&day, &data); err != nil {
+			return nil, errors.Join(domain.ErrRepoSchema, err)
+		}
+
+		result = append(result, domain.VisitorSketch{
+			URLId:  urlID,
+			Day:    time.Unix(day, 0).UTC(),
+			Sketch: data,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return result, nil
+}
+
+func NewSQLHitsRepository(cfg config.AppConfig, db *sql.DB) (domain.HitsRepository, error) {
+	dialect, err := newSQLDialect(cfg.Storage.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlHitsRepo{
+		db:           db,
+		dialect:      dialect,
+		readTimeout:  cfg.Sql.ReadTimeout,
+		writeTimeout: cfg.Sql.WriteTimeout,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLHitsAccumulate(t *testing.T) {
+	for name, cfg := range sqlBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := NewSQLHitsRepository(cfg, openTestSQL(t, cfg))
+			assert.NoError(t, err)
+			ctx := context.Background()
+			start := time.Date(2025, 1, 1, 10, 0, 30, 0, time.UTC)
+
+			// REF: counters of the same bucket add up across calls
+			assert.NoError(t, repo.AddHits(ctx, []domain.HitCount{
+				{URLId: validId, Granularity: domain.GranularityMinute, Start: start, Hits: 2},
+				{URLId: validId, Granularity: domain.GranularityMinute, Start: start.Add(time.Minute), Hits: 1},
+				{URLId: "other", Granularity: domain.GranularityMinute, Start: start, Hits: 7},
+			}))
+			assert.NoError(t, repo.AddHits(ctx, []domain.HitCount{
+				{URLId: validId, Granularity: domain.GranularityMinute, Start: start, Hits: 3},
+			}))
+
+			series, err := repo.Series(ctx, validId, domain.GranularityMinute, start.Add(-time.Hour), start.Add(time.Hour))
+			assert.NoError(t, err)
+			assert.Equal(t, []domain.HitCount{
+				{URLId: validId, Granularity: domain.GranularityMinute, Start: start.Truncate(time.Minute), Hits: 5},
+				{URLId: validId, Granularity: domain.GranularityMinute, Start: start.Truncate(time.Minute).Add(time.Minute), Hits: 1},
+			}, series)
+
+			// REF: bounds are inclusive and other granularities are apart
+			series, err = repo.Series(ctx, validId, domain.GranularityMinute, start.Add(time.Minute).Truncate(time.Minute), start.Add(time.Hour))
+			assert.NoError(t, err)
+			assert.Len(t, series, 1)
+
+			series, err = repo.Series(ctx, validId, domain.GranularityHour, start.Add(-time.Hour), start.Add(time.Hour))
+			assert.NoError(t, err)
+			assert.Empty(t, series)
+		})
+	}
+}
+
+func TestSQLVisitorsMerge(t *testing.T) {
+	for name, cfg := range sqlBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := NewSQLHitsRepository(cfg, openTestSQL(t, cfg))
+			assert.NoError(t, err)
+			assertVisitorsMerge(t, repo)
+		})
+	}
+}
+
+func TestSQLBotsAccumulate(t *testing.T) {
+	for name, cfg := range sqlBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := NewSQLHitsRepository(cfg, openTestSQL(t, cfg))
+			assert.NoError(t, err)
+			assertBotsAccumulate(t, repo)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+-- Timestamps are stored as unix nanoseconds, expires_at is 0 when the URL never expires.
+-- Identifiers use the C collation so they sort byte-wise as in the other backends
+CREATE TABLE urls (
+    url_id     TEXT COLLATE "C" NOT NULL PRIMARY KEY,
+    full_url   TEXT    NOT NULL,
+    created_by TEXT    NOT NULL,
+    created_at BIGINT  NOT NULL,
+    enabled    BOOLEAN NOT NULL,
+    expires_at BIGINT  NOT NULL DEFAULT 0,
+    version    BIGINT  NOT NULL DEFAULT 0
+);
+
+CREATE INDEX urls_created_by ON urls (created_by, created_at);
+
+CREATE TABLE url_revisions (
+    url_id     TEXT    NOT NULL,
+    version    BIGINT  NOT NULL,
+    full_url   TEXT    NOT NULL,
+    enabled    BOOLEAN NOT NULL,
+    changed_by TEXT    NOT NULL,
+    changed_at BIGINT  NOT NULL,
+    PRIMARY KEY (url_id, version)
+);
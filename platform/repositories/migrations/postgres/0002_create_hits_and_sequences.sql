@@ -0,0 +1,13 @@
+-- bucket_start is the unix time in seconds of the bucket beginning
+CREATE TABLE url_hits (
+    url_id       TEXT    NOT NULL,
+    granularity  TEXT    NOT NULL,
+    bucket_start BIGINT  NOT NULL,
+    hits         BIGINT  NOT NULL,
+    PRIMARY KEY (url_id, granularity, bucket_start)
+);
+
+CREATE TABLE sequences (
+    sequence_name TEXT    NOT NULL PRIMARY KEY,
+    next_value    BIGINT  NOT NULL
+);
@@ -0,0 +1,22 @@
+-- Timestamps are stored as unix nanoseconds, expires_at is 0 when the URL never expires
+CREATE TABLE urls (
+    url_id     TEXT    NOT NULL PRIMARY KEY,
+    full_url   TEXT    NOT NULL,
+    created_by TEXT    NOT NULL,
+    created_at INTEGER NOT NULL,
+    enabled    INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL DEFAULT 0,
+    version    INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX urls_created_by ON urls (created_by, created_at);
+
+CREATE TABLE url_revisions (
+    url_id     TEXT    NOT NULL,
+    version    INTEGER NOT NULL,
+    full_url   TEXT    NOT NULL,
+    enabled    INTEGER NOT NULL,
+    changed_by TEXT    NOT NULL,
+    changed_at INTEGER NOT NULL,
+    PRIMARY KEY (url_id, version)
+);
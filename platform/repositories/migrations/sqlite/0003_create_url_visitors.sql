@@ -0,0 +1,7 @@
+-- day is the unix time in seconds of the UTC day beginning, sketch a serialized HyperLogLog
+CREATE TABLE url_visitors (
+    url_id TEXT    NOT NULL,
+    day    INTEGER NOT NULL,
+    sketch BLOB    NOT NULL,
+    PRIMARY KEY (url_id, day)
+);
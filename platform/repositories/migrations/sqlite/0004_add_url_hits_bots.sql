@@ -0,0 +1,2 @@
+-- bots is how many of the hits came from crawlers and link preview fetchers
+ALTER TABLE url_hits ADD COLUMN bots INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,392 @@
+// Package repotest is a conformance suite for domain.URLRepository
+// implementations, so every backend and decorator behaves like DynamoDB
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// Factory returns an empty repository, t allows registering cleanups
+type Factory func(t *testing.T) domain.URLRepository
+
+var (
+	upstream, _      = url.Parse("https://opentelemetry.io/docs")
+	otherUpstream, _ = url.Parse("https://go.dev/doc")
+	insecure, _      = url.Parse("http://opentelemetry.io")
+	author           = "root@neonmei.cloud"
+)
+
+// NewShortURL is a valid enabled URL. Times are truncated to seconds, the
+// precision DynamoDB stores them with, and in UTC so they compare equal
+func NewShortURL(id string) domain.ShortURL {
+	return domain.ShortURL{
+		ID:        id,
+		Upstream:  *upstream,
+		CreatedBy: author,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Enabled:   true,
+	}
+}
+
+// TestURLRepository runs every conformance check against fresh repositories
+func TestURLRepository(t *testing.T, newRepo Factory) {
+	checks := []struct {
+		name  string
+		check func(t *testing.T, repo domain.URLRepository)
+	}{
+		{"RoundTrip", testRoundTrip},
+		{"Duplicate", testDuplicate},
+		{"SoftDelete", testSoftDelete},
+		{"Purge", testPurge},
+		{"NotFound", testNotFound},
+		{"Validation", testValidation},
+		{"List", testList},
+		{"Update", testUpdate},
+		{"Concurrency", testConcurrency},
+		{"Cancellation", testCancellation},
+	}
+
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			c.check(t, newRepo(t))
+		})
+	}
+}
+
+func testRoundTrip(t *testing.T, repo domain.URLRepository) {
+	ctx := context.Background()
+
+	item := NewShortURL("rt1")
+	item.ExpiresAt = item.CreatedAt.Add(time.Hour)
+	assert.NoError(t, repo.Save(ctx, item))
+
+	retrieved, err := repo.Get(ctx, item.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, retrieved) {
+		assert.Equal(t, item.ID, retrieved.ID)
+		assert.Equal(t, item.Upstream.String(), retrieved.Upstream.String())
+		assert.Equal(t, item.CreatedBy, retrieved.CreatedBy)
+		assert.True(t, item.CreatedAt.Equal(retrieved.CreatedAt), "created_at %s != %s", item.CreatedAt, retrieved.CreatedAt)
+		assert.True(t, item.ExpiresAt.Equal(retrieved.ExpiresAt), "expires_at %s != %s", item.ExpiresAt, retrieved.ExpiresAt)
+		assert.True(t, retrieved.Enabled)
+		assert.Equal(t, int64(0), retrieved.Version)
+	}
+
+	// REF: never expiring URLs keep a zero expiration
+	assert.NoError(t, repo.Save(ctx, NewShortURL("rt2")))
+	retrieved, err = repo.Get(ctx, "rt2")
+	assert.NoError(t, err)
+	if assert.NotNil(t, retrieved) {
+		assert.True(t, retrieved.ExpiresAt.IsZero())
+	}
+}
+
+func testDuplicate(t *testing.T, repo domain.URLRepository) {
+	ctx := context.Background()
+
+	item := NewShortURL("dup")
+	assert.NoError(t, repo.Save(ctx, item))
+
+	// REF: the first writer keeps the identifier, like a conditional put
+	other := NewShortURL("dup")
+	other.Upstream = *otherUpstream
+	assert.ErrorIs(t, repo.Save(ctx, other), domain.ErrURLAlreadyExists)
+
+	retrieved, err := repo.Get(ctx, "dup")
+	assert.NoError(t, err)
+	if assert.NotNil(t, retrieved) {
+		assert.Equal(t, upstream.String(), retrieved.Upstream.String())
+	}
+
+	// REF: disabled URLs still hold their identifier
+	assert.NoError(t, repo.Delete(ctx, "dup"))
+	assert.ErrorIs(t, repo.Save(ctx, other), domain.ErrURLAlreadyExists)
+}
+
+func testSoftDelete(t *testing.T, repo domain.URLRepository) {
+	ctx := context.Background()
+
+	assert.NoError(t, repo.Save(ctx, NewShortURL("del")))
+	assert.NoError(t, repo.Delete(ctx, "del"))
+
+	retrieved, err := repo.Get(ctx, "del")
+	assert.NoError(t, err)
+	if assert.NotNil(t, retrieved) {
+		assert.False(t, retrieved.Enabled)
+	}
+
+	// REF: deleting twice is fine
+	assert.NoError(t, repo.Delete(ctx, "del"))
+
+	assert.NoError(t, repo.Restore(ctx, "del"))
+	retrieved, err = repo.Get(ctx, "del")
+	assert.NoError(t, err)
+	if assert.NotNil(t, retrieved) {
+		assert.True(t, retrieved.Enabled)
+	}
+}
+
+func testPurge(t *testing.T, repo domain.URLRepository) {
+	ctx := context.Background()
+
+	item := NewShortURL("purge")
+	assert.NoError(t, repo.Save(ctx, item))
+	assert.NoError(t, repo.Update(ctx, item, domain.Revision{
+		Upstream:  *upstream,
+		Enabled:   true,
+		ChangedBy: author,
+		ChangedAt: time.Now().UTC().Truncate(time.Second),
+	}))
+	assert.NoError(t, repo.Purge(ctx, "purge"))
+
+	retrieved, err := repo.Get(ctx, "purge")
+	assert.Nil(t, retrieved)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// REF: the identifier is free again, without the old history
+	assert.NoError(t, repo.Save(ctx, item))
+	revisions, err := repo.Revisions(ctx, "purge")
+	assert.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func testNotFound(t *testing.T, repo domain.URLRepository) {
+	ctx := context.Background()
+
+	retrieved, err := repo.Get(ctx, "missing")
+	assert.Nil(t, retrieved)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	assert.ErrorIs(t, repo.Delete(ctx, "missing"), domain.ErrURLNotFound)
+	assert.ErrorIs(t, repo.Restore(ctx, "missing"), domain.ErrURLNotFound)
+	assert.ErrorIs(t, repo.Purge(ctx, "missing"), domain.ErrURLNotFound)
+
+	_, err = repo.Revisions(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// REF: a missing URL must not be created by the failed calls above
+	retrieved, err = repo.Get(ctx, "missing")
+	assert.Nil(t, retrieved)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func testValidation(t *testing.T, repo domain.URLRepository) {
+	ctx := context.Background()
+
+	invalid := map[string]func(u *domain.ShortURL){
+		"insecure":   func(u *domain.ShortURL) { u.Upstream = *insecure },
+		"empty id":   func(u *domain.ShortURL) { u.ID = "" },
+		"invalid id": func(u *domain.ShortURL) { u.ID = "not valid" },
+		"author":     func(u *domain.ShortURL) { u.CreatedBy = "root" },
+		"created":    func(u *domain.ShortURL) { u.CreatedAt = time.Time{} },
+		"expiration": func(u *domain.ShortURL) { u.ExpiresAt = u.CreatedAt.Add(-time.Hour) },
+	}
+
+	for name, mutate := range invalid {
+		item := NewShortURL("invalid")
+		mutate(&item)
+
+		assert.Error(t, repo.Save(ctx, item), name)
+		assert.NotErrorIs(t, repo.Save(ctx, item), domain.ErrUnavailableRepo, name)
+	}
+
+	// REF: nothing was stored
+	_, err := repo.Get(ctx, "invalid")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// REF: updates validate too
+	item := NewShortURL("valid")
+	assert.NoError(t, repo.Save(ctx, item))
+	item.Upstream = *insecure
+	assert.Error(t, repo.Update(ctx, item, domain.Revision{Upstream: *upstream, ChangedBy: author, ChangedAt: time.Now()}))
+
+	retrieved, err := repo.Get(ctx, "valid")
+	assert.NoError(t, err)
+	if assert.NotNil(t, retrieved) {
+		assert.Equal(t, upstream.String(), retrieved.Upstream.String())
+	}
+}
+
+func testList(t *testing.T, repo domain.URLRepository) {
+	ctx := context.Background()
+
+	for _, id := range []string{"l1", "l2", "l3", "l4", "l5"} {
+		assert.NoError(t, repo.Save(ctx, NewShortURL(id)))
+	}
+	assert.NoError(t, repo.Delete(ctx, "l3"))
+
+	other := NewShortURL("l6")
+	other.Upstream = *otherUpstream
+	other.CreatedBy = "other@neonmei.cloud"
+	assert.NoError(t, repo.Save(ctx, other))
+
+	// REF: the cursor walks every item exactly once, DynamoDB scans promise no order
+	seen := []string{}
+	cursor := ""
+	for pages := 0; pages < 10; pages++ {
+		page, err := repo.List(ctx, domain.URLFilter{}, cursor, 2)
+		if !assert.NoError(t, err) {
+			break
+		}
+
+		assert.LessOrEqual(t, len(page.Items), 2)
+		for _, item := range page.Items {
+			seen = append(seen, item.ID)
+		}
+
+		if page.Cursor == "" {
+			break
+		}
+		cursor = page.Cursor
+	}
+	assert.ElementsMatch(t, []string{"l1", "l2", "l3", "l4", "l5", "l6"}, seen)
+
+	disabled := false
+	page, err := repo.List(ctx, domain.URLFilter{Enabled: &disabled}, "", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, "l3", page.Items[0].ID)
+	}
+
+	page, err = repo.List(ctx, domain.URLFilter{CreatedBy: "other@neonmei.cloud"}, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+
+	page, err = repo.List(ctx, domain.URLFilter{UpstreamHost: "GO.DEV"}, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+
+	_, err = repo.List(ctx, domain.URLFilter{}, "not a cursor!", 10)
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+}
+
+func testUpdate(t *testing.T, repo domain.URLRepository) {
+	ctx := context.Background()
+
+	item := NewShortURL("upd")
+	assert.NoError(t, repo.Save(ctx, item))
+
+	revision := domain.Revision{
+		Version:   0,
+		Upstream:  *upstream,
+		Enabled:   true,
+		ChangedBy: author,
+		ChangedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	updated := item
+	updated.Upstream = *otherUpstream
+	assert.NoError(t, repo.Update(ctx, updated, revision))
+
+	// REF: the second writer holding the same version loses
+	assert.ErrorIs(t, repo.Update(ctx, updated, revision), domain.ErrVersionConflict)
+
+	retrieved, err := repo.Get(ctx, "upd")
+	assert.NoError(t, err)
+	if assert.NotNil(t, retrieved) {
+		assert.Equal(t, int64(1), retrieved.Version)
+		assert.Equal(t, otherUpstream.String(), retrieved.Upstream.String())
+	}
+
+	revisions, err := repo.Revisions(ctx, "upd")
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 1) {
+		assert.Equal(t, revision.Version, revisions[0].Version)
+		assert.Equal(t, revision.Upstream.String(), revisions[0].Upstream.String())
+		assert.Equal(t, revision.ChangedBy, revisions[0].ChangedBy)
+		assert.True(t, revision.ChangedAt.Equal(revisions[0].ChangedAt))
+	}
+
+	missing := NewShortURL("missing")
+	assert.Error(t, repo.Update(ctx, missing, revision))
+}
+
+func testConcurrency(t *testing.T, repo domain.URLRepository) {
+	ctx := context.Background()
+	const writers = 8
+
+	// REF: exactly one of many writers racing for an identifier wins
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		inserted int
+	)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := repo.Save(ctx, NewShortURL("race"))
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrURLAlreadyExists)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			inserted++
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, inserted)
+
+	// REF: distinct identifiers never get in each other's way
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("c%d", i)
+
+			assert.NoError(t, repo.Save(ctx, NewShortURL(id)))
+			_, err := repo.Get(ctx, id)
+			assert.NoError(t, err)
+			assert.NoError(t, repo.Delete(ctx, id))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < writers; i++ {
+		retrieved, err := repo.Get(ctx, fmt.Sprintf("c%d", i))
+		assert.NoError(t, err)
+		if assert.NotNil(t, retrieved) {
+			assert.False(t, retrieved.Enabled)
+		}
+	}
+}
+
+// testCancellation expects writes to fail without side effects once the caller
+// gave up. Reads may still be answered, i.e: from a cache, but must be right
+func testCancellation(t *testing.T, repo domain.URLRepository) {
+	ctx := context.Background()
+	assert.NoError(t, repo.Save(ctx, NewShortURL("kept")))
+
+	cancelled, cancelFunc := context.WithCancel(ctx)
+	cancelFunc()
+
+	assert.ErrorIs(t, repo.Save(cancelled, NewShortURL("cancelled")), context.Canceled)
+	assert.ErrorIs(t, repo.Delete(cancelled, "kept"), context.Canceled)
+	assert.ErrorIs(t, repo.Purge(cancelled, "kept"), context.Canceled)
+
+	retrieved, err := repo.Get(cancelled, "kept")
+	if err != nil {
+		assert.ErrorIs(t, err, context.Canceled)
+	} else {
+		assert.True(t, retrieved.Enabled)
+	}
+
+	_, err = repo.Get(ctx, "cancelled")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	retrieved, err = repo.Get(ctx, "kept")
+	assert.NoError(t, err)
+	if assert.NotNil(t, retrieved) {
+		assert.True(t, retrieved.Enabled)
+	}
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	bolt "go.etcd.io/bbolt"
+)
+
+type boltSequenceRepo struct {
+	db *bolt.DB
+}
+
+// Lease runs in a write transaction, bolt serializes them so blocks never overlap
+func (d *boltSequenceRepo) Lease(_ context.Context, name string, size uint64) (uint64, error) {
+	var start uint64
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		sequences := tx.Bucket(boltSequencesBucket)
+		if current := sequences.Get([]byte(name)); current != nil {
+			start = binary.BigEndian.Uint64(current)
+		}
+
+		return sequences.Put([]byte(name), binary.BigEndian.AppendUint64(nil, start+size))
+	})
+	if err != nil {
+		return 0, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return start, nil
+}
+
+func NewBoltSequenceRepository(db *bolt.DB) (domain.SequenceRepository, error) {
+	if err := ensureBoltBuckets(db, boltSequencesBucket); err != nil {
+		return nil, err
+	}
+
+	return &boltSequenceRepo{db: db}, nil
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	awsDynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories/dtos"
+)
+
+type dynaSequenceRepo struct {
+	tableName    string
+	client       clients.DynamoDbClient
+	writeTimeout time.Duration
+}
+
+// Lease uses an atomic ADD, so concurrent replicas always get disjoint blocks
+func (d *dynaSequenceRepo) Lease(ctx context.Context, name string, size uint64) (uint64, error) {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.writeTimeout)
+	defer cancelFunc()
+
+	output, err := d.client.UpdateItem(newCtx, &awsDynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"sequence_name": &types.AttributeValueMemberS{Value: name},
+		},
+		UpdateExpression: aws.String("ADD next_value :size"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":size": &types.AttributeValueMemberN{Value: strconv.FormatUint(size, 10)},
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		return 0, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	item := dtos.SequenceItem{}
+	if err := attributevalue.UnmarshalMap(output.Attributes, &item); err != nil {
+		return 0, errors.Join(domain.ErrRepoSchema, err)
+	}
+
+	if item.Next < size {
+		return 0, domain.ErrRepoSchema
+	}
+
+	return item.Next - size, nil
+}
+
+func NewDynamoSequenceRepository(cfg config.AppConfig, client clients.DynamoDbClient) domain.SequenceRepository {
+	return &dynaSequenceRepo{
+		tableName:    cfg.Dynamo.SequencesTableName,
+		client:       client,
+		writeTimeout: cfg.Dynamo.WriteTimeout,
+	}
+}
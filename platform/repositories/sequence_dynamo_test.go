@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	awsDynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/neonmei/challenge_urlshortener/domain"
+	clientMock "github.com/neonmei/challenge_urlshortener/mocks/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/clients/dynamotest"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSequenceBackendLease(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoSequenceRepository(cfg, dynamoClient)
+
+	dynamoClient.On("UpdateItem", mock.Anything, mock.MatchedBy(func(in *awsDynamodb.UpdateItemInput) bool {
+		name := in.Key["sequence_name"].(*types.AttributeValueMemberS).Value
+		size := in.ExpressionAttributeValues[":size"].(*types.AttributeValueMemberN).Value
+		return *in.UpdateExpression == "ADD next_value :size" && name == "counter" && size == "1000"
+	})).Return(&awsDynamodb.UpdateItemOutput{
+		Attributes: map[string]types.AttributeValue{
+			"next_value": &types.AttributeValueMemberN{Value: "3000"},
+		},
+	}, nil).Once()
+
+	start, err := repo.Lease(ctx, "counter", 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2000), start)
+}
+
+func TestSequenceBackendErrors(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoSequenceRepository(cfg, dynamoClient)
+
+	dynamoClient.On("UpdateItem", mock.Anything, mock.Anything).Return(nil, errors.New("throttled")).Once()
+	_, err := repo.Lease(ctx, "counter", 1000)
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+
+	dynamoClient.On("UpdateItem", mock.Anything, mock.Anything).Return(&awsDynamodb.UpdateItemOutput{
+		Attributes: map[string]types.AttributeValue{
+			"next_value": &types.AttributeValueMemberS{Value: "not a number"},
+		},
+	}, nil).Once()
+	_, err = repo.Lease(ctx, "counter", 1000)
+	assert.ErrorIs(t, err, domain.ErrRepoSchema)
+}
+
+func TestSequenceBackendEndToEnd(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	repo := NewDynamoSequenceRepository(cfg, dynamotest.NewFake(dynamotest.AppTables(cfg)...))
+
+	// REF: blocks are consecutive and disjoint
+	for _, expected := range []uint64{0, 1000, 2000} {
+		start, err := repo.Lease(ctx, "counter", 1000)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, start)
+	}
+
+	start, err := repo.Lease(ctx, "other", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), start)
+}
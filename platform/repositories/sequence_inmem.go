@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+type memorySequenceRepo struct {
+	mu   sync.Mutex
+	next map[string]uint64
+}
+
+func (d *memorySequenceRepo) Lease(_ context.Context, name string, size uint64) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	start := d.next[name]
+	d.next[name] = start + size
+	return start, nil
+}
+
+// NewMemorySequences is an in-memory sequence repository for development and tests
+func NewMemorySequences() domain.SequenceRepository {
+	return &memorySequenceRepo{next: map[string]uint64{}}
+}
@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemorySequencesLeaseDisjointBlocks(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemorySequences()
+
+	first, err := repo.Lease(ctx, "counter", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), first)
+
+	second, err := repo.Lease(ctx, "counter", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), second)
+
+	// REF: sequences are independent
+	other, err := repo.Lease(ctx, "hashids", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), other)
+}
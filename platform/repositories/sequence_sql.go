@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+)
+
+type sqlSequenceRepo struct {
+	db           *sql.DB
+	dialect      sqlDialect
+	writeTimeout time.Duration
+}
+
+// Lease is a single upsert returning the new value, concurrent replicas always get disjoint blocks
+func (d *sqlSequenceRepo) Lease(ctx context.Context, name string, size uint64) (uint64, error) {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.writeTimeout)
+	defer cancelFunc()
+
+	var next int64
+	err := d.db.QueryRowContext(newCtx, d.dialect.rebind(
+		"INSERT INTO sequences (sequence_name, next_value) VALUES (?, ?) "+
+			"ON CONFLICT (sequence_name) DO UPDATE SET next_value = sequences.next_value + excluded.next_value "+
+			"RETURNING next_value"),
+		name, int64(size),
+	).Scan(&next)
+	if err != nil {
+		return 0, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	if next < int64(size) {
+		return 0, domain.ErrRepoSchema
+	}
+
+	return uint64(next) - size, nil
+}
+
+func NewSQLSequenceRepository(cfg config.AppConfig, db *sql.DB) (domain.SequenceRepository, error) {
+	dialect, err := newSQLDialect(cfg.Storage.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlSequenceRepo{
+		db:           db,
+		dialect:      dialect,
+		writeTimeout: cfg.Sql.WriteTimeout,
+	}, nil
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLSequenceLease(t *testing.T) {
+	for name, cfg := range sqlBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := NewSQLSequenceRepository(cfg, openTestSQL(t, cfg))
+			assert.NoError(t, err)
+			ctx := context.Background()
+
+			start, err := repo.Lease(ctx, "ids", 10)
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(0), start)
+
+			start, err = repo.Lease(ctx, "ids", 10)
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(10), start)
+
+			// REF: sequences are independent
+			start, err = repo.Lease(ctx, "other", 5)
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(0), start)
+
+			// REF: concurrent leases get disjoint blocks
+			var (
+				wg     sync.WaitGroup
+				mu     sync.Mutex
+				starts = map[uint64]bool{}
+			)
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					start, err := repo.Lease(ctx, "ids", 10)
+					assert.NoError(t, err)
+
+					mu.Lock()
+					defer mu.Unlock()
+					starts[start] = true
+				}()
+			}
+			wg.Wait()
+			assert.Len(t, starts, 8)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationLockId is an arbitrary key serializing migrations of PostgreSQL replicas
+const migrationLockId = 7305041
+
+// sqlDialect papers over the differences between the supported SQL backends
+type sqlDialect struct {
+	name string
+}
+
+func newSQLDialect(backend string) (sqlDialect, error) {
+	switch backend {
+	case clients.BackendSQLite, clients.BackendPostgres:
+		return sqlDialect{name: backend}, nil
+	default:
+		return sqlDialect{}, fmt.Errorf("%w: %s", domain.ErrUnknownBackend, backend)
+	}
+}
+
+// rebind turns the ? placeholders queries are written with into $n for PostgreSQL
+func (d sqlDialect) rebind(query string) string {
+	if d.name != clients.BackendPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+
+	return b.String()
+}
+
+// forUpdate locks selected rows until the transaction ends, SQLite has a
+// single writer and needs no row locks
+func (d sqlDialect) forUpdate() string {
+	if d.name != clients.BackendPostgres {
+		return ""
+	}
+
+	return " FOR UPDATE"
+}
+
+type migration struct {
+	version int
+	script  string
+}
+
+func (d sqlDialect) migrations() ([]migration, error) {
+	dir := path.Join("migrations", d.name)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []migration{}
+	for _, entry := range entries {
+		// REF: files are named <version>_<description>.sql
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration name %s", entry.Name())
+		}
+
+		script, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, migration{version: version, script: string(script)})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].version < result[j].version })
+	return result, nil
+}
+
+// MigrateSQL brings the schema up to date, applying pending migrations in a
+// single transaction. Already applied versions are recorded in schema_migrations
+func MigrateSQL(ctx context.Context, backend string, db *sql.DB) error {
+	dialect, err := newSQLDialect(backend)
+	if err != nil {
+		return err
+	}
+
+	pending, err := dialect.migrations()
+	if err != nil {
+		return errors.Join(errors.New("cannot read migrations"), err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+	defer tx.Rollback()
+
+	if dialect.name == clients.BackendPostgres {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockId); err != nil {
+			return errors.Join(domain.ErrUnavailableRepo, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL PRIMARY KEY)"); err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	var current int
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	for _, m := range pending {
+		if m.version <= current {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, m.script); err != nil {
+			return errors.Join(fmt.Errorf("migration %d failed", m.version), err)
+		}
+
+		if _, err := tx.ExecContext(ctx, dialect.rebind("INSERT INTO schema_migrations (version) VALUES (?)"), m.version); err != nil {
+			return errors.Join(domain.ErrUnavailableRepo, err)
+		}
+	}
+
+	return tx.Commit()
+}
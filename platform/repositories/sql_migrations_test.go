@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateSQLIdempotent(t *testing.T) {
+	for name, cfg := range sqlBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			db := openTestSQL(t, cfg)
+			ctx := context.Background()
+
+			// REF: running again applies nothing
+			assert.NoError(t, MigrateSQL(ctx, cfg.Storage.Backend, db))
+
+			dialect, err := newSQLDialect(cfg.Storage.Backend)
+			assert.NoError(t, err)
+			migrations, err := dialect.migrations()
+			assert.NoError(t, err)
+
+			var applied, latest int
+			assert.NoError(t, db.QueryRow("SELECT COUNT(*), MAX(version) FROM schema_migrations").Scan(&applied, &latest))
+			assert.Equal(t, len(migrations), applied)
+			assert.Equal(t, migrations[len(migrations)-1].version, latest)
+		})
+	}
+}
+
+func TestMigrationsPerDialect(t *testing.T) {
+	sqlite, _ := newSQLDialect(clients.BackendSQLite)
+	postgres, _ := newSQLDialect(clients.BackendPostgres)
+
+	sqliteMigrations, err := sqlite.migrations()
+	assert.NoError(t, err)
+	postgresMigrations, err := postgres.migrations()
+	assert.NoError(t, err)
+
+	// REF: both dialects must evolve in lockstep
+	assert.Equal(t, len(sqliteMigrations), len(postgresMigrations))
+	for i := range sqliteMigrations {
+		assert.Equal(t, i+1, sqliteMigrations[i].version)
+		assert.Equal(t, sqliteMigrations[i].version, postgresMigrations[i].version)
+	}
+
+	_, err = newSQLDialect(clients.BackendDynamo)
+	assert.ErrorIs(t, err, domain.ErrUnknownBackend)
+}
+
+func TestRebind(t *testing.T) {
+	sqlite, _ := newSQLDialect(clients.BackendSQLite)
+	postgres, _ := newSQLDialect(clients.BackendPostgres)
+
+	query := "SELECT 1 FROM urls WHERE url_id = ? AND version = ?"
+	assert.Equal(t, query, sqlite.rebind(query))
+	assert.Equal(t, "SELECT 1 FROM urls WHERE url_id = $1 AND version = $2", postgres.rebind(query))
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+// StaleStore keeps the last known good copy of URLs read from upstream,
+// bounded to capacity entries evicting the least recently refreshed. It
+// outlives ristretto evictions so hot links, which soft TTL refreshes keep at
+// the front, can still be served while upstream is unavailable. A nil store
+// keeps nothing
+type StaleStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+func (s *StaleStore) Put(shortUrl domain.ShortURL) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, found := s.items[shortUrl.ID]; found {
+		elem.Value = shortUrl
+		s.order.MoveToFront(elem)
+		return
+	}
+
+	s.items[shortUrl.ID] = s.order.PushFront(shortUrl)
+	if s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(domain.ShortURL).ID)
+	}
+}
+
+func (s *StaleStore) Get(urlID string) (domain.ShortURL, bool) {
+	if s == nil {
+		return domain.ShortURL{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, found := s.items[urlID]
+	if !found {
+		return domain.ShortURL{}, false
+	}
+
+	return elem.Value.(domain.ShortURL), true
+}
+
+func (s *StaleStore) Remove(urlID string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, found := s.items[urlID]; found {
+		s.order.Remove(elem)
+		delete(s.items, urlID)
+	}
+}
+
+// SetEnabled keeps known copies in line with deletes and restores, without
+// refreshing their position
+func (s *StaleStore) SetEnabled(urlID string, enabled bool) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, found := s.items[urlID]; found {
+		shortUrl := elem.Value.(domain.ShortURL)
+		shortUrl.Enabled = enabled
+		elem.Value = shortUrl
+	}
+}
+
+// NewStaleStore returns nil, which keeps nothing, for a capacity under one
+func NewStaleStore(capacity int) *StaleStore {
+	if capacity < 1 {
+		return nil
+	}
+
+	return &StaleStore{
+		capacity: capacity,
+		order:    list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaleStoreEvictsLeastRecentlyRefreshed(t *testing.T) {
+	store := NewStaleStore(2)
+
+	store.Put(domain.ShortURL{ID: "a"})
+	store.Put(domain.ShortURL{ID: "b"})
+	store.Put(domain.ShortURL{ID: "a", Version: 1})
+	store.Put(domain.ShortURL{ID: "c"})
+
+	_, found := store.Get("b")
+	assert.False(t, found)
+
+	item, found := store.Get("a")
+	assert.True(t, found)
+	assert.Equal(t, int64(1), item.Version)
+
+	_, found = store.Get("c")
+	assert.True(t, found)
+}
+
+func TestStaleStoreTracksDeletes(t *testing.T) {
+	store := NewStaleStore(10)
+	store.Put(domain.ShortURL{ID: validId, Enabled: true})
+
+	store.SetEnabled(validId, false)
+	item, found := store.Get(validId)
+	assert.True(t, found)
+	assert.False(t, item.Enabled)
+
+	store.Remove(validId)
+	_, found = store.Get(validId)
+	assert.False(t, found)
+}
+
+func TestStaleStoreDisabled(t *testing.T) {
+	store := NewStaleStore(0)
+	assert.Nil(t, store)
+
+	// REF: a nil store is usable and keeps nothing
+	store.Put(domain.ShortURL{ID: validId})
+	store.SetEnabled(validId, false)
+	store.Remove(validId)
+	_, found := store.Get(validId)
+	assert.False(t, found)
+}
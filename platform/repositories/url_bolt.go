@@ -0,0 +1,301 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories/dtos"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltURLRepo keeps one JSON record per identifier in the urls bucket, and the
+// history of each URL in a nested bucket of revisions keyed by version. Every
+// write is a bolt transaction, fsynced before returning
+type boltURLRepo struct {
+	db  *bolt.DB
+	ids validators.IdAlphabet
+}
+
+func getBoltRecord(tx *bolt.Tx, urlID string) (*dtos.URLRecord, error) {
+	raw := tx.Bucket(boltURLsBucket).Get([]byte(urlID))
+	if raw == nil {
+		return nil, domain.ErrURLNotFound
+	}
+
+	record := dtos.URLRecord{}
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return nil, errors.Join(domain.ErrRepoSchema, err)
+	}
+
+	return &record, nil
+}
+
+func putBoltRecord(tx *bolt.Tx, record dtos.URLRecord) error {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return errors.Join(errors.New("cannot serialize urlRecord"), err)
+	}
+
+	return tx.Bucket(boltURLsBucket).Put([]byte(record.Id), raw)
+}
+
+// boltContext fails operations whose caller already gave up, bolt itself is not context aware
+func boltContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return nil
+}
+
+// boltError leaves domain errors untouched, anything else comes from the storage
+func boltError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, known := range []error{domain.ErrURLNotFound, domain.ErrURLAlreadyExists, domain.ErrVersionConflict, domain.ErrRepoSchema} {
+		if errors.Is(err, known) {
+			return err
+		}
+	}
+
+	return errors.Join(domain.ErrUnavailableRepo, err)
+}
+
+func (d *boltURLRepo) Save(ctx context.Context, shortUrl domain.ShortURL) error {
+	if err := validators.ValidateShortURL(shortUrl, d.ids); err != nil {
+		return err
+	}
+
+	if err := boltContext(ctx); err != nil {
+		return err
+	}
+
+	return boltError(d.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltURLsBucket).Get([]byte(shortUrl.ID)) != nil {
+			return domain.ErrURLAlreadyExists
+		}
+
+		return putBoltRecord(tx, dtos.FromDomainRecord(shortUrl))
+	}))
+}
+
+func (d *boltURLRepo) Get(ctx context.Context, urlID string) (*domain.ShortURL, error) {
+	if err := boltContext(ctx); err != nil {
+		return nil, err
+	}
+
+	var result *domain.ShortURL
+	err := d.db.View(func(tx *bolt.Tx) error {
+		record, err := getBoltRecord(tx, urlID)
+		if err != nil {
+			return err
+		}
+
+		result, err = record.Domain(d.ids)
+		if err != nil {
+			return errors.Join(domain.ErrRepoSchema, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return result, nil
+}
+
+func (d *boltURLRepo) Delete(ctx context.Context, urlID string) error {
+	return d.setEnabled(ctx, urlID, false)
+}
+
+func (d *boltURLRepo) Restore(ctx context.Context, urlID string) error {
+	return d.setEnabled(ctx, urlID, true)
+}
+
+func (d *boltURLRepo) setEnabled(ctx context.Context, urlID string, enabled bool) error {
+	if err := boltContext(ctx); err != nil {
+		return err
+	}
+
+	return boltError(d.db.Update(func(tx *bolt.Tx) error {
+		record, err := getBoltRecord(tx, urlID)
+		if err != nil {
+			return err
+		}
+
+		record.Enabled = enabled
+		return putBoltRecord(tx, *record)
+	}))
+}
+
+func (d *boltURLRepo) Purge(ctx context.Context, urlID string) error {
+	if err := boltContext(ctx); err != nil {
+		return err
+	}
+
+	return boltError(d.db.Update(func(tx *bolt.Tx) error {
+		urls := tx.Bucket(boltURLsBucket)
+		if urls.Get([]byte(urlID)) == nil {
+			return domain.ErrURLNotFound
+		}
+
+		revisions := tx.Bucket(boltRevisionsBucket)
+		if revisions.Bucket([]byte(urlID)) != nil {
+			if err := revisions.DeleteBucket([]byte(urlID)); err != nil {
+				return err
+			}
+		}
+
+		return urls.Delete([]byte(urlID))
+	}))
+}
+
+// List walks keys in byte order, the cursor is the last identifier returned
+func (d *boltURLRepo) List(ctx context.Context, filter domain.URLFilter, cursor string, limit int) (*domain.URLPage, error) {
+	key, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := boltContext(ctx); err != nil {
+		return nil, err
+	}
+
+	page := &domain.URLPage{Items: []domain.ShortURL{}}
+	err = d.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltURLsBucket).Cursor()
+		after := []byte(key["url_id"])
+
+		k, v := c.Seek(after)
+		if k != nil && bytes.Equal(k, after) {
+			k, v = c.Next()
+		}
+
+		for ; k != nil; k, v = c.Next() {
+			record := dtos.URLRecord{}
+			if err := json.Unmarshal(v, &record); err != nil {
+				return errors.Join(domain.ErrRepoSchema, err)
+			}
+
+			shortUrl, err := record.Domain(d.ids)
+			if err != nil {
+				return errors.Join(domain.ErrRepoSchema, err)
+			}
+
+			if !filter.Matches(*shortUrl) {
+				continue
+			}
+
+			if len(page.Items) == limit {
+				page.Cursor = encodeCursor(map[string]string{"url_id": page.Items[limit-1].ID})
+				break
+			}
+			page.Items = append(page.Items, *shortUrl)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return page, nil
+}
+
+func boltVersionKey(version int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(version))
+}
+
+func (d *boltURLRepo) Update(ctx context.Context, shortUrl domain.ShortURL, revision domain.Revision) error {
+	if err := validators.ValidateShortURL(shortUrl, d.ids); err != nil {
+		return err
+	}
+
+	if err := boltContext(ctx); err != nil {
+		return err
+	}
+
+	return boltError(d.db.Update(func(tx *bolt.Tx) error {
+		current, err := getBoltRecord(tx, shortUrl.ID)
+		if err != nil {
+			return err
+		}
+
+		if current.Version != shortUrl.Version {
+			return domain.ErrVersionConflict
+		}
+
+		shortUrl.Version++
+		if err := putBoltRecord(tx, dtos.FromDomainRecord(shortUrl)); err != nil {
+			return err
+		}
+
+		history, err := tx.Bucket(boltRevisionsBucket).CreateBucketIfNotExists([]byte(shortUrl.ID))
+		if err != nil {
+			return err
+		}
+
+		raw, err := json.Marshal(dtos.FromRevisionRecord(revision))
+		if err != nil {
+			return errors.Join(errors.New("cannot serialize revision"), err)
+		}
+
+		return history.Put(boltVersionKey(revision.Version), raw)
+	}))
+}
+
+func (d *boltURLRepo) Revisions(ctx context.Context, urlID string) ([]domain.Revision, error) {
+	if err := boltContext(ctx); err != nil {
+		return nil, err
+	}
+
+	result := []domain.Revision{}
+	err := d.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltURLsBucket).Get([]byte(urlID)) == nil {
+			return domain.ErrURLNotFound
+		}
+
+		history := tx.Bucket(boltRevisionsBucket).Bucket([]byte(urlID))
+		if history == nil {
+			return nil
+		}
+
+		// REF: big endian version keys iterate oldest first
+		return history.ForEach(func(_, v []byte) error {
+			record := dtos.RevisionRecord{}
+			if err := json.Unmarshal(v, &record); err != nil {
+				return errors.Join(domain.ErrRepoSchema, err)
+			}
+
+			revision, err := record.Domain()
+			if err != nil {
+				return errors.Join(domain.ErrRepoSchema, err)
+			}
+
+			result = append(result, *revision)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, boltError(err)
+	}
+
+	return result, nil
+}
+
+// NewBoltURLRepository stores URLs in an embedded bolt database, creating its buckets if needed
+func NewBoltURLRepository(db *bolt.DB, ids validators.IdAlphabet) (domain.URLRepository, error) {
+	if err := ensureBoltBuckets(db, boltURLsBucket, boltRevisionsBucket); err != nil {
+		return nil, err
+	}
+
+	return &boltURLRepo{db: db, ids: ids}, nil
+}
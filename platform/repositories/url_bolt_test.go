@@ -0,0 +1,235 @@
+package repositories
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+func boltTestConfig(t *testing.T) config.AppConfig {
+	cfg := config.AppConfig{}
+	cfg.Bolt.Path = filepath.Join(t.TempDir(), "shortener.bolt")
+	cfg.Bolt.OpenTimeout = time.Second
+	return cfg
+}
+
+func openTestBolt(t *testing.T, cfg config.AppConfig) *bolt.DB {
+	t.Helper()
+
+	db, err := clients.NewBoltClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestBoltRepoBasic(t *testing.T) {
+	repo, err := NewBoltURLRepository(openTestBolt(t, boltTestConfig(t)), validators.IdAlphabet{})
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	validItem := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now().UTC(),
+		Enabled:   true,
+		ExpiresAt: time.Now().Add(time.Hour).UTC(),
+	}
+
+	// REF: Save-time validation
+	assert.Error(t, repo.Save(ctx, domain.ShortURL{
+		ID:        validId,
+		Upstream:  *URLBadSchema,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}))
+
+	retrieved, err := repo.Get(ctx, validId)
+	assert.Nil(t, retrieved)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// REF: Existance
+	assert.NoError(t, repo.Save(ctx, validItem))
+	assert.ErrorIs(t, repo.Save(ctx, validItem), domain.ErrURLAlreadyExists)
+
+	retrieved, err = repo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, validItem, *retrieved)
+
+	// REF: Deletion is logical, same as DynamoDB
+	assert.NoError(t, repo.Delete(ctx, validId))
+	retrieved, err = repo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.False(t, retrieved.Enabled)
+
+	// REF: Restore
+	assert.NoError(t, repo.Restore(ctx, validId))
+	retrieved, err = repo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.True(t, retrieved.Enabled)
+
+	// REF: Purge
+	assert.NoError(t, repo.Purge(ctx, validId))
+	retrieved, err = repo.Get(ctx, validId)
+	assert.Nil(t, retrieved)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	assert.ErrorIs(t, repo.Delete(ctx, validId), domain.ErrURLNotFound)
+	assert.ErrorIs(t, repo.Restore(ctx, validId), domain.ErrURLNotFound)
+	assert.ErrorIs(t, repo.Purge(ctx, validId), domain.ErrURLNotFound)
+}
+
+func TestBoltRepoList(t *testing.T) {
+	repo, err := NewBoltURLRepository(openTestBolt(t, boltTestConfig(t)), validators.IdAlphabet{})
+	assert.NoError(t, err)
+	ctx := context.Background()
+	otherURL, _ := url.Parse("https://go.dev/doc")
+
+	for _, id := range []string{"a1", "a2", "a3", "a4", "a5"} {
+		assert.NoError(t, repo.Save(ctx, domain.ShortURL{
+			ID:        id,
+			Upstream:  *validURL,
+			CreatedBy: validAuthor,
+			CreatedAt: time.Now(),
+			Enabled:   id != "a3",
+		}))
+	}
+	assert.NoError(t, repo.Save(ctx, domain.ShortURL{
+		ID:        "b1",
+		Upstream:  *otherURL,
+		CreatedBy: "other@neonmei.cloud",
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}))
+
+	// REF: walk every page following the cursor
+	seen := []string{}
+	cursor := ""
+	for {
+		page, err := repo.List(ctx, domain.URLFilter{}, cursor, 2)
+		assert.NoError(t, err)
+		for _, item := range page.Items {
+			seen = append(seen, item.ID)
+		}
+
+		if page.Cursor == "" {
+			break
+		}
+		cursor = page.Cursor
+	}
+	assert.Equal(t, []string{"a1", "a2", "a3", "a4", "a5", "b1"}, seen)
+
+	// REF: filters
+	disabled := false
+	page, err := repo.List(ctx, domain.URLFilter{Enabled: &disabled}, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, "a3", page.Items[0].ID)
+
+	page, err = repo.List(ctx, domain.URLFilter{UpstreamHost: "go.dev"}, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Empty(t, page.Cursor)
+
+	_, err = repo.List(ctx, domain.URLFilter{}, "not a cursor", 10)
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+}
+
+func TestBoltRepoUpdate(t *testing.T) {
+	repo, err := NewBoltURLRepository(openTestBolt(t, boltTestConfig(t)), validators.IdAlphabet{})
+	assert.NoError(t, err)
+	ctx := context.Background()
+	otherURL, _ := url.Parse("https://go.dev/doc")
+
+	item := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}
+	assert.NoError(t, repo.Save(ctx, item))
+
+	revision := domain.Revision{
+		Version:   0,
+		Upstream:  *validURL,
+		Enabled:   true,
+		ChangedBy: validAuthor,
+		ChangedAt: time.Now().UTC(),
+	}
+	item.Upstream = *otherURL
+	assert.NoError(t, repo.Update(ctx, item, revision))
+
+	retrieved, err := repo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), retrieved.Version)
+	assert.Equal(t, otherURL.String(), retrieved.Upstream.String())
+
+	// REF: a stale version loses
+	assert.ErrorIs(t, repo.Update(ctx, item, revision), domain.ErrVersionConflict)
+
+	revisions, err := repo.Revisions(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Revision{revision}, revisions)
+
+	item.ID = "missing"
+	assert.ErrorIs(t, repo.Update(ctx, item, revision), domain.ErrURLNotFound)
+	_, err = repo.Revisions(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// REF: Purge drops the history too
+	assert.NoError(t, repo.Purge(ctx, validId))
+	item.ID = validId
+	item.Version = 0
+	assert.NoError(t, repo.Save(ctx, item))
+	revisions, err = repo.Revisions(ctx, validId)
+	assert.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func TestBoltRepoDurable(t *testing.T) {
+	cfg := boltTestConfig(t)
+	ctx := context.Background()
+
+	db, err := clients.NewBoltClient(cfg)
+	assert.NoError(t, err)
+	repo, err := NewBoltURLRepository(db, validators.IdAlphabet{})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Save(ctx, domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}))
+
+	// REF: the file is locked while open
+	locked := cfg
+	locked.Bolt.OpenTimeout = 10 * time.Millisecond
+	_, err = clients.NewBoltClient(locked)
+	assert.Error(t, err)
+	assert.NoError(t, db.Close())
+
+	// REF: writes survive reopening the file
+	repo, err = NewBoltURLRepository(openTestBolt(t, cfg), validators.IdAlphabet{})
+	assert.NoError(t, err)
+	retrieved, err := repo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, validURL.String(), retrieved.Upstream.String())
+
+	_, err = repo.Get(ctx, "other")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
@@ -3,24 +3,86 @@ package repositories
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/dgraph-io/ristretto/v2"
 	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/invalidation"
 	"github.com/neonmei/challenge_urlshortener/platform/o11y/semconv"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type (
-	URLCache       = ristretto.Cache[string, domain.ShortURL]
-	URLCacheConfig = ristretto.Config[string, domain.ShortURL]
+	URLCache       = ristretto.Cache[string, CacheEntry]
+	URLCacheConfig = ristretto.Config[string, CacheEntry]
 )
 
+// CacheEntry is what the URL cache holds for an identifier
+type CacheEntry struct {
+	URL domain.ShortURL
+
+	// Missing marks identifiers upstream does not know
+	Missing bool
+
+	// FetchedAt is when URL was last read from or written to upstream
+	FetchedAt time.Time
+}
+
 const CachedShortURLCost = 1
 
 type cachedRepository struct {
-	upstream domain.URLRepository
-	cache    *URLCache
+	upstream     domain.URLRepository
+	cache        *URLCache
+	stale        *StaleStore
+	peers        invalidation.Notifier
+	lookups      *coalescer
+	counters     *CacheCounters
+	negativeTTL  time.Duration
+	negativeCost int64
+	softTTL      time.Duration
+}
+
+// CachedOption customizes the cached repository decorator
+type CachedOption func(*cachedRepository)
+
+// WithPeerNotifier propagates deletes to the caches of other replicas
+func WithPeerNotifier(n invalidation.Notifier) CachedOption {
+	return func(d *cachedRepository) {
+		d.peers = n
+	}
+}
+
+// WithNegativeCache remembers not found lookups for ttl, so unknown
+// identifiers do not reach upstream on every request. A zero ttl disables it
+func WithNegativeCache(ttl time.Duration, cost int64) CachedOption {
+	return func(d *cachedRepository) {
+		d.negativeTTL = ttl
+		d.negativeCost = cost
+	}
+}
+
+// WithCacheCounters shares the decorator counters, i.e: to export them as metrics
+func WithCacheCounters(c *CacheCounters) CachedOption {
+	return func(d *cachedRepository) {
+		d.counters = c
+	}
+}
+
+// WithStaleWhileRevalidate serves entries older than softTTL while they are
+// refreshed in the background. A zero softTTL never refreshes
+func WithStaleWhileRevalidate(softTTL time.Duration) CachedOption {
+	return func(d *cachedRepository) {
+		d.softTTL = softTTL
+	}
+}
+
+// WithStaleIfError serves the last known good copy kept in store when
+// upstream is unavailable and the cache no longer has the entry
+func WithStaleIfError(store *StaleStore) CachedOption {
+	return func(d *cachedRepository) {
+		d.stale = store
+	}
 }
 
 func (d *cachedRepository) Save(ctx context.Context, shortUrl domain.ShortURL) error {
@@ -29,61 +91,237 @@ func (d *cachedRepository) Save(ctx context.Context, shortUrl domain.ShortURL) e
 		return err
 	}
 
-	d.cache.Set(shortUrl.ID, shortUrl, CachedShortURLCost)
+	// REF: a negative entry would survive a rejected Set
+	d.cache.Del(shortUrl.ID)
+	setCache(d.cache, shortUrl)
+	d.stale.Put(shortUrl)
 	d.cache.Wait()
+	d.notifyPeers(shortUrl.ID, invalidation.ActionEvict)
 	return nil
 }
 
 func (d *cachedRepository) Get(ctx context.Context, urlID string) (*domain.ShortURL, error) {
-	cacheItem, found := d.cache.Get(urlID)
+	span := trace.SpanFromContext(ctx)
+	entry, found := d.cache.Get(urlID)
+	if found && entry.Missing {
+		d.counters.negativeHits.Add(1)
+		span.SetAttributes(attribute.Bool(semconv.CacheHit, true), attribute.Bool(semconv.CacheNegativeHit, true))
+		return nil, domain.ErrURLNotFound
+	}
+
+	// REF: the upstream call is shared, so one caller going away must not cancel it for the rest
+	upstreamCtx := context.WithoutCancel(ctx)
 	if found {
-		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool(semconv.CacheHit, true))
-		return &cacheItem, nil
+		span.SetAttributes(attribute.Bool(semconv.CacheHit, true))
+		if d.softTTL > 0 && time.Since(entry.FetchedAt) > d.softTTL {
+			d.counters.stale.Add(1)
+			span.SetAttributes(attribute.Bool(semconv.CacheStale, true))
+			d.lookups.Go(urlID, func() (*domain.ShortURL, error) {
+				return d.load(upstreamCtx, urlID)
+			})
+		}
+
+		return &entry.URL, nil
+	}
+
+	result, err, shared := d.lookups.Do(urlID, func() (*domain.ShortURL, error) {
+		return d.load(upstreamCtx, urlID)
+	})
+
+	span.SetAttributes(attribute.Bool(semconv.CacheHit, false), attribute.Bool(semconv.CacheCoalesced, shared))
+	if shared {
+		d.counters.coalesced.Add(1)
+	}
+
+	// REF: degraded mode, better an old destination than an error page
+	if errors.Is(err, domain.ErrUnavailableRepo) {
+		if lastKnown, found := d.stale.Get(urlID); found {
+			d.counters.stale.Add(1)
+			span.SetAttributes(attribute.Bool(semconv.CacheStale, true))
+			return &lastKnown, nil
+		}
 	}
 
-	result, err := d.upstream.Get(ctx, urlID)
 	if err != nil {
 		return nil, err
 	}
 
-	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool(semconv.CacheHit, false))
-	d.cache.Set(urlID, *result, CachedShortURLCost)
-	return result, nil
+	// REF: every caller gets its own copy of a shared result
+	item := *result
+	return &item, nil
 }
 
-func (d *cachedRepository) Delete(ctx context.Context, urlID string) error {
-	if _, err := d.upstream.Get(ctx, urlID); err != nil {
-		// This is more of a consistency assertion
-		if errors.Is(err, domain.ErrURLNotFound) {
-			d.cache.Del(urlID)
+// load reads upstream and records the outcome in every cache layer. Failures
+// leave them untouched, so stale entries keep being served
+func (d *cachedRepository) load(ctx context.Context, urlID string) (*domain.ShortURL, error) {
+	result, err := d.upstream.Get(ctx, urlID)
+	if errors.Is(err, domain.ErrURLNotFound) {
+		d.stale.Remove(urlID)
+		d.cache.Del(urlID)
+		if d.negativeTTL > 0 {
+			d.cache.SetWithTTL(urlID, CacheEntry{URL: domain.ShortURL{ID: urlID}, Missing: true}, d.negativeCost, d.negativeTTL)
 		}
-		return err
 	}
 
-	err := d.upstream.Delete(ctx, urlID)
-	if err != nil {
+	if err == nil {
+		setCache(d.cache, *result)
+		d.stale.Put(*result)
+	}
+
+	return result, err
+}
+
+func (d *cachedRepository) Delete(ctx context.Context, urlID string) error {
+	if err := d.upstream.Delete(ctx, urlID); err != nil {
+		d.evictIfMissing(urlID, err)
 		return err
 	}
 
 	d.tryNegativeCache(urlID)
+	d.notifyPeers(urlID, invalidation.ActionDisable)
 	return nil
 }
 
+// Restore flips a cached negative entry back to positive, peers re-read it
+func (d *cachedRepository) Restore(ctx context.Context, urlID string) error {
+	if err := d.upstream.Restore(ctx, urlID); err != nil {
+		d.evictIfMissing(urlID, err)
+		return err
+	}
+
+	if entry, found := d.cache.Get(urlID); found && !entry.Missing {
+		entry.URL.Enabled = true
+		setCache(d.cache, entry.URL)
+		d.cache.Wait()
+	}
+
+	d.stale.SetEnabled(urlID, true)
+	d.notifyPeers(urlID, invalidation.ActionEvict)
+	return nil
+}
+
+// Purge drops every cached copy, so replicas answer from upstream: not found
+func (d *cachedRepository) Purge(ctx context.Context, urlID string) error {
+	if err := d.upstream.Purge(ctx, urlID); err != nil {
+		d.evictIfMissing(urlID, err)
+		return err
+	}
+
+	d.cache.Del(urlID)
+	d.stale.Remove(urlID)
+	d.notifyPeers(urlID, invalidation.ActionEvict)
+	return nil
+}
+
+// evictIfMissing is more of a consistency assertion, upstream does not know
+// the URL so nobody should be serving it
+func (d *cachedRepository) evictIfMissing(urlID string, err error) {
+	if errors.Is(err, domain.ErrURLNotFound) {
+		d.cache.Del(urlID)
+		d.stale.Remove(urlID)
+		d.notifyPeers(urlID, invalidation.ActionEvict)
+	}
+}
+
+// Update refreshes the local copy and evicts the stale one from peers
+func (d *cachedRepository) Update(ctx context.Context, shortUrl domain.ShortURL, revision domain.Revision) error {
+	if err := d.upstream.Update(ctx, shortUrl, revision); err != nil {
+		// REF: our copy may be the stale one, let the retry read upstream
+		if errors.Is(err, domain.ErrVersionConflict) {
+			d.cache.Del(shortUrl.ID)
+		}
+		return err
+	}
+
+	shortUrl.Version++
+	setCache(d.cache, shortUrl)
+	d.stale.Put(shortUrl)
+	d.cache.Wait()
+	d.notifyPeers(shortUrl.ID, invalidation.ActionEvict)
+	return nil
+}
+
+// Revisions are not cached, history is administrative
+func (d *cachedRepository) Revisions(ctx context.Context, urlID string) ([]domain.Revision, error) {
+	return d.upstream.Revisions(ctx, urlID)
+}
+
+// List is not cached, listings are administrative and must be fresh
+func (d *cachedRepository) List(ctx context.Context, filter domain.URLFilter, cursor string, limit int) (*domain.URLPage, error) {
+	return d.upstream.List(ctx, filter, cursor, limit)
+}
+
+func (d *cachedRepository) notifyPeers(urlID string, action invalidation.Action) {
+	if d.peers != nil {
+		d.peers.Notify(urlID, action)
+	}
+}
+
 // tryNegativeCache if item is in cache, mark it as disabled
 func (d *cachedRepository) tryNegativeCache(shortId string) {
-	shortUrl, found := d.cache.Get(shortId)
-	if !found {
+	cacheTarget{d.cache, d.stale}.Invalidate(shortId, invalidation.ActionDisable)
+}
+
+// setCache stores an item, bounding its lifetime to its expiration so that
+// ristretto never outlives it. Already expired items are not cached.
+func setCache(cache *URLCache, shortUrl domain.ShortURL) {
+	entry := CacheEntry{URL: shortUrl, FetchedAt: time.Now()}
+	if shortUrl.ExpiresAt.IsZero() {
+		cache.Set(shortUrl.ID, entry, CachedShortURLCost)
 		return
 	}
 
-	shortUrl.Enabled = false
-	d.cache.Set(shortUrl.ID, shortUrl, CachedShortURLCost)
-	d.cache.Wait()
+	ttl := time.Until(shortUrl.ExpiresAt)
+	if ttl <= 0 {
+		cache.Del(shortUrl.ID)
+		return
+	}
+
+	cache.SetWithTTL(shortUrl.ID, entry, CachedShortURLCost, ttl)
 }
 
-func NewCached(repo domain.URLRepository, cache *URLCache) domain.URLRepository {
-	return &cachedRepository{
+func NewCached(repo domain.URLRepository, cache *URLCache, opts ...CachedOption) domain.URLRepository {
+	d := &cachedRepository{
 		cache:    cache,
 		upstream: repo,
+		lookups:  newCoalescer(),
+		counters: &CacheCounters{},
+	}
+
+	for _, opt := range opts {
+		opt(d)
 	}
+
+	return d
+}
+
+// cacheTarget applies invalidations coming from peer replicas
+type cacheTarget struct {
+	cache *URLCache
+	stale *StaleStore
+}
+
+func (c cacheTarget) Invalidate(urlID string, action invalidation.Action) {
+	switch action {
+	case invalidation.ActionEvict:
+		c.cache.Del(urlID)
+		c.stale.Remove(urlID)
+	case invalidation.ActionDisable:
+		c.stale.SetEnabled(urlID, false)
+		entry, found := c.cache.Get(urlID)
+		if !found || entry.Missing {
+			return
+		}
+
+		entry.URL.Enabled = false
+		setCache(c.cache, entry.URL)
+	}
+
+	c.cache.Wait()
+}
+
+// NewCacheTarget exposes a cache, and optionally its last known good store,
+// so peers can invalidate their entries
+func NewCacheTarget(cache *URLCache, stale *StaleStore) invalidation.Target {
+	return cacheTarget{cache: cache, stale: stale}
 }
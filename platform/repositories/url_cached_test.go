@@ -2,12 +2,17 @@ package repositories
 
 import (
 	"context"
+	"net/url"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/dgraph-io/ristretto/v2"
 	"github.com/neonmei/challenge_urlshortener/domain"
+	mockDomain "github.com/neonmei/challenge_urlshortener/mocks/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/invalidation"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func makeCache(t *testing.T) *URLCache {
@@ -50,6 +55,41 @@ func TestCachedBasic(t *testing.T) {
 	assert.Equal(t, validId, result.ID)
 }
 
+// recordingNotifier keeps what would have been sent to peers
+type recordingNotifier struct {
+	mu      sync.Mutex
+	actions map[string]invalidation.Action
+}
+
+func (n *recordingNotifier) Notify(urlID string, action invalidation.Action) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.actions[urlID] = action
+}
+
+func TestCachedSaveNotifiesPeers(t *testing.T) {
+	notifier := &recordingNotifier{actions: map[string]invalidation.Action{}}
+	cachedRepo := NewCached(NewMemory(), makeCache(t), WithPeerNotifier(notifier))
+	ctx := context.Background()
+
+	validItem := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}
+
+	// REF: peers may hold a negative entry from a lookup before the link existed
+	assert.NoError(t, cachedRepo.Save(ctx, validItem))
+	assert.Equal(t, map[string]invalidation.Action{validId: invalidation.ActionEvict}, notifier.actions)
+
+	// REF: rejected saves change nothing peers could have cached
+	delete(notifier.actions, validId)
+	assert.ErrorIs(t, cachedRepo.Save(ctx, validItem), domain.ErrURLAlreadyExists)
+	assert.Empty(t, notifier.actions)
+}
+
 func TestCachedFetch(t *testing.T) {
 	upstreamRepo := NewMemory()
 	cachedRepo := NewCached(upstreamRepo, makeCache(t))
@@ -104,3 +144,290 @@ func TestCachedDeleteErrShouldPropagate(t *testing.T) {
 	// REF: Save into cache, perform logical deletion
 	assert.ErrorIs(t, domain.ErrURLNotFound, cachedRepo.Delete(ctx, validId))
 }
+
+func TestCachedExpiration(t *testing.T) {
+	cache := makeCache(t)
+	upstreamRepo := NewMemory()
+	cachedRepo := NewCached(upstreamRepo, cache)
+	ctx := context.Background()
+
+	validItem := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+		ExpiresAt: time.Now().Add(time.Second),
+	}
+
+	assert.NoError(t, cachedRepo.Save(ctx, validItem))
+	_, found := cache.Get(validId)
+	assert.True(t, found)
+
+	// REF: once expired ristretto must not serve it anymore
+	time.Sleep(1500 * time.Millisecond)
+	_, found = cache.Get(validId)
+	assert.False(t, found)
+
+	// REF: fetching an expired item should not put it back into the cache
+	result, err := cachedRepo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.True(t, result.Expired(time.Now()))
+	_, found = cache.Get(validId)
+	assert.False(t, found)
+}
+
+func TestCachedUpdate(t *testing.T) {
+	cache := makeCache(t)
+	upstreamRepo := NewMemory()
+	cachedRepo := NewCached(upstreamRepo, cache)
+	ctx := context.Background()
+	otherURL, _ := url.Parse("https://go.dev")
+
+	validItem := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}
+	assert.NoError(t, cachedRepo.Save(ctx, validItem))
+
+	updated := validItem
+	updated.Upstream = *otherURL
+	assert.NoError(t, cachedRepo.Update(ctx, updated, domain.Revision{}))
+
+	// REF: cache picks up the new destination and version
+	result, err := cachedRepo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, otherURL.String(), result.Upstream.String())
+	assert.Equal(t, int64(1), result.Version)
+
+	// REF: on conflict the local copy is dropped
+	assert.ErrorIs(t, cachedRepo.Update(ctx, updated, domain.Revision{}), domain.ErrVersionConflict)
+	_, found := cache.Get(validId)
+	assert.False(t, found)
+}
+
+func TestCachedRestoreAndPurge(t *testing.T) {
+	cache := makeCache(t)
+	upstreamRepo := NewMemory()
+	cachedRepo := NewCached(upstreamRepo, cache)
+	ctx := context.Background()
+
+	validItem := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}
+	assert.NoError(t, cachedRepo.Save(ctx, validItem))
+	assert.NoError(t, cachedRepo.Delete(ctx, validId))
+
+	// REF: negative entry turns positive again
+	assert.NoError(t, cachedRepo.Restore(ctx, validId))
+	item, found := cache.Get(validId)
+	assert.True(t, found)
+	assert.True(t, item.URL.Enabled)
+
+	// REF: purge drops the entry
+	assert.NoError(t, cachedRepo.Purge(ctx, validId))
+	_, found = cache.Get(validId)
+	assert.False(t, found)
+
+	result, err := cachedRepo.Get(ctx, validId)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	assert.Nil(t, result)
+}
+
+func TestCachedNegativeLookups(t *testing.T) {
+	cache := makeCache(t)
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	counters := &CacheCounters{}
+	cachedRepo := NewCached(upstreamRepo, cache, WithNegativeCache(time.Minute, 1), WithCacheCounters(counters))
+	ctx := context.Background()
+
+	// REF: upstream is asked only once for an unknown identifier
+	upstreamRepo.On("Get", mock.Anything, validId).Return(nil, domain.ErrURLNotFound).Once()
+	for range 3 {
+		result, err := cachedRepo.Get(ctx, validId)
+		cache.Wait()
+		assert.ErrorIs(t, err, domain.ErrURLNotFound)
+		assert.Nil(t, result)
+	}
+	assert.Equal(t, uint64(2), counters.NegativeHits())
+
+	// REF: peer invalidations must not turn a negative entry into a disabled URL
+	NewCacheTarget(cache, nil).Invalidate(validId, invalidation.ActionDisable)
+	_, err := cachedRepo.Get(ctx, validId)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// REF: creating the identifier replaces the negative entry
+	validItem := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}
+	upstreamRepo.On("Save", mock.Anything, validItem).Return(nil).Once()
+	assert.NoError(t, cachedRepo.Save(ctx, validItem))
+
+	result, err := cachedRepo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, validId, result.ID)
+}
+
+func TestCachedNegativeLookupsDisabled(t *testing.T) {
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	cachedRepo := NewCached(upstreamRepo, makeCache(t))
+	ctx := context.Background()
+
+	upstreamRepo.On("Get", mock.Anything, validId).Return(nil, domain.ErrURLNotFound).Twice()
+	for range 2 {
+		_, err := cachedRepo.Get(ctx, validId)
+		assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	}
+}
+
+func TestCachedCoalescesConcurrentMisses(t *testing.T) {
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	counters := &CacheCounters{}
+	cachedRepo := NewCached(upstreamRepo, makeCache(t), WithCacheCounters(counters))
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	upstreamRepo.On("Get", mock.Anything, validId).
+		Run(func(mock.Arguments) { <-release }).
+		Return(&domain.ShortURL{ID: validId, Upstream: *validURL, CreatedAt: time.Now(), Enabled: true}, nil).
+		Once()
+
+	const callers = 8
+	results := make(chan *domain.ShortURL, callers)
+	wg := sync.WaitGroup{}
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := cachedRepo.Get(ctx, validId)
+			assert.NoError(t, err)
+			results <- result
+		}()
+	}
+
+	// REF: wait until every follower is parked on the leader call
+	assert.Eventually(t, func() bool { return upstreamInFlight(cachedRepo, validId) }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	// REF: callers do not share the same pointer
+	seen := map[*domain.ShortURL]bool{}
+	for result := range results {
+		assert.Equal(t, validId, result.ID)
+		assert.False(t, seen[result])
+		seen[result] = true
+	}
+	assert.Equal(t, uint64(callers-1), counters.CoalescedLookups())
+}
+
+func upstreamInFlight(repo domain.URLRepository, urlID string) bool {
+	lookups := repo.(*cachedRepository).lookups
+	lookups.mu.Lock()
+	defer lookups.mu.Unlock()
+
+	_, found := lookups.calls[urlID]
+	return found
+}
+
+func TestCachedStaleWhileRevalidate(t *testing.T) {
+	cache := makeCache(t)
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	counters := &CacheCounters{}
+	cachedRepo := NewCached(upstreamRepo, cache, WithStaleWhileRevalidate(time.Minute), WithCacheCounters(counters))
+	ctx := context.Background()
+
+	old := domain.ShortURL{ID: validId, Upstream: *validURL, CreatedAt: time.Now(), Enabled: true}
+	cache.Set(validId, CacheEntry{URL: old, FetchedAt: time.Now().Add(-time.Hour)}, 1)
+	cache.Wait()
+
+	refreshed := old
+	refreshed.Version = 1
+	upstreamRepo.On("Get", mock.Anything, validId).Return(&refreshed, nil).Once()
+
+	// REF: the old copy is served right away, the refresh happens behind it
+	result, err := cachedRepo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), result.Version)
+	assert.Equal(t, uint64(1), counters.StaleServed())
+
+	assert.Eventually(t, func() bool {
+		entry, found := cache.Get(validId)
+		return found && entry.URL.Version == 1
+	}, time.Second, time.Millisecond)
+
+	// REF: fresh entries do not trigger refreshes, the mock allows a single Get
+	result, err = cachedRepo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.Version)
+}
+
+func TestCachedRevalidateDropsPurged(t *testing.T) {
+	cache := makeCache(t)
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	stale := NewStaleStore(10)
+	cachedRepo := NewCached(upstreamRepo, cache, WithStaleWhileRevalidate(time.Minute), WithStaleIfError(stale))
+	ctx := context.Background()
+
+	old := domain.ShortURL{ID: validId, Upstream: *validURL, CreatedAt: time.Now(), Enabled: true}
+	cache.Set(validId, CacheEntry{URL: old, FetchedAt: time.Now().Add(-time.Hour)}, 1)
+	cache.Wait()
+	stale.Put(old)
+
+	upstreamRepo.On("Get", mock.Anything, validId).Return(nil, domain.ErrURLNotFound).Once()
+	_, err := cachedRepo.Get(ctx, validId)
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, found := cache.Get(validId)
+		return !found
+	}, time.Second, time.Millisecond)
+
+	_, found := stale.Get(validId)
+	assert.False(t, found)
+}
+
+func TestCachedStaleIfError(t *testing.T) {
+	cache := makeCache(t)
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	counters := &CacheCounters{}
+	stale := NewStaleStore(10)
+	cachedRepo := NewCached(upstreamRepo, cache, WithStaleIfError(stale), WithCacheCounters(counters))
+	ctx := context.Background()
+
+	item := domain.ShortURL{ID: validId, Upstream: *validURL, CreatedAt: time.Now(), Enabled: true}
+	upstreamRepo.On("Get", mock.Anything, validId).Return(&item, nil).Once()
+	_, err := cachedRepo.Get(ctx, validId)
+	assert.NoError(t, err)
+
+	// REF: ristretto evicted the entry, then the outage begins
+	cache.Del(validId)
+	upstreamRepo.On("Get", mock.Anything, validId).Return(nil, domain.ErrUnavailableRepo)
+
+	result, err := cachedRepo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, validId, result.ID)
+	assert.Equal(t, uint64(1), counters.StaleServed())
+
+	// REF: unknown identifiers still fail
+	upstreamRepo.On("Get", mock.Anything, "other").Return(nil, domain.ErrUnavailableRepo)
+	_, err = cachedRepo.Get(ctx, "other")
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+
+	// REF: peers evicting the URL drop the last known copy as well
+	NewCacheTarget(cache, stale).Invalidate(validId, invalidation.ActionEvict)
+	_, err = cachedRepo.Get(ctx, validId)
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+}
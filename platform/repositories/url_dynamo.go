@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/neonmei/challenge_urlshortener/domain/validators"
@@ -17,15 +18,21 @@ import (
 	"github.com/neonmei/challenge_urlshortener/platform/config"
 )
 
+// listMaxPages bounds how many DynamoDB pages a single List call reads when
+// filters discard most of the items
+const listMaxPages = 10
+
 type dynaURLRepo struct {
-	tableName    string
-	client       clients.DynamoDbClient
-	readTimeout  time.Duration
-	writeTimeout time.Duration
+	tableName      string
+	createdByIndex string
+	client         clients.DynamoDbClient
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	ids            validators.IdAlphabet
 }
 
 func (d *dynaURLRepo) Save(ctx context.Context, shortUrl domain.ShortURL) error {
-	if err := validators.ValidateShortURL(shortUrl); err != nil {
+	if err := validators.ValidateShortURL(shortUrl, d.ids); err != nil {
 		return err
 	}
 
@@ -43,6 +50,12 @@ func (d *dynaURLRepo) Save(ctx context.Context, shortUrl domain.ShortURL) error
 		Item:                item,
 		ConditionExpression: aws.String("attribute_not_exists(url_id)"),
 	})
+
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return errors.Join(domain.ErrURLAlreadyExists, err)
+	}
+
 	if err != nil {
 		return errors.Join(domain.ErrUnavailableRepo, err)
 	}
@@ -59,6 +72,9 @@ func (d *dynaURLRepo) Get(ctx context.Context, urlID string) (*domain.ShortURL,
 		Key: map[string]types.AttributeValue{
 			"url_id": &types.AttributeValueMemberS{Value: urlID},
 		},
+		// REF: revisions can grow large and are not needed to redirect
+		ProjectionExpression:     aws.String("url_id, created_at, created_by, enabled, full_url, expires_at, #version"),
+		ExpressionAttributeNames: map[string]string{"#version": "version"},
 	})
 	if err != nil {
 		return nil, errors.Join(domain.ErrUnavailableRepo, err)
@@ -73,7 +89,7 @@ func (d *dynaURLRepo) Get(ctx context.Context, urlID string) (*domain.ShortURL,
 		return nil, errors.Join(domain.ErrRepoSchema, err)
 	}
 
-	shortUrl, err := itemModel.Domain()
+	shortUrl, err := itemModel.Domain(d.ids)
 	if err != nil {
 		return nil, errors.Join(domain.ErrRepoSchema, err)
 	}
@@ -82,6 +98,39 @@ func (d *dynaURLRepo) Get(ctx context.Context, urlID string) (*domain.ShortURL,
 }
 
 func (d *dynaURLRepo) Delete(ctx context.Context, urlID string) error {
+	return d.setEnabled(ctx, urlID, false)
+}
+
+func (d *dynaURLRepo) Restore(ctx context.Context, urlID string) error {
+	return d.setEnabled(ctx, urlID, true)
+}
+
+func (d *dynaURLRepo) Purge(ctx context.Context, urlID string) error {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.writeTimeout)
+	defer cancelFunc()
+
+	_, err := d.client.DeleteItem(newCtx, &awsDynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"url_id": &types.AttributeValueMemberS{Value: urlID},
+		},
+		ConditionExpression: aws.String("attribute_exists(url_id)"),
+	})
+
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return domain.ErrURLNotFound
+	}
+
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+	return nil
+}
+
+// setEnabled is conditioned on existence, otherwise UpdateItem would upsert
+// a partial item
+func (d *dynaURLRepo) setEnabled(ctx context.Context, urlID string, enabled bool) error {
 	newCtx, cancelFunc := context.WithTimeout(ctx, d.writeTimeout)
 	defer cancelFunc()
 
@@ -90,22 +139,247 @@ func (d *dynaURLRepo) Delete(ctx context.Context, urlID string) error {
 		Key: map[string]types.AttributeValue{
 			"url_id": &types.AttributeValueMemberS{Value: urlID},
 		},
-		UpdateExpression: aws.String("SET enabled = :enabled"),
+		UpdateExpression:    aws.String("SET enabled = :enabled"),
+		ConditionExpression: aws.String("attribute_exists(url_id)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":enabled": &types.AttributeValueMemberBOOL{Value: enabled},
+		},
+	})
+
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return domain.ErrURLNotFound
+	}
+
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+	return nil
+}
+
+// Update conditions the write on the version read by the caller. Items written
+// before versioning existed have no version attribute and count as version 0.
+func (d *dynaURLRepo) Update(ctx context.Context, shortUrl domain.ShortURL, revision domain.Revision) error {
+	if err := validators.ValidateShortURL(shortUrl, d.ids); err != nil {
+		return err
+	}
+
+	revisionItem, err := attributevalue.Marshal(dtos.FromRevision(revision))
+	if err != nil {
+		return errors.Join(errors.New("cannot serialize revision"), err)
+	}
+
+	condition := "attribute_exists(url_id) AND #version = :expected"
+	if shortUrl.Version == 0 {
+		condition = "attribute_exists(url_id) AND (attribute_not_exists(#version) OR #version = :expected)"
+	}
+
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.writeTimeout)
+	defer cancelFunc()
+
+	_, err = d.client.UpdateItem(newCtx, &awsDynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]types.AttributeValue{
+			"url_id": &types.AttributeValueMemberS{Value: shortUrl.ID},
+		},
+		UpdateExpression:    aws.String("SET full_url = :full_url, enabled = :enabled, #version = :next, revisions = list_append(if_not_exists(revisions, :empty), :revision)"),
+		ConditionExpression: aws.String(condition),
+		ExpressionAttributeNames: map[string]string{
+			"#version": "version",
+		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":enabled": &types.AttributeValueMemberBOOL{Value: false},
+			":full_url": &types.AttributeValueMemberS{Value: shortUrl.Upstream.String()},
+			":enabled":  &types.AttributeValueMemberBOOL{Value: shortUrl.Enabled},
+			":expected": &types.AttributeValueMemberN{Value: strconv.FormatInt(shortUrl.Version, 10)},
+			":next":     &types.AttributeValueMemberN{Value: strconv.FormatInt(shortUrl.Version+1, 10)},
+			":empty":    &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+			":revision": &types.AttributeValueMemberL{Value: []types.AttributeValue{revisionItem}},
 		},
 	})
+
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return errors.Join(domain.ErrVersionConflict, err)
+	}
+
 	if err != nil {
 		return errors.Join(domain.ErrUnavailableRepo, err)
 	}
+
 	return nil
 }
 
-func NewDynamoURLRepository(cfg config.AppConfig, client clients.DynamoDbClient) domain.URLRepository {
+func (d *dynaURLRepo) Revisions(ctx context.Context, urlID string) ([]domain.Revision, error) {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.readTimeout)
+	defer cancelFunc()
+
+	itemResult, err := d.client.GetItem(newCtx, &awsDynamodb.GetItemInput{
+		TableName: &d.tableName,
+		Key: map[string]types.AttributeValue{
+			"url_id": &types.AttributeValueMemberS{Value: urlID},
+		},
+		ProjectionExpression: aws.String("url_id, revisions"),
+	})
+	if err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	if len(itemResult.Item) == 0 {
+		return nil, domain.ErrURLNotFound
+	}
+
+	itemModel := dtos.RevisionsItem{}
+	if err = attributevalue.UnmarshalMap(itemResult.Item, &itemModel); err != nil {
+		return nil, errors.Join(domain.ErrRepoSchema, err)
+	}
+
+	revisions := make([]domain.Revision, 0, len(itemModel.Revisions))
+	for _, revisionItem := range itemModel.Revisions {
+		revision, err := revisionItem.Domain()
+		if err != nil {
+			return nil, errors.Join(domain.ErrRepoSchema, err)
+		}
+		revisions = append(revisions, *revision)
+	}
+
+	return revisions, nil
+}
+
+// List queries the created_by index when filtering by author, otherwise it
+// falls back to a Scan. Remaining filters are applied on the read items.
+func (d *dynaURLRepo) List(ctx context.Context, filter domain.URLFilter, cursor string, limit int) (*domain.URLPage, error) {
+	startKey, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.URLPage{Items: []domain.ShortURL{}}
+	for pages := 0; pages < listMaxPages; pages++ {
+		items, lastKey, err := d.listPage(ctx, filter, startKey, int32(limit))
+		if err != nil {
+			return nil, err
+		}
+
+		for i, item := range items {
+			if !filter.Matches(item) {
+				continue
+			}
+
+			page.Items = append(page.Items, item)
+			if len(page.Items) == limit {
+				if i < len(items)-1 || len(lastKey) > 0 {
+					page.Cursor = encodeCursor(d.itemKey(filter, item))
+				}
+				return page, nil
+			}
+		}
+
+		if len(lastKey) == 0 {
+			return page, nil
+		}
+		startKey = lastKey
+	}
+
+	// REF: too sparse, hand back what we have and let the caller continue
+	page.Cursor = encodeCursor(startKey)
+	return page, nil
+}
+
+func (d *dynaURLRepo) listPage(ctx context.Context, filter domain.URLFilter, startKey map[string]string, limit int32) ([]domain.ShortURL, map[string]string, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, d.readTimeout)
+	defer cancelFunc()
+
+	var rawItems []map[string]types.AttributeValue
+	var lastKey map[string]types.AttributeValue
+
+	if filter.CreatedBy != "" {
+		output, err := d.client.Query(ctx, &awsDynamodb.QueryInput{
+			TableName:              aws.String(d.tableName),
+			IndexName:              aws.String(d.createdByIndex),
+			KeyConditionExpression: aws.String("created_by = :created_by"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":created_by": &types.AttributeValueMemberS{Value: filter.CreatedBy},
+			},
+			ExclusiveStartKey: toAttributeKey(startKey),
+			Limit:             aws.Int32(limit),
+		})
+		if err != nil {
+			return nil, nil, errors.Join(domain.ErrUnavailableRepo, err)
+		}
+		rawItems, lastKey = output.Items, output.LastEvaluatedKey
+	} else {
+		output, err := d.client.Scan(ctx, &awsDynamodb.ScanInput{
+			TableName:         aws.String(d.tableName),
+			ExclusiveStartKey: toAttributeKey(startKey),
+			Limit:             aws.Int32(limit),
+		})
+		if err != nil {
+			return nil, nil, errors.Join(domain.ErrUnavailableRepo, err)
+		}
+		rawItems, lastKey = output.Items, output.LastEvaluatedKey
+	}
+
+	itemModels := []dtos.URLItem{}
+	if err := attributevalue.UnmarshalListOfMaps(rawItems, &itemModels); err != nil {
+		return nil, nil, errors.Join(domain.ErrRepoSchema, err)
+	}
+
+	items := make([]domain.ShortURL, 0, len(itemModels))
+	for _, itemModel := range itemModels {
+		shortUrl, err := itemModel.Domain(d.ids)
+		if err != nil {
+			return nil, nil, errors.Join(domain.ErrRepoSchema, err)
+		}
+		items = append(items, *shortUrl)
+	}
+
+	return items, fromAttributeKey(lastKey), nil
+}
+
+// itemKey builds the key DynamoDB expects to resume right after item
+func (d *dynaURLRepo) itemKey(filter domain.URLFilter, item domain.ShortURL) map[string]string {
+	key := map[string]string{"url_id": item.ID}
+	if filter.CreatedBy != "" {
+		itemModel := dtos.FromDomain(item)
+		key["created_by"] = itemModel.Author
+		key["created_at"] = itemModel.Created
+	}
+
+	return key
+}
+
+// toAttributeKey and fromAttributeKey convert cursors, every key attribute is a string
+func toAttributeKey(key map[string]string) map[string]types.AttributeValue {
+	if len(key) == 0 {
+		return nil
+	}
+
+	result := make(map[string]types.AttributeValue, len(key))
+	for name, value := range key {
+		result[name] = &types.AttributeValueMemberS{Value: value}
+	}
+
+	return result
+}
+
+func fromAttributeKey(key map[string]types.AttributeValue) map[string]string {
+	result := make(map[string]string, len(key))
+	for name, value := range key {
+		if s, ok := value.(*types.AttributeValueMemberS); ok {
+			result[name] = s.Value
+		}
+	}
+
+	return result
+}
+
+func NewDynamoURLRepository(cfg config.AppConfig, ids validators.IdAlphabet, client clients.DynamoDbClient) domain.URLRepository {
 	return &dynaURLRepo{
-		tableName:    cfg.Dynamo.TableName,
-		client:       client,
-		readTimeout:  cfg.Dynamo.ReadTimeout,
-		writeTimeout: cfg.Dynamo.WriteTimeout,
+		tableName:      cfg.Dynamo.TableName,
+		createdByIndex: cfg.Dynamo.CreatedByIndex,
+		client:         client,
+		readTimeout:    cfg.Dynamo.ReadTimeout,
+		writeTimeout:   cfg.Dynamo.WriteTimeout,
+		ids:            ids,
 	}
 }
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"errors"
+	"strconv"
 	"testing"
 	"time"
 
@@ -12,7 +13,9 @@ import (
 	awsDynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
 	clientMock "github.com/neonmei/challenge_urlshortener/mocks/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/clients/dynamotest"
 	"github.com/neonmei/challenge_urlshortener/platform/config"
 	"github.com/neonmei/challenge_urlshortener/platform/repositories/dtos"
 	"github.com/stretchr/testify/assert"
@@ -23,7 +26,7 @@ func TestBackendErrorHandling(t *testing.T) {
 	cfg := config.Load()
 	ctx := context.Background()
 	dynamoClient := clientMock.NewMockDynamoDbClient(t)
-	repo := NewDynamoURLRepository(cfg, dynamoClient)
+	repo := NewDynamoURLRepository(cfg, validators.IdAlphabet{}, dynamoClient)
 	validItem := domain.ShortURL{
 		ID:        validId,
 		Upstream:  *validURL,
@@ -53,7 +56,7 @@ func TestBackendGetNotFound(t *testing.T) {
 	cfg := config.Load()
 	ctx := context.Background()
 	dynamoClient := clientMock.NewMockDynamoDbClient(t)
-	repo := NewDynamoURLRepository(cfg, dynamoClient)
+	repo := NewDynamoURLRepository(cfg, validators.IdAlphabet{}, dynamoClient)
 
 	// validItem := domain.ShortURL{
 	// 	ID:        validId,
@@ -77,7 +80,7 @@ func TestBackendGetFound(t *testing.T) {
 	cfg := config.Load()
 	ctx := context.Background()
 	dynamoClient := clientMock.NewMockDynamoDbClient(t)
-	repo := NewDynamoURLRepository(cfg, dynamoClient)
+	repo := NewDynamoURLRepository(cfg, validators.IdAlphabet{}, dynamoClient)
 
 	validItem := domain.ShortURL{
 		ID:        validId,
@@ -105,3 +108,273 @@ func TestBackendGetFound(t *testing.T) {
 	assert.Equal(t, validItem.CreatedBy, result.CreatedBy)
 	assert.Equal(t, validItem.Enabled, result.Enabled)
 }
+
+func TestBackendSaveConflict(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoURLRepository(cfg, validators.IdAlphabet{}, dynamoClient)
+
+	validItem := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}
+
+	dynamoClient.On("PutItem", mock.Anything, mock.Anything).Return(nil, &types.ConditionalCheckFailedException{})
+
+	err := repo.Save(ctx, validItem)
+	assert.ErrorIs(t, err, domain.ErrURLAlreadyExists)
+	assert.NotErrorIs(t, err, domain.ErrUnavailableRepo)
+}
+
+func TestBackendExpirationRoundtrip(t *testing.T) {
+	validItem := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	itemDto := dtos.FromDomain(validItem)
+	assert.Equal(t, validItem.ExpiresAt.Unix(), itemDto.TTL)
+
+	itemDynamo, err := attributevalue.MarshalMap(itemDto)
+	assert.NoError(t, err)
+	assert.Contains(t, itemDynamo, "ttl")
+
+	// REF: items without expiration must not carry a TTL attribute
+	noExpiry := validItem
+	noExpiry.ExpiresAt = time.Time{}
+	itemDynamo, err = attributevalue.MarshalMap(dtos.FromDomain(noExpiry))
+	assert.NoError(t, err)
+	assert.NotContains(t, itemDynamo, "ttl")
+	assert.NotContains(t, itemDynamo, "expires_at")
+
+	result, err := itemDto.Domain(validators.IdAlphabet{})
+	assert.NoError(t, err)
+	assert.Equal(t, validItem.ExpiresAt.Unix(), result.ExpiresAt.Unix())
+}
+
+func TestBackendListByAuthorUsesIndex(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoURLRepository(cfg, validators.IdAlphabet{}, dynamoClient)
+
+	items := []map[string]types.AttributeValue{}
+	for _, id := range []string{"a1", "a2", "a3"} {
+		item, err := attributevalue.MarshalMap(dtos.FromDomain(domain.ShortURL{
+			ID:        id,
+			Upstream:  *validURL,
+			CreatedBy: validAuthor,
+			CreatedAt: time.Now(),
+			Enabled:   id != "a2",
+		}))
+		assert.NoError(t, err)
+		items = append(items, item)
+	}
+
+	dynamoClient.On("Query", mock.Anything, mock.MatchedBy(func(in *awsDynamodb.QueryInput) bool {
+		return *in.IndexName == cfg.Dynamo.CreatedByIndex
+	})).Return(&awsDynamodb.QueryOutput{Items: items}, nil).Once()
+
+	// REF: a page of one, more items remain so a cursor is handed back
+	enabled := true
+	page, err := repo.List(ctx, domain.URLFilter{CreatedBy: validAuthor, Enabled: &enabled}, "", 1)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, "a1", page.Items[0].ID)
+	assert.NotEmpty(t, page.Cursor)
+
+	// REF: resuming uses the full index key of the last returned item
+	dynamoClient.On("Query", mock.Anything, mock.MatchedBy(func(in *awsDynamodb.QueryInput) bool {
+		urlID := in.ExclusiveStartKey["url_id"].(*types.AttributeValueMemberS).Value
+		createdBy := in.ExclusiveStartKey["created_by"].(*types.AttributeValueMemberS).Value
+		return urlID == "a1" && createdBy == validAuthor
+	})).Return(&awsDynamodb.QueryOutput{Items: items[1:]}, nil).Once()
+
+	page, err = repo.List(ctx, domain.URLFilter{CreatedBy: validAuthor, Enabled: &enabled}, page.Cursor, 1)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, "a3", page.Items[0].ID)
+	assert.Empty(t, page.Cursor)
+}
+
+func TestBackendListScan(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoURLRepository(cfg, validators.IdAlphabet{}, dynamoClient)
+
+	item, err := attributevalue.MarshalMap(dtos.FromDomain(domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}))
+	assert.NoError(t, err)
+
+	dynamoClient.On("Scan", mock.Anything, mock.Anything).Return(&awsDynamodb.ScanOutput{
+		Items:            []map[string]types.AttributeValue{item},
+		LastEvaluatedKey: map[string]types.AttributeValue{"url_id": &types.AttributeValueMemberS{Value: validId}},
+	}, nil).Once()
+
+	page, err := repo.List(ctx, domain.URLFilter{}, "", 1)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.NotEmpty(t, page.Cursor)
+
+	dynamoErr := errors.New("dynamo backend failed")
+	dynamoClient.On("Scan", mock.Anything, mock.Anything).Return(nil, dynamoErr)
+	_, err = repo.List(ctx, domain.URLFilter{}, page.Cursor, 1)
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+}
+
+func TestBackendUpdate(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoURLRepository(cfg, validators.IdAlphabet{}, dynamoClient)
+
+	validItem := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+		Version:   3,
+	}
+	revision := domain.Revision{Version: 3, Upstream: *validURL, ChangedBy: validAuthor, ChangedAt: time.Now()}
+
+	dynamoClient.On("UpdateItem", mock.Anything, mock.MatchedBy(func(in *awsDynamodb.UpdateItemInput) bool {
+		expected := in.ExpressionAttributeValues[":expected"].(*types.AttributeValueMemberN).Value
+		next := in.ExpressionAttributeValues[":next"].(*types.AttributeValueMemberN).Value
+		return expected == "3" && next == "4" && *in.ConditionExpression == "attribute_exists(url_id) AND #version = :expected"
+	})).Return(&awsDynamodb.UpdateItemOutput{}, nil).Once()
+	assert.NoError(t, repo.Update(ctx, validItem, revision))
+
+	dynamoClient.On("UpdateItem", mock.Anything, mock.Anything).Return(nil, &types.ConditionalCheckFailedException{}).Once()
+	assert.ErrorIs(t, repo.Update(ctx, validItem, revision), domain.ErrVersionConflict)
+}
+
+func TestBackendRevisions(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoURLRepository(cfg, validators.IdAlphabet{}, dynamoClient)
+
+	revision := domain.Revision{Version: 0, Upstream: *validURL, Enabled: true, ChangedBy: validAuthor, ChangedAt: time.Now()}
+	item, err := attributevalue.MarshalMap(dtos.RevisionsItem{
+		Id:        validId,
+		Revisions: []dtos.RevisionItem{dtos.FromRevision(revision)},
+	})
+	assert.NoError(t, err)
+
+	dynamoClient.On("GetItem", mock.Anything, mock.Anything).Return(&awsDynamodb.GetItemOutput{Item: item}, nil).Once()
+	revisions, err := repo.Revisions(ctx, validId)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+	assert.Equal(t, validURL.String(), revisions[0].Upstream.String())
+	assert.Equal(t, revision.ChangedAt.Unix(), revisions[0].ChangedAt.Unix())
+
+	dynamoClient.On("GetItem", mock.Anything, mock.Anything).Return(&awsDynamodb.GetItemOutput{}, nil).Once()
+	_, err = repo.Revisions(ctx, validId)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestBackendMissingItemOperations(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoURLRepository(cfg, validators.IdAlphabet{}, dynamoClient)
+
+	// REF: writes on missing items must not upsert partial items
+	dynamoClient.On("UpdateItem", mock.Anything, mock.MatchedBy(func(in *awsDynamodb.UpdateItemInput) bool {
+		return *in.ConditionExpression == "attribute_exists(url_id)"
+	})).Return(nil, &types.ConditionalCheckFailedException{})
+	dynamoClient.On("DeleteItem", mock.Anything, mock.MatchedBy(func(in *awsDynamodb.DeleteItemInput) bool {
+		return *in.ConditionExpression == "attribute_exists(url_id)"
+	})).Return(nil, &types.ConditionalCheckFailedException{})
+
+	assert.ErrorIs(t, repo.Delete(ctx, validId), domain.ErrURLNotFound)
+	assert.ErrorIs(t, repo.Restore(ctx, validId), domain.ErrURLNotFound)
+	assert.ErrorIs(t, repo.Purge(ctx, validId), domain.ErrURLNotFound)
+}
+
+func TestBackendRestoreAndPurge(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := clientMock.NewMockDynamoDbClient(t)
+	repo := NewDynamoURLRepository(cfg, validators.IdAlphabet{}, dynamoClient)
+
+	dynamoClient.On("UpdateItem", mock.Anything, mock.MatchedBy(func(in *awsDynamodb.UpdateItemInput) bool {
+		return in.ExpressionAttributeValues[":enabled"].(*types.AttributeValueMemberBOOL).Value
+	})).Return(&awsDynamodb.UpdateItemOutput{}, nil).Once()
+	dynamoClient.On("DeleteItem", mock.Anything, mock.Anything).Return(&awsDynamodb.DeleteItemOutput{}, nil).Once()
+
+	assert.NoError(t, repo.Restore(ctx, validId))
+	assert.NoError(t, repo.Purge(ctx, validId))
+}
+
+func TestBackendEndToEndLegacyItems(t *testing.T) {
+	cfg := config.Load()
+	ctx := context.Background()
+	dynamoClient := dynamotest.NewFake(dynamotest.AppTables(cfg)...)
+	repo := NewDynamoURLRepository(cfg, validators.IdAlphabet{}, dynamoClient)
+	createdAt := time.Now().UTC().Truncate(time.Second)
+
+	// REF: items written before versioning have no version attribute
+	_, err := dynamoClient.PutItem(ctx, &awsDynamodb.PutItemInput{
+		TableName: &cfg.Dynamo.TableName,
+		Item: map[string]types.AttributeValue{
+			"url_id":     &types.AttributeValueMemberS{Value: validId},
+			"created_at": &types.AttributeValueMemberS{Value: createdAt.Format(dtos.DynamoTimeFormat)},
+			"created_by": &types.AttributeValueMemberS{Value: validAuthor},
+			"enabled":    &types.AttributeValueMemberBOOL{Value: true},
+			"full_url":   &types.AttributeValueMemberS{Value: validURL.String()},
+		},
+	})
+	assert.NoError(t, err)
+
+	shortUrl, err := repo.Get(ctx, validId)
+	assert.NoError(t, err)
+	if !assert.NotNil(t, shortUrl) {
+		return
+	}
+	assert.Equal(t, int64(0), shortUrl.Version)
+
+	assert.NoError(t, repo.Update(ctx, *shortUrl, domain.Revision{
+		Upstream:  shortUrl.Upstream,
+		Enabled:   true,
+		ChangedBy: validAuthor,
+		ChangedAt: createdAt,
+	}))
+
+	shortUrl, err = repo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), shortUrl.Version)
+
+	// REF: expiring items carry the native TTL attribute
+	expiring := domain.ShortURL{
+		ID:        "expiring",
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(time.Hour),
+		Enabled:   true,
+	}
+	assert.NoError(t, repo.Save(ctx, expiring))
+
+	output, err := dynamoClient.GetItem(ctx, &awsDynamodb.GetItemInput{
+		TableName: &cfg.Dynamo.TableName,
+		Key:       map[string]types.AttributeValue{"url_id": &types.AttributeValueMemberS{Value: "expiring"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &types.AttributeValueMemberN{Value: strconv.FormatInt(expiring.ExpiresAt.Unix(), 10)}, output.Item["ttl"])
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/o11y/semconv"
+	"github.com/neonmei/challenge_urlshortener/platform/resilience"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// hedgedRepository fires a second Get when the first one is slower than most
+// recent ones, trading a few extra reads for a shorter tail. Every other
+// operation goes straight upstream
+type hedgedRepository struct {
+	domain.URLRepository
+	latencies *resilience.LatencyTracker
+	minDelay  time.Duration
+	maxDelay  time.Duration
+	fired     metric.Int64Counter
+	won       metric.Int64Counter
+}
+
+// delay is the observed percentile within bounds, the upper bound until
+// enough latencies were observed
+func (d *hedgedRepository) delay() time.Duration {
+	percentile, ok := d.latencies.Percentile()
+	if !ok {
+		return d.maxDelay
+	}
+
+	return min(max(percentile, d.minDelay), d.maxDelay)
+}
+
+func (d *hedgedRepository) Get(ctx context.Context, urlID string) (*domain.ShortURL, error) {
+	result, err, outcome := resilience.Hedge(ctx, d.delay(), isFinalRead, func(ctx context.Context) (*domain.ShortURL, error) {
+		start := time.Now()
+		result, err := d.URLRepository.Get(ctx, urlID)
+
+		// REF: cancelled losers would skew the percentile down
+		if ctx.Err() == nil && (err == nil || errors.Is(err, domain.ErrURLNotFound)) {
+			d.latencies.Observe(time.Since(start))
+		}
+
+		return result, err
+	})
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Bool(semconv.HedgeFired, outcome.Fired),
+		attribute.Bool(semconv.HedgeWon, outcome.Won),
+	)
+
+	if outcome.Fired {
+		d.fired.Add(ctx, 1)
+	}
+
+	if outcome.Won {
+		d.won.Add(ctx, 1)
+	}
+
+	return result, err
+}
+
+// isFinalRead is any answer but unavailability, which the other call may beat
+func isFinalRead(err error) bool {
+	return !errors.Is(err, domain.ErrUnavailableRepo)
+}
+
+// NewHedged decorates repo with hedged reads, it goes right above the
+// DynamoDB repository so the circuit breaker sees a hedged Get as one call
+func NewHedged(cfg config.AppConfig, repo domain.URLRepository) (domain.URLRepository, error) {
+	m := otel.GetMeterProvider().Meter("repositories")
+	fired, err := m.Int64Counter(
+		semconv.MetricHedgesFired,
+		metric.WithDescription("Number of reads that fired a second, hedged request."),
+		metric.WithUnit("{call}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	won, err := m.Int64Counter(
+		semconv.MetricHedgesWon,
+		metric.WithDescription("Number of hedged requests that answered before the original one."),
+		metric.WithUnit("{call}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hedgedRepository{
+		URLRepository: repo,
+		latencies:     resilience.NewLatencyTracker(cfg.Hedging.Percentile, cfg.Hedging.Window),
+		minDelay:      cfg.Hedging.MinDelay,
+		maxDelay:      cfg.Hedging.MaxDelay,
+		fired:         fired,
+		won:           won,
+	}, nil
+}
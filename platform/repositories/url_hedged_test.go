@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	mockDomain "github.com/neonmei/challenge_urlshortener/mocks/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func hedgingConfig() config.AppConfig {
+	cfg := config.Load()
+	cfg.Hedging.Window = 64
+	cfg.Hedging.MinDelay = time.Millisecond
+	cfg.Hedging.MaxDelay = 5 * time.Millisecond
+	return cfg
+}
+
+func TestHedgedSlowReadIsHedged(t *testing.T) {
+	ctx := context.Background()
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	repo, err := NewHedged(hedgingConfig(), upstreamRepo)
+	assert.NoError(t, err)
+
+	item := &domain.ShortURL{ID: validId}
+	upstreamRepo.On("Get", mock.Anything, validId).
+		Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+		Return(nil, domain.ErrUnavailableRepo).Once()
+	upstreamRepo.On("Get", mock.Anything, validId).Return(item, nil).Once()
+
+	start := time.Now()
+	result, err := repo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, item, result)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestHedgedNotFoundIsFinal(t *testing.T) {
+	ctx := context.Background()
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	repo, err := NewHedged(hedgingConfig(), upstreamRepo)
+	assert.NoError(t, err)
+
+	upstreamRepo.On("Get", mock.Anything, validId).Return(nil, domain.ErrURLNotFound).Once()
+	_, err = repo.Get(ctx, validId)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestHedgedDelayFollowsLatencies(t *testing.T) {
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	repo, err := NewHedged(hedgingConfig(), upstreamRepo)
+	assert.NoError(t, err)
+	hedged := repo.(*hedgedRepository)
+
+	// REF: without observations the upper bound is used
+	assert.Equal(t, 5*time.Millisecond, hedged.delay())
+
+	for range 64 {
+		hedged.latencies.Observe(3 * time.Millisecond)
+	}
+	assert.Equal(t, 3*time.Millisecond, hedged.delay())
+
+	for range 64 {
+		hedged.latencies.Observe(time.Microsecond)
+	}
+	assert.Equal(t, time.Millisecond, hedged.delay())
+}
+
+func TestHedgedWritesPassThrough(t *testing.T) {
+	ctx := context.Background()
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	repo, err := NewHedged(hedgingConfig(), upstreamRepo)
+	assert.NoError(t, err)
+
+	upstreamRepo.On("Delete", mock.Anything, validId).Return(nil).Once()
+	assert.NoError(t, repo.Delete(ctx, validId))
+}
@@ -2,30 +2,188 @@ package repositories
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/neonmei/challenge_urlshortener/domain"
 	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories/dtos"
 )
 
-type memoryRepo struct {
-	data map[string]domain.ShortURL
+// Operation names passed to fault injectors
+const (
+	OpGet       = "get"
+	OpSave      = "save"
+	OpDelete    = "delete"
+	OpRestore   = "restore"
+	OpPurge     = "purge"
+	OpList      = "list"
+	OpUpdate    = "update"
+	OpRevisions = "revisions"
+)
+
+// MemoryRepo is an in-memory repository with the same semantics as DynamoDB,
+// designed for troubleshooting, development and tests
+type MemoryRepo struct {
+	mu        sync.RWMutex
+	data      map[string]domain.ShortURL
+	revisions map[string][]domain.Revision
+
+	latency time.Duration
+	jitter  time.Duration
+	fault   func(op string) error
+	ids     validators.IdAlphabet
+}
+
+type MemoryOption func(*MemoryRepo)
+
+// WithIdAlphabet accepts identifiers of ids along with base62 ones
+func WithIdAlphabet(ids validators.IdAlphabet) MemoryOption {
+	return func(d *MemoryRepo) {
+		d.ids = ids
+	}
+}
+
+// WithLatency delays every operation by latency plus up to jitter
+func WithLatency(latency time.Duration, jitter time.Duration) MemoryOption {
+	return func(d *MemoryRepo) {
+		d.latency = latency
+		d.jitter = jitter
+	}
+}
+
+// WithFaults fails operations for which fault returns an error, before touching any data
+func WithFaults(fault func(op string) error) MemoryOption {
+	return func(d *MemoryRepo) {
+		d.fault = fault
+	}
+}
+
+// FailureRate is a fault injector failing a random fraction of every operation
+// with ErrUnavailableRepo, like a flaky network would
+func FailureRate(rate float64) func(op string) error {
+	return func(string) error {
+		if rand.Float64() < rate {
+			return domain.ErrUnavailableRepo
+		}
+
+		return nil
+	}
+}
+
+// inject applies the artificial latency and faults, honoring cancellation like
+// a networked repository would
+func (d *MemoryRepo) inject(ctx context.Context, op string) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	delay := d.latency
+	if d.jitter > 0 {
+		delay += rand.N(d.jitter)
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return errors.Join(domain.ErrUnavailableRepo, ctx.Err())
+		}
+	}
+
+	if d.fault != nil {
+		return d.fault(op)
+	}
+
+	return nil
 }
 
-func (d *memoryRepo) Delete(_ context.Context, urlID string) error {
+func (d *MemoryRepo) Delete(ctx context.Context, urlID string) error {
+	if err := d.inject(ctx, OpDelete); err != nil {
+		return err
+	}
+
+	return d.setEnabled(urlID, false)
+}
+
+func (d *MemoryRepo) Restore(ctx context.Context, urlID string) error {
+	if err := d.inject(ctx, OpRestore); err != nil {
+		return err
+	}
+
+	return d.setEnabled(urlID, true)
+}
+
+func (d *MemoryRepo) Purge(ctx context.Context, urlID string) error {
+	if err := d.inject(ctx, OpPurge); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, found := d.data[urlID]; !found {
+		return domain.ErrURLNotFound
+	}
+
 	delete(d.data, urlID)
+	delete(d.revisions, urlID)
 	return nil
 }
 
-func (d *memoryRepo) Save(_ context.Context, shortUrl domain.ShortURL) error {
-	if err := validators.ValidateShortURL(shortUrl); err != nil {
+func (d *MemoryRepo) setEnabled(urlID string, enabled bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	shortUrl, found := d.data[urlID]
+	if !found {
+		return domain.ErrURLNotFound
+	}
+
+	shortUrl.Enabled = enabled
+	d.data[urlID] = shortUrl
+	return nil
+}
+
+// Save rejects identifiers already taken, same as the DynamoDB conditional put
+func (d *MemoryRepo) Save(ctx context.Context, shortUrl domain.ShortURL) error {
+	if err := validators.ValidateShortURL(shortUrl, d.ids); err != nil {
 		return err
 	}
 
+	if err := d.inject(ctx, OpSave); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, found := d.data[shortUrl.ID]; found {
+		return domain.ErrURLAlreadyExists
+	}
+
 	d.data[shortUrl.ID] = shortUrl
 	return nil
 }
 
-func (d *memoryRepo) Get(_ context.Context, urlID string) (*domain.ShortURL, error) {
+func (d *MemoryRepo) Get(ctx context.Context, urlID string) (*domain.ShortURL, error) {
+	if err := d.inject(ctx, OpGet); err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
 	result, found := d.data[urlID]
 	if !found {
 		return nil, domain.ErrURLNotFound
@@ -34,7 +192,198 @@ func (d *memoryRepo) Get(_ context.Context, urlID string) (*domain.ShortURL, err
 	return &result, nil
 }
 
-// NewMemory is an in-memory repository designed for troubleshooting and development
-func NewMemory() domain.URLRepository {
-	return &memoryRepo{data: map[string]domain.ShortURL{}}
+// List walks items ordered by identifier, the cursor is the last one returned
+func (d *MemoryRepo) List(ctx context.Context, filter domain.URLFilter, cursor string, limit int) (*domain.URLPage, error) {
+	key, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.inject(ctx, OpList); err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ids := make([]string, 0, len(d.data))
+	for id := range d.data {
+		if id > key["url_id"] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	page := &domain.URLPage{Items: []domain.ShortURL{}}
+	for _, id := range ids {
+		if !filter.Matches(d.data[id]) {
+			continue
+		}
+
+		if len(page.Items) == limit {
+			page.Cursor = encodeCursor(map[string]string{"url_id": page.Items[limit-1].ID})
+			break
+		}
+		page.Items = append(page.Items, d.data[id])
+	}
+
+	return page, nil
+}
+
+func (d *MemoryRepo) Update(ctx context.Context, shortUrl domain.ShortURL, revision domain.Revision) error {
+	if err := validators.ValidateShortURL(shortUrl, d.ids); err != nil {
+		return err
+	}
+
+	if err := d.inject(ctx, OpUpdate); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current, found := d.data[shortUrl.ID]
+	if !found {
+		return domain.ErrURLNotFound
+	}
+
+	if current.Version != shortUrl.Version {
+		return domain.ErrVersionConflict
+	}
+
+	shortUrl.Version++
+	d.data[shortUrl.ID] = shortUrl
+	d.revisions[shortUrl.ID] = append(d.revisions[shortUrl.ID], revision)
+	return nil
+}
+
+func (d *MemoryRepo) Revisions(ctx context.Context, urlID string) ([]domain.Revision, error) {
+	if err := d.inject(ctx, OpRevisions); err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if _, found := d.data[urlID]; !found {
+		return nil, domain.ErrURLNotFound
+	}
+
+	return append([]domain.Revision{}, d.revisions[urlID]...), nil
+}
+
+// memorySnapshot is the JSON document written by Snapshot
+type memorySnapshot struct {
+	URLs      []dtos.URLRecord                 `json:"urls"`
+	Revisions map[string][]dtos.RevisionRecord `json:"revisions"`
+}
+
+// Snapshot writes every URL and its history as JSON
+func (d *MemoryRepo) Snapshot(w io.Writer) error {
+	d.mu.RLock()
+	snapshot := memorySnapshot{
+		URLs:      make([]dtos.URLRecord, 0, len(d.data)),
+		Revisions: map[string][]dtos.RevisionRecord{},
+	}
+
+	for _, shortUrl := range d.data {
+		snapshot.URLs = append(snapshot.URLs, dtos.FromDomainRecord(shortUrl))
+	}
+
+	for id, revisions := range d.revisions {
+		for _, revision := range revisions {
+			snapshot.Revisions[id] = append(snapshot.Revisions[id], dtos.FromRevisionRecord(revision))
+		}
+	}
+	d.mu.RUnlock()
+
+	sort.Slice(snapshot.URLs, func(i, j int) bool { return snapshot.URLs[i].Id < snapshot.URLs[j].Id })
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+// Load replaces the contents of the repository with a snapshot
+func (d *MemoryRepo) Load(r io.Reader) error {
+	snapshot := memorySnapshot{}
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return errors.Join(domain.ErrRepoSchema, err)
+	}
+
+	data := make(map[string]domain.ShortURL, len(snapshot.URLs))
+	for _, record := range snapshot.URLs {
+		shortUrl, err := record.Domain(d.ids)
+		if err != nil {
+			return errors.Join(domain.ErrRepoSchema, err)
+		}
+
+		data[shortUrl.ID] = *shortUrl
+	}
+
+	revisions := make(map[string][]domain.Revision, len(snapshot.Revisions))
+	for id, records := range snapshot.Revisions {
+		for _, record := range records {
+			revision, err := record.Domain()
+			if err != nil {
+				return errors.Join(domain.ErrRepoSchema, err)
+			}
+
+			revisions[id] = append(revisions[id], *revision)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.data = data
+	d.revisions = revisions
+	return nil
+}
+
+// SnapshotFile writes a snapshot to a temporary file renamed over path, so a
+// crash midway keeps the previous snapshot intact
+func (d *MemoryRepo) SnapshotFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := d.Snapshot(tmp); err != nil {
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile loads a snapshot written by SnapshotFile
+func (d *MemoryRepo) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return d.Load(f)
+}
+
+// NewMemory is an in-memory repository designed for troubleshooting, development and tests
+func NewMemory(opts ...MemoryOption) *MemoryRepo {
+	d := &MemoryRepo{
+		data:      map[string]domain.ShortURL{},
+		revisions: map[string][]domain.Revision{},
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
 }
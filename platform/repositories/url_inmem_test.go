@@ -2,6 +2,12 @@ package repositories
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -42,11 +48,257 @@ func TestInmemRepoBasic(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, validItem, *retrieved)
 
-	// REF: Deletion
+	// REF: Deletion is logical, same as DynamoDB
 	err = repo.Delete(ctx, validId)
 	assert.NoError(t, err)
 
+	retrieved, err = repo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.False(t, retrieved.Enabled)
+
+	// REF: Restore
+	assert.NoError(t, repo.Restore(ctx, validId))
+	retrieved, err = repo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.True(t, retrieved.Enabled)
+
+	// REF: Purge
+	assert.NoError(t, repo.Purge(ctx, validId))
 	retrieved, err = repo.Get(ctx, validId)
 	assert.Nil(t, retrieved)
 	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	assert.ErrorIs(t, repo.Delete(ctx, validId), domain.ErrURLNotFound)
+	assert.ErrorIs(t, repo.Restore(ctx, validId), domain.ErrURLNotFound)
+	assert.ErrorIs(t, repo.Purge(ctx, validId), domain.ErrURLNotFound)
+}
+
+func TestInmemRepoDuplicate(t *testing.T) {
+	repo := NewMemory()
+	ctx := context.Background()
+
+	validItem := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}
+
+	assert.NoError(t, repo.Save(ctx, validItem))
+	assert.ErrorIs(t, repo.Save(ctx, validItem), domain.ErrURLAlreadyExists)
+}
+
+func TestInmemRepoList(t *testing.T) {
+	repo := NewMemory()
+	ctx := context.Background()
+	otherURL, _ := url.Parse("https://go.dev/doc")
+
+	for _, id := range []string{"a1", "a2", "a3", "a4", "a5"} {
+		assert.NoError(t, repo.Save(ctx, domain.ShortURL{
+			ID:        id,
+			Upstream:  *validURL,
+			CreatedBy: validAuthor,
+			CreatedAt: time.Now(),
+			Enabled:   id != "a3",
+		}))
+	}
+	assert.NoError(t, repo.Save(ctx, domain.ShortURL{
+		ID:        "b1",
+		Upstream:  *otherURL,
+		CreatedBy: "other@neonmei.cloud",
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}))
+
+	// REF: walk every page following the cursor
+	seen := []string{}
+	cursor := ""
+	for {
+		page, err := repo.List(ctx, domain.URLFilter{}, cursor, 2)
+		assert.NoError(t, err)
+		for _, item := range page.Items {
+			seen = append(seen, item.ID)
+		}
+
+		if page.Cursor == "" {
+			break
+		}
+		cursor = page.Cursor
+	}
+	assert.Equal(t, []string{"a1", "a2", "a3", "a4", "a5", "b1"}, seen)
+
+	// REF: filters
+	enabled := true
+	page, err := repo.List(ctx, domain.URLFilter{CreatedBy: validAuthor, Enabled: &enabled}, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 4)
+	assert.Empty(t, page.Cursor)
+
+	page, err = repo.List(ctx, domain.URLFilter{UpstreamHost: "GO.dev"}, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, "b1", page.Items[0].ID)
+
+	page, err = repo.List(ctx, domain.URLFilter{CreatedAfter: time.Now().Add(time.Hour)}, "", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	_, err = repo.List(ctx, domain.URLFilter{}, "not a cursor!", 10)
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+}
+
+func TestInmemRepoUpdate(t *testing.T) {
+	repo := NewMemory()
+	ctx := context.Background()
+
+	validItem := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}
+	revision := domain.Revision{Upstream: *validURL, Enabled: true, ChangedBy: validAuthor, ChangedAt: time.Now()}
+
+	assert.ErrorIs(t, repo.Update(ctx, validItem, revision), domain.ErrURLNotFound)
+	assert.NoError(t, repo.Save(ctx, validItem))
+
+	updated := validItem
+	updated.Enabled = false
+	assert.NoError(t, repo.Update(ctx, updated, revision))
+
+	// REF: same expected version twice, second writer loses
+	assert.ErrorIs(t, repo.Update(ctx, updated, revision), domain.ErrVersionConflict)
+
+	retrieved, err := repo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), retrieved.Version)
+	assert.False(t, retrieved.Enabled)
+
+	revisions, err := repo.Revisions(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Revision{revision}, revisions)
+}
+
+func TestInmemRepoConcurrent(t *testing.T) {
+	repo := NewMemory()
+	ctx := context.Background()
+
+	// REF: meant for -race, every operation from many goroutines at once
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("c%d", i%4)
+
+			_ = repo.Save(ctx, domain.ShortURL{
+				ID:        id,
+				Upstream:  *validURL,
+				CreatedBy: validAuthor,
+				CreatedAt: time.Now(),
+				Enabled:   true,
+			})
+			_, _ = repo.Get(ctx, id)
+			_ = repo.Delete(ctx, id)
+			_ = repo.Restore(ctx, id)
+			_, _ = repo.List(ctx, domain.URLFilter{}, "", 10)
+			_, _ = repo.Revisions(ctx, id)
+		}(i)
+	}
+	wg.Wait()
+
+	page, err := repo.List(ctx, domain.URLFilter{}, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 4)
+}
+
+func TestInmemRepoSnapshot(t *testing.T) {
+	repo := NewMemory()
+	ctx := context.Background()
+
+	validItem := domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now().UTC(),
+		Enabled:   true,
+		ExpiresAt: time.Now().Add(time.Hour).UTC(),
+	}
+	revision := domain.Revision{Upstream: *validURL, Enabled: true, ChangedBy: validAuthor, ChangedAt: time.Now().UTC()}
+	assert.NoError(t, repo.Save(ctx, validItem))
+	assert.NoError(t, repo.Update(ctx, validItem, revision))
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	assert.NoError(t, repo.SnapshotFile(path))
+
+	// REF: loading replaces whatever was there
+	loaded := NewMemory()
+	assert.NoError(t, loaded.Save(ctx, domain.ShortURL{
+		ID:        "other",
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}))
+	assert.NoError(t, loaded.LoadFile(path))
+
+	_, err := loaded.Get(ctx, "other")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	retrieved, err := loaded.Get(ctx, validId)
+	assert.NoError(t, err)
+	validItem.Version = 1
+	assert.Equal(t, validItem, *retrieved)
+
+	revisions, err := loaded.Revisions(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Revision{revision}, revisions)
+
+	assert.ErrorIs(t, loaded.Load(strings.NewReader("not json")), domain.ErrRepoSchema)
+	assert.ErrorIs(t, loaded.LoadFile(filepath.Join(t.TempDir(), "missing.json")), os.ErrNotExist)
+}
+
+func TestInmemRepoInjection(t *testing.T) {
+	ctx := context.Background()
+
+	// REF: latency honors cancellation
+	slow := NewMemory(WithLatency(time.Hour, 0))
+	timeoutCtx, cancelFunc := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelFunc()
+
+	_, err := slow.Get(timeoutCtx, validId)
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	delayed := NewMemory(WithLatency(5*time.Millisecond, 5*time.Millisecond))
+	started := time.Now()
+	_, err = delayed.Get(ctx, validId)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	assert.GreaterOrEqual(t, time.Since(started), 5*time.Millisecond)
+
+	// REF: faults are chosen per operation and leave data untouched
+	failing := NewMemory(WithFaults(func(op string) error {
+		if op == OpSave {
+			return domain.ErrUnavailableRepo
+		}
+		return nil
+	}))
+	assert.ErrorIs(t, failing.Save(ctx, domain.ShortURL{
+		ID:        validId,
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}), domain.ErrUnavailableRepo)
+
+	_, err = failing.Get(ctx, validId)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	_, err = NewMemory(WithFaults(FailureRate(1))).Get(ctx, validId)
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+
+	_, err = NewMemory(WithFaults(FailureRate(0))).Get(ctx, validId)
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
 }
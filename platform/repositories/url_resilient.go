@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/resilience"
+)
+
+// resilientRepository guards an unreliable repository, usually DynamoDB,
+// with a circuit breaker. Reads are retried as they are idempotent, writes are
+// not as a timed out write may have been applied
+type resilientRepository struct {
+	upstream domain.URLRepository
+	breaker  *resilience.Breaker
+	retry    resilience.RetryConfig
+	// readBudget bounds a read including its retries, zero leaves it unbounded
+	readBudget time.Duration
+}
+
+// guard runs fn through the breaker. Only unavailability counts as a failure,
+// not found or conflicts mean the repository is healthy, and neither do callers
+// giving up. Failing fast keeps wrapping domain.ErrUnavailableRepo so callers
+// degrade the same way
+func (d *resilientRepository) guard(ctx context.Context, fn func() error) error {
+	ticket, err := d.breaker.Allow(ctx)
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	err = fn()
+	d.breaker.Done(ctx, ticket, errors.Is(err, domain.ErrUnavailableRepo) && ctx.Err() == nil)
+	return err
+}
+
+// read retries fn within the backend read timeout, so retries never make a read
+// slower than a single attempt was allowed to be. The breaker sees the caller
+// context, running out of budget is a backend failure and not a cancellation
+func (d *resilientRepository) read(ctx context.Context, fn func(ctx context.Context) error) error {
+	readCtx := ctx
+	if d.readBudget > 0 {
+		var cancelFunc context.CancelFunc
+		readCtx, cancelFunc = context.WithTimeout(ctx, d.readBudget)
+		defer cancelFunc()
+	}
+
+	return resilience.Retry(readCtx, d.retry, isRetryable, func() error {
+		return d.guard(ctx, func() error { return fn(readCtx) })
+	})
+}
+
+// isRetryable gives up once the circuit is open, retrying would fail fast anyway
+func isRetryable(err error) bool {
+	return errors.Is(err, domain.ErrUnavailableRepo) && !errors.Is(err, resilience.ErrCircuitOpen)
+}
+
+func (d *resilientRepository) Get(ctx context.Context, urlID string) (*domain.ShortURL, error) {
+	var result *domain.ShortURL
+	err := d.read(ctx, func(ctx context.Context) (err error) {
+		result, err = d.upstream.Get(ctx, urlID)
+		return err
+	})
+
+	return result, err
+}
+
+func (d *resilientRepository) List(ctx context.Context, filter domain.URLFilter, cursor string, limit int) (*domain.URLPage, error) {
+	var result *domain.URLPage
+	err := d.read(ctx, func(ctx context.Context) (err error) {
+		result, err = d.upstream.List(ctx, filter, cursor, limit)
+		return err
+	})
+
+	return result, err
+}
+
+func (d *resilientRepository) Revisions(ctx context.Context, urlID string) ([]domain.Revision, error) {
+	var result []domain.Revision
+	err := d.read(ctx, func(ctx context.Context) (err error) {
+		result, err = d.upstream.Revisions(ctx, urlID)
+		return err
+	})
+
+	return result, err
+}
+
+func (d *resilientRepository) Save(ctx context.Context, shortUrl domain.ShortURL) error {
+	return d.guard(ctx, func() error { return d.upstream.Save(ctx, shortUrl) })
+}
+
+func (d *resilientRepository) Update(ctx context.Context, shortUrl domain.ShortURL, revision domain.Revision) error {
+	return d.guard(ctx, func() error { return d.upstream.Update(ctx, shortUrl, revision) })
+}
+
+func (d *resilientRepository) Delete(ctx context.Context, urlID string) error {
+	return d.guard(ctx, func() error { return d.upstream.Delete(ctx, urlID) })
+}
+
+func (d *resilientRepository) Restore(ctx context.Context, urlID string) error {
+	return d.guard(ctx, func() error { return d.upstream.Restore(ctx, urlID) })
+}
+
+func (d *resilientRepository) Purge(ctx context.Context, urlID string) error {
+	return d.guard(ctx, func() error { return d.upstream.Purge(ctx, urlID) })
+}
+
+// NewResilient decorates repo with a circuit breaker and read retries. It
+// goes below NewCached, so the cache serves stale entries while the circuit
+// is open
+func NewResilient(cfg config.AppConfig, repo domain.URLRepository) (domain.URLRepository, error) {
+	breaker, err := resilience.NewBreaker("url_repository", resilience.BreakerConfig{
+		FailureThreshold:  cfg.Resilience.FailureThreshold,
+		OpenTimeout:       cfg.Resilience.OpenTimeout,
+		HalfOpenSuccesses: cfg.Resilience.HalfOpenSuccesses,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &resilientRepository{
+		upstream: repo,
+		breaker:  breaker,
+		retry: resilience.RetryConfig{
+			Retries:    cfg.Resilience.ReadRetries,
+			Backoff:    cfg.Resilience.RetryBackoff,
+			MaxBackoff: cfg.Resilience.RetryMaxBackoff,
+		},
+		readBudget: readBudget(cfg),
+	}, nil
+}
+
+// readBudget is the read timeout of the configured backend
+func readBudget(cfg config.AppConfig) time.Duration {
+	switch cfg.Storage.Backend {
+	case clients.BackendDynamo:
+		return cfg.Dynamo.ReadTimeout
+	case clients.BackendSQLite, clients.BackendPostgres:
+		return cfg.Sql.ReadTimeout
+	default:
+		return 0
+	}
+}
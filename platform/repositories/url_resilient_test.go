@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	mockDomain "github.com/neonmei/challenge_urlshortener/mocks/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/resilience"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func resilienceConfig() config.AppConfig {
+	cfg := config.Load()
+	cfg.Resilience.FailureThreshold = 3
+	cfg.Resilience.OpenTimeout = time.Minute
+	cfg.Resilience.ReadRetries = 1
+	cfg.Resilience.RetryBackoff = time.Millisecond
+	return cfg
+}
+
+func TestResilientRetriesReads(t *testing.T) {
+	ctx := context.Background()
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	repo, err := NewResilient(resilienceConfig(), upstreamRepo)
+	assert.NoError(t, err)
+
+	item := &domain.ShortURL{ID: validId}
+	upstreamRepo.On("Get", mock.Anything, validId).Return(nil, domain.ErrUnavailableRepo).Once()
+	upstreamRepo.On("Get", mock.Anything, validId).Return(item, nil).Once()
+
+	result, err := repo.Get(ctx, validId)
+	assert.NoError(t, err)
+	assert.Equal(t, item, result)
+
+	// REF: not found is an answer, not a failure
+	upstreamRepo.On("Get", mock.Anything, "other").Return(nil, domain.ErrURLNotFound).Once()
+	_, err = repo.Get(ctx, "other")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestResilientDoesNotRetryWrites(t *testing.T) {
+	ctx := context.Background()
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	repo, err := NewResilient(resilienceConfig(), upstreamRepo)
+	assert.NoError(t, err)
+
+	upstreamRepo.On("Save", mock.Anything, mock.Anything).Return(domain.ErrUnavailableRepo).Once()
+	assert.ErrorIs(t, repo.Save(ctx, domain.ShortURL{ID: validId}), domain.ErrUnavailableRepo)
+}
+
+func TestResilientFailsFastWhileOpen(t *testing.T) {
+	ctx := context.Background()
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	repo, err := NewResilient(resilienceConfig(), upstreamRepo)
+	assert.NoError(t, err)
+
+	// REF: three failures open the circuit, the retry of the second read never happens
+	upstreamRepo.On("Get", mock.Anything, validId).Return(nil, domain.ErrUnavailableRepo).Times(3)
+	for range 2 {
+		_, err := repo.Get(ctx, validId)
+		assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+	}
+
+	// REF: upstream is not reached, the mock would fail on a fourth call
+	_, err = repo.Get(ctx, validId)
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+	assert.ErrorIs(t, err, resilience.ErrCircuitOpen)
+	assert.ErrorIs(t, repo.Delete(ctx, validId), resilience.ErrCircuitOpen)
+}
+
+func TestResilientIgnoresCancellations(t *testing.T) {
+	upstreamRepo := NewMemory(WithLatency(time.Hour, 0))
+	repo, err := NewResilient(resilienceConfig(), upstreamRepo)
+	assert.NoError(t, err)
+
+	// REF: callers giving up say nothing about the repository health
+	cancelled, cancelFunc := context.WithCancel(context.Background())
+	cancelFunc()
+	for range 5 {
+		assert.ErrorIs(t, repo.Delete(cancelled, validId), context.Canceled)
+	}
+
+	assert.NotErrorIs(t, repo.Delete(cancelled, validId), resilience.ErrCircuitOpen)
+}
+
+func TestResilientBoundsReadRetries(t *testing.T) {
+	cfg := resilienceConfig()
+	cfg.Storage.Backend = clients.BackendDynamo
+	cfg.Dynamo.ReadTimeout = 50 * time.Millisecond
+	cfg.Resilience.ReadRetries = 5
+	upstreamRepo := mockDomain.NewMockURLRepository(t)
+	repo, err := NewResilient(cfg, upstreamRepo)
+	assert.NoError(t, err)
+
+	// REF: a backend timing out answers once the read budget is spent
+	upstreamRepo.On("Get", mock.Anything, validId).
+		Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+		Return(nil, domain.ErrUnavailableRepo).Once()
+
+	start := time.Now()
+	_, err = repo.Get(context.Background(), validId)
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+	assert.Less(t, time.Since(start), 4*cfg.Dynamo.ReadTimeout)
+}
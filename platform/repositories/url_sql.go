@@ -0,0 +1,369 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+)
+
+const urlColumns = "url_id, full_url, created_by, created_at, enabled, expires_at, version"
+
+type sqlURLRepo struct {
+	db           *sql.DB
+	dialect      sqlDialect
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	ids          validators.IdAlphabet
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanShortURL(row rowScanner, ids validators.IdAlphabet) (*domain.ShortURL, error) {
+	var (
+		shortUrl             domain.ShortURL
+		fullURL              string
+		createdAt, expiresAt int64
+	)
+
+	err := row.Scan(&shortUrl.ID, &fullURL, &shortUrl.CreatedBy, &createdAt, &shortUrl.Enabled, &expiresAt, &shortUrl.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(fullURL)
+	if err != nil {
+		return nil, errors.Join(domain.ErrRepoSchema, err)
+	}
+
+	shortUrl.Upstream = *u
+	shortUrl.CreatedAt = time.Unix(0, createdAt).UTC()
+	if expiresAt != 0 {
+		shortUrl.ExpiresAt = time.Unix(0, expiresAt).UTC()
+	}
+
+	if err := validators.ValidateShortURL(shortUrl, ids); err != nil {
+		return nil, errors.Join(domain.ErrRepoSchema, err)
+	}
+
+	return &shortUrl, nil
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
+
+// Save relies on ON CONFLICT DO NOTHING, so only the first of concurrent writers inserts
+func (d *sqlURLRepo) Save(ctx context.Context, shortUrl domain.ShortURL) error {
+	if err := validators.ValidateShortURL(shortUrl, d.ids); err != nil {
+		return err
+	}
+
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.writeTimeout)
+	defer cancelFunc()
+
+	result, err := d.db.ExecContext(newCtx, d.dialect.rebind(
+		"INSERT INTO urls ("+urlColumns+") VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (url_id) DO NOTHING"),
+		shortUrl.ID, shortUrl.Upstream.String(), shortUrl.CreatedBy, shortUrl.CreatedAt.UnixNano(),
+		shortUrl.Enabled, unixNano(shortUrl.ExpiresAt), shortUrl.Version,
+	)
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	if inserted == 0 {
+		return domain.ErrURLAlreadyExists
+	}
+
+	return nil
+}
+
+func (d *sqlURLRepo) Get(ctx context.Context, urlID string) (*domain.ShortURL, error) {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.readTimeout)
+	defer cancelFunc()
+
+	row := d.db.QueryRowContext(newCtx, d.dialect.rebind("SELECT "+urlColumns+" FROM urls WHERE url_id = ?"), urlID)
+	shortUrl, err := scanShortURL(row, d.ids)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
+
+	if errors.Is(err, domain.ErrRepoSchema) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return shortUrl, nil
+}
+
+func (d *sqlURLRepo) Delete(ctx context.Context, urlID string) error {
+	return d.setEnabled(ctx, urlID, false)
+}
+
+func (d *sqlURLRepo) Restore(ctx context.Context, urlID string) error {
+	return d.setEnabled(ctx, urlID, true)
+}
+
+func (d *sqlURLRepo) setEnabled(ctx context.Context, urlID string, enabled bool) error {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.writeTimeout)
+	defer cancelFunc()
+
+	result, err := d.db.ExecContext(newCtx, d.dialect.rebind("UPDATE urls SET enabled = ? WHERE url_id = ?"), enabled, urlID)
+	return affectedOne(result, err)
+}
+
+func (d *sqlURLRepo) Purge(ctx context.Context, urlID string) error {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.writeTimeout)
+	defer cancelFunc()
+
+	tx, err := d.db.BeginTx(newCtx, nil)
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(newCtx, d.dialect.rebind("DELETE FROM url_revisions WHERE url_id = ?"), urlID); err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	result, err := tx.ExecContext(newCtx, d.dialect.rebind("DELETE FROM urls WHERE url_id = ?"), urlID)
+	if err := affectedOne(result, err); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return nil
+}
+
+// affectedOne maps a statement touching no rows to ErrURLNotFound
+func affectedOne(result sql.Result, err error) error {
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	if affected == 0 {
+		return domain.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// List pushes every filter but the upstream host down to the database, which
+// is matched while walking the rows ordered by identifier
+func (d *sqlURLRepo) List(ctx context.Context, filter domain.URLFilter, cursor string, limit int) (*domain.URLPage, error) {
+	key, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{"url_id > ?"}
+	args := []any{key["url_id"]}
+
+	if filter.CreatedBy != "" {
+		conditions = append(conditions, "created_by = ?")
+		args = append(args, filter.CreatedBy)
+	}
+
+	if filter.Enabled != nil {
+		conditions = append(conditions, "enabled = ?")
+		args = append(args, *filter.Enabled)
+	}
+
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.CreatedAfter.UnixNano())
+	}
+
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, filter.CreatedBefore.UnixNano())
+	}
+
+	query := "SELECT " + urlColumns + " FROM urls WHERE " + strings.Join(conditions, " AND ") + " ORDER BY url_id"
+	if filter.UpstreamHost == "" {
+		// REF: one extra row tells whether there is a next page
+		query += " LIMIT ?"
+		args = append(args, limit+1)
+	}
+
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.readTimeout)
+	defer cancelFunc()
+
+	rows, err := d.db.QueryContext(newCtx, d.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+	defer rows.Close()
+
+	page := &domain.URLPage{Items: []domain.ShortURL{}}
+	for rows.Next() {
+		shortUrl, err := scanShortURL(rows, d.ids)
+		if err != nil {
+			return nil, err
+		}
+
+		if !filter.Matches(*shortUrl) {
+			continue
+		}
+
+		if len(page.Items) == limit {
+			page.Cursor = encodeCursor(map[string]string{"url_id": page.Items[limit-1].ID})
+			break
+		}
+		page.Items = append(page.Items, *shortUrl)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return page, nil
+}
+
+func (d *sqlURLRepo) Update(ctx context.Context, shortUrl domain.ShortURL, revision domain.Revision) error {
+	if err := validators.ValidateShortURL(shortUrl, d.ids); err != nil {
+		return err
+	}
+
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.writeTimeout)
+	defer cancelFunc()
+
+	tx, err := d.db.BeginTx(newCtx, nil)
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(newCtx, d.dialect.rebind(
+		"UPDATE urls SET full_url = ?, enabled = ?, version = ? WHERE url_id = ? AND version = ?"),
+		shortUrl.Upstream.String(), shortUrl.Enabled, shortUrl.Version+1, shortUrl.ID, shortUrl.Version,
+	)
+	if err := affectedOne(result, err); err != nil {
+		if !errors.Is(err, domain.ErrURLNotFound) {
+			return err
+		}
+
+		// REF: nothing matched, either the URL is gone or somebody else updated it first
+		var exists int
+		err := tx.QueryRowContext(newCtx, d.dialect.rebind("SELECT 1 FROM urls WHERE url_id = ?"), shortUrl.ID).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrURLNotFound
+		}
+
+		if err != nil {
+			return errors.Join(domain.ErrUnavailableRepo, err)
+		}
+
+		return domain.ErrVersionConflict
+	}
+
+	_, err = tx.ExecContext(newCtx, d.dialect.rebind(
+		"INSERT INTO url_revisions (url_id, version, full_url, enabled, changed_by, changed_at) VALUES (?, ?, ?, ?, ?, ?)"),
+		shortUrl.ID, revision.Version, revision.Upstream.String(), revision.Enabled, revision.ChangedBy, revision.ChangedAt.UnixNano(),
+	)
+	if err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return nil
+}
+
+func (d *sqlURLRepo) Revisions(ctx context.Context, urlID string) ([]domain.Revision, error) {
+	newCtx, cancelFunc := context.WithTimeout(ctx, d.readTimeout)
+	defer cancelFunc()
+
+	var exists int
+	err := d.db.QueryRowContext(newCtx, d.dialect.rebind("SELECT 1 FROM urls WHERE url_id = ?"), urlID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	rows, err := d.db.QueryContext(newCtx, d.dialect.rebind(
+		"SELECT version, full_url, enabled, changed_by, changed_at FROM url_revisions WHERE url_id = ? ORDER BY version"), urlID)
+	if err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+	defer rows.Close()
+
+	result := []domain.Revision{}
+	for rows.Next() {
+		var (
+			revision  domain.Revision
+			fullURL   string
+			changedAt int64
+		)
+
+		if err := rows.Scan(&revision.Version, &fullURL, &revision.Enabled, &revision.ChangedBy, &changedAt); err != nil {
+			return nil, errors.Join(domain.ErrRepoSchema, err)
+		}
+
+		u, err := url.Parse(fullURL)
+		if err != nil {
+			return nil, errors.Join(domain.ErrRepoSchema, err)
+		}
+
+		revision.Upstream = *u
+		revision.ChangedAt = time.Unix(0, changedAt).UTC()
+		result = append(result, revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(domain.ErrUnavailableRepo, err)
+	}
+
+	return result, nil
+}
+
+// NewSQLURLRepository stores URLs in SQLite or PostgreSQL, according to
+// cfg.Storage.Backend. The schema must be created beforehand with MigrateSQL
+func NewSQLURLRepository(cfg config.AppConfig, ids validators.IdAlphabet, db *sql.DB) (domain.URLRepository, error) {
+	dialect, err := newSQLDialect(cfg.Storage.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlURLRepo{
+		db:           db,
+		dialect:      dialect,
+		readTimeout:  cfg.Sql.ReadTimeout,
+		writeTimeout: cfg.Sql.WriteTimeout,
+		ids:          ids,
+	}, nil
+}
@@ -0,0 +1,306 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// sqlBackends yields a migrated SQLite database, plus PostgreSQL when
+// SHORTENER_TEST_POSTGRES_DSN points to a disposable database
+func sqlBackends(t *testing.T) map[string]config.AppConfig {
+	t.Helper()
+
+	sqlite := config.AppConfig{}
+	sqlite.Storage.Backend = clients.BackendSQLite
+	sqlite.Sql.Dsn = filepath.Join(t.TempDir(), "shortener.db")
+
+	backends := map[string]config.AppConfig{clients.BackendSQLite: sqlite}
+	if dsn := os.Getenv("SHORTENER_TEST_POSTGRES_DSN"); dsn != "" {
+		postgres := config.AppConfig{}
+		postgres.Storage.Backend = clients.BackendPostgres
+		postgres.Sql.Dsn = dsn
+		postgres.Sql.MaxOpenConns = 4
+		backends[clients.BackendPostgres] = postgres
+	}
+
+	for name, cfg := range backends {
+		cfg.Sql.ReadTimeout = time.Second
+		cfg.Sql.WriteTimeout = time.Second
+		backends[name] = cfg
+	}
+
+	return backends
+}
+
+func openTestSQL(t *testing.T, cfg config.AppConfig) *sql.DB {
+	t.Helper()
+
+	db, err := clients.NewSQLClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if cfg.Storage.Backend == clients.BackendPostgres {
+		if _, err := db.Exec("DROP TABLE IF EXISTS urls, url_revisions, url_hits, url_visitors, sequences, schema_migrations"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := MigrateSQL(context.Background(), cfg.Storage.Backend, db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSQLRepoBasic(t *testing.T) {
+	for name, cfg := range sqlBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := NewSQLURLRepository(cfg, validators.IdAlphabet{}, openTestSQL(t, cfg))
+			assert.NoError(t, err)
+			ctx := context.Background()
+
+			validItem := domain.ShortURL{
+				ID:        validId,
+				Upstream:  *validURL,
+				CreatedBy: validAuthor,
+				CreatedAt: time.Now().UTC(),
+				Enabled:   true,
+				ExpiresAt: time.Now().Add(time.Hour).UTC(),
+			}
+
+			// REF: Save-time validation
+			assert.Error(t, repo.Save(ctx, domain.ShortURL{
+				ID:        validId,
+				Upstream:  *URLBadSchema,
+				CreatedBy: validAuthor,
+				CreatedAt: time.Now(),
+				Enabled:   true,
+			}))
+
+			retrieved, err := repo.Get(ctx, validId)
+			assert.Nil(t, retrieved)
+			assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+			// REF: Existance, times round trip with nanosecond precision
+			assert.NoError(t, repo.Save(ctx, validItem))
+			retrieved, err = repo.Get(ctx, validId)
+			assert.NoError(t, err)
+			assert.Equal(t, validItem, *retrieved)
+
+			// REF: Deletion is logical, same as DynamoDB
+			assert.NoError(t, repo.Delete(ctx, validId))
+			retrieved, err = repo.Get(ctx, validId)
+			assert.NoError(t, err)
+			assert.False(t, retrieved.Enabled)
+
+			// REF: Restore
+			assert.NoError(t, repo.Restore(ctx, validId))
+			retrieved, err = repo.Get(ctx, validId)
+			assert.NoError(t, err)
+			assert.True(t, retrieved.Enabled)
+
+			// REF: Purge
+			assert.NoError(t, repo.Purge(ctx, validId))
+			retrieved, err = repo.Get(ctx, validId)
+			assert.Nil(t, retrieved)
+			assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+			assert.ErrorIs(t, repo.Delete(ctx, validId), domain.ErrURLNotFound)
+			assert.ErrorIs(t, repo.Restore(ctx, validId), domain.ErrURLNotFound)
+			assert.ErrorIs(t, repo.Purge(ctx, validId), domain.ErrURLNotFound)
+		})
+	}
+}
+
+func TestSQLRepoInsertIfAbsent(t *testing.T) {
+	for name, cfg := range sqlBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := NewSQLURLRepository(cfg, validators.IdAlphabet{}, openTestSQL(t, cfg))
+			assert.NoError(t, err)
+			ctx := context.Background()
+
+			// REF: only one of many concurrent writers of the same id wins
+			var (
+				wg       sync.WaitGroup
+				mu       sync.Mutex
+				inserted int
+			)
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := repo.Save(ctx, domain.ShortURL{
+						ID:        validId,
+						Upstream:  *validURL,
+						CreatedBy: validAuthor,
+						CreatedAt: time.Now(),
+						Enabled:   true,
+					})
+
+					if err != nil {
+						assert.ErrorIs(t, err, domain.ErrURLAlreadyExists)
+						return
+					}
+
+					mu.Lock()
+					defer mu.Unlock()
+					inserted++
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, 1, inserted)
+		})
+	}
+}
+
+func TestSQLRepoList(t *testing.T) {
+	for name, cfg := range sqlBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := NewSQLURLRepository(cfg, validators.IdAlphabet{}, openTestSQL(t, cfg))
+			assert.NoError(t, err)
+			ctx := context.Background()
+			otherURL, _ := url.Parse("https://go.dev/doc")
+
+			for _, id := range []string{"a1", "a2", "a3", "a4", "a5"} {
+				assert.NoError(t, repo.Save(ctx, domain.ShortURL{
+					ID:        id,
+					Upstream:  *validURL,
+					CreatedBy: validAuthor,
+					CreatedAt: time.Now(),
+					Enabled:   id != "a3",
+				}))
+			}
+			assert.NoError(t, repo.Save(ctx, domain.ShortURL{
+				ID:        "b1",
+				Upstream:  *otherURL,
+				CreatedBy: "other@neonmei.cloud",
+				CreatedAt: time.Now(),
+				Enabled:   true,
+			}))
+
+			// REF: walk every page following the cursor
+			seen := []string{}
+			cursor := ""
+			for {
+				page, err := repo.List(ctx, domain.URLFilter{}, cursor, 2)
+				assert.NoError(t, err)
+				for _, item := range page.Items {
+					seen = append(seen, item.ID)
+				}
+
+				if page.Cursor == "" {
+					break
+				}
+				cursor = page.Cursor
+			}
+			assert.Equal(t, []string{"a1", "a2", "a3", "a4", "a5", "b1"}, seen)
+
+			// REF: filters
+			disabled := false
+			page, err := repo.List(ctx, domain.URLFilter{Enabled: &disabled}, "", 10)
+			assert.NoError(t, err)
+			assert.Len(t, page.Items, 1)
+			assert.Equal(t, "a3", page.Items[0].ID)
+
+			page, err = repo.List(ctx, domain.URLFilter{CreatedBy: "other@neonmei.cloud"}, "", 10)
+			assert.NoError(t, err)
+			assert.Len(t, page.Items, 1)
+
+			page, err = repo.List(ctx, domain.URLFilter{UpstreamHost: "GO.dev"}, "", 10)
+			assert.NoError(t, err)
+			assert.Len(t, page.Items, 1)
+			assert.Empty(t, page.Cursor)
+
+			page, err = repo.List(ctx, domain.URLFilter{CreatedAfter: time.Now().Add(time.Hour)}, "", 10)
+			assert.NoError(t, err)
+			assert.Empty(t, page.Items)
+
+			_, err = repo.List(ctx, domain.URLFilter{}, "not a cursor", 10)
+			assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+		})
+	}
+}
+
+func TestSQLRepoUpdate(t *testing.T) {
+	for name, cfg := range sqlBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo, err := NewSQLURLRepository(cfg, validators.IdAlphabet{}, openTestSQL(t, cfg))
+			assert.NoError(t, err)
+			ctx := context.Background()
+			otherURL, _ := url.Parse("https://go.dev/doc")
+
+			item := domain.ShortURL{
+				ID:        validId,
+				Upstream:  *validURL,
+				CreatedBy: validAuthor,
+				CreatedAt: time.Now(),
+				Enabled:   true,
+			}
+			assert.NoError(t, repo.Save(ctx, item))
+
+			revision := domain.Revision{
+				Version:   0,
+				Upstream:  *validURL,
+				Enabled:   true,
+				ChangedBy: validAuthor,
+				ChangedAt: time.Now().UTC(),
+			}
+			item.Upstream = *otherURL
+			assert.NoError(t, repo.Update(ctx, item, revision))
+
+			retrieved, err := repo.Get(ctx, validId)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), retrieved.Version)
+			assert.Equal(t, otherURL.String(), retrieved.Upstream.String())
+
+			// REF: a stale version loses
+			assert.ErrorIs(t, repo.Update(ctx, item, revision), domain.ErrVersionConflict)
+
+			revisions, err := repo.Revisions(ctx, validId)
+			assert.NoError(t, err)
+			assert.Equal(t, []domain.Revision{revision}, revisions)
+
+			item.ID = "missing"
+			assert.ErrorIs(t, repo.Update(ctx, item, revision), domain.ErrURLNotFound)
+			_, err = repo.Revisions(ctx, "missing")
+			assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+			// REF: Purge drops the history too
+			assert.NoError(t, repo.Purge(ctx, validId))
+			item.ID = validId
+			item.Version = 0
+			assert.NoError(t, repo.Save(ctx, item))
+			revisions, err = repo.Revisions(ctx, validId)
+			assert.NoError(t, err)
+			assert.Empty(t, revisions)
+		})
+	}
+}
+
+func TestSQLRepoUnavailable(t *testing.T) {
+	cfg := sqlBackends(t)[clients.BackendSQLite]
+	db := openTestSQL(t, cfg)
+	repo, err := NewSQLURLRepository(cfg, validators.IdAlphabet{}, db)
+	assert.NoError(t, err)
+	db.Close()
+
+	_, err = repo.Get(context.Background(), validId)
+	assert.ErrorIs(t, err, domain.ErrUnavailableRepo)
+
+	_, err = NewSQLURLRepository(config.AppConfig{}, validators.IdAlphabet{}, db)
+	assert.ErrorIs(t, err, domain.ErrUnknownBackend)
+}
@@ -0,0 +1,200 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/platform/o11y/semconv"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half_open"
+	case StateOpen:
+		return "open"
+	}
+
+	return "unknown"
+}
+
+type BreakerConfig struct {
+	// FailureThreshold is how many consecutive failures open the circuit
+	FailureThreshold int
+
+	// OpenTimeout is how long calls fail fast before probing again
+	OpenTimeout time.Duration
+
+	// HalfOpenSuccesses is how many probes must succeed to close the circuit,
+	// it also bounds how many probes run at once
+	HalfOpenSuccesses int
+}
+
+// Breaker is a consecutive failures circuit breaker. While open every call
+// fails fast, after OpenTimeout a few probe calls decide whether it closes or
+// opens again
+type Breaker struct {
+	mu          sync.Mutex
+	name        string
+	cfg         BreakerConfig
+	state       State
+	failures    int
+	successes   int
+	probes      int
+	generation  uint64
+	openedAt    time.Time
+	now         func() time.Time
+	transitions metric.Int64Counter
+}
+
+// Ticket is a call admitted by Allow, it tells Done whether the call took a
+// probe slot and which state it was admitted in
+type Ticket struct {
+	probe      bool
+	generation uint64
+}
+
+// Allow reserves a call, whose ticket must be reported with Done. It returns
+// ErrCircuitOpen while open or when every probe slot is taken
+func (b *Breaker) Allow(ctx context.Context) (Ticket, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.transition(ctx, StateHalfOpen)
+	}
+
+	ticket := Ticket{generation: b.generation}
+	switch b.state {
+	case StateOpen:
+		return Ticket{}, ErrCircuitOpen
+	case StateHalfOpen:
+		if b.probes >= b.cfg.HalfOpenSuccesses {
+			return Ticket{}, ErrCircuitOpen
+		}
+		b.probes++
+		ticket.probe = true
+	}
+
+	return ticket, nil
+}
+
+// Done reports the outcome of a call reserved with Allow
+func (b *Breaker) Done(ctx context.Context, ticket Ticket, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// REF: calls admitted before the last transition report on a state that is
+	// gone, and their probe slot was already released with it
+	if ticket.generation != b.generation {
+		return
+	}
+
+	if ticket.probe {
+		b.probes--
+	}
+
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.transition(ctx, StateOpen)
+		}
+	case StateHalfOpen:
+		if failed {
+			b.transition(ctx, StateOpen)
+			return
+		}
+
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenSuccesses {
+			b.transition(ctx, StateClosed)
+		}
+	}
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// transition must be called holding the lock
+func (b *Breaker) transition(ctx context.Context, to State) {
+	attrs := []attribute.KeyValue{
+		attribute.String(semconv.BreakerName, b.name),
+		attribute.String(semconv.BreakerFrom, b.state.String()),
+		attribute.String(semconv.BreakerTo, to.String()),
+	}
+
+	b.transitions.Add(ctx, 1, metric.WithAttributes(attrs...))
+	trace.SpanFromContext(ctx).AddEvent("circuit_breaker.transition", trace.WithAttributes(attrs...))
+
+	b.state = to
+	b.generation++
+	b.failures, b.successes, b.probes = 0, 0, 0
+	if to == StateOpen {
+		b.openedAt = b.now()
+	}
+}
+
+func NewBreaker(name string, cfg BreakerConfig) (*Breaker, error) {
+	if cfg.FailureThreshold < 1 || cfg.HalfOpenSuccesses < 1 {
+		return nil, errors.New("breaker thresholds must be positive")
+	}
+
+	m := otel.GetMeterProvider().Meter("resilience")
+	transitions, err := m.Int64Counter(
+		semconv.MetricBreakerTransitions,
+		metric.WithDescription("Number of circuit breaker state transitions."),
+		metric.WithUnit("{transition}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Breaker{
+		name:        name,
+		cfg:         cfg,
+		now:         time.Now,
+		transitions: transitions,
+	}
+
+	_, err = m.Int64ObservableGauge(
+		semconv.MetricBreakerState,
+		metric.WithDescription("Circuit breaker state: 0 closed, 1 half open, 2 open."),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(b.State()), metric.WithAttributes(attribute.String(semconv.BreakerName, name)))
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
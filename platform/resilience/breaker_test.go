@@ -0,0 +1,125 @@
+package resilience
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBreaker(t *testing.T) (*Breaker, *time.Time) {
+	b, err := NewBreaker("test", BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Second, HalfOpenSuccesses: 2})
+	assert.NoError(t, err)
+
+	now := time.Date(2025, 2, 5, 0, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func call(ctx context.Context, b *Breaker, failed bool) error {
+	ticket, err := b.Allow(ctx)
+	if err != nil {
+		return err
+	}
+
+	b.Done(ctx, ticket, failed)
+	return nil
+}
+
+func TestBreakerOpensOnConsecutiveFailures(t *testing.T) {
+	ctx := context.Background()
+	b, _ := newTestBreaker(t)
+
+	// REF: successes reset the count
+	assert.NoError(t, call(ctx, b, true))
+	assert.NoError(t, call(ctx, b, true))
+	assert.NoError(t, call(ctx, b, false))
+	assert.NoError(t, call(ctx, b, true))
+	assert.NoError(t, call(ctx, b, true))
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.NoError(t, call(ctx, b, true))
+	assert.Equal(t, StateOpen, b.State())
+	assert.ErrorIs(t, call(ctx, b, false), ErrCircuitOpen)
+}
+
+func TestBreakerHalfOpenProbes(t *testing.T) {
+	ctx := context.Background()
+	b, now := newTestBreaker(t)
+	for range 3 {
+		assert.NoError(t, call(ctx, b, true))
+	}
+
+	*now = now.Add(time.Second)
+
+	// REF: only as many probes as successes required run at once
+	first, err := b.Allow(ctx)
+	assert.NoError(t, err)
+	second, err := b.Allow(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StateHalfOpen, b.State())
+	_, err = b.Allow(ctx)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	b.Done(ctx, first, false)
+	b.Done(ctx, second, false)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreakerStaleCallsKeepProbeSlots(t *testing.T) {
+	ctx := context.Background()
+	b, now := newTestBreaker(t)
+
+	// REF: a slow call admitted while closed outlives the circuit opening
+	slow, err := b.Allow(ctx)
+	assert.NoError(t, err)
+	for range 3 {
+		assert.NoError(t, call(ctx, b, true))
+	}
+
+	*now = now.Add(time.Second)
+	probe, err := b.Allow(ctx)
+	assert.NoError(t, err)
+
+	// REF: it is not a probe, it neither frees a slot nor counts as a success
+	b.Done(ctx, slow, false)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	other, err := b.Allow(ctx)
+	assert.NoError(t, err)
+	_, err = b.Allow(ctx)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// REF: probes of a previous half open round do not free slots of the next
+	b.Done(ctx, probe, true)
+	*now = now.Add(time.Second)
+	_, err = b.Allow(ctx)
+	assert.NoError(t, err)
+	b.Done(ctx, other, false)
+	_, err = b.Allow(ctx)
+	assert.NoError(t, err)
+	_, err = b.Allow(ctx)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	ctx := context.Background()
+	b, now := newTestBreaker(t)
+	for range 3 {
+		assert.NoError(t, call(ctx, b, true))
+	}
+
+	*now = now.Add(time.Second)
+	assert.NoError(t, call(ctx, b, true))
+	assert.Equal(t, StateOpen, b.State())
+
+	// REF: the open timeout starts over
+	*now = now.Add(time.Second / 2)
+	assert.ErrorIs(t, call(ctx, b, false), ErrCircuitOpen)
+}
+
+func TestBreakerRejectsBadConfig(t *testing.T) {
+	_, err := NewBreaker("test", BreakerConfig{})
+	assert.Error(t, err)
+}
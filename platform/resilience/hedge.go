@@ -0,0 +1,54 @@
+package resilience
+
+import (
+	"context"
+	"time"
+)
+
+// HedgeOutcome tells whether a second call was fired and whether it answered
+type HedgeOutcome struct {
+	Fired bool
+	Won   bool
+}
+
+type hedgeResult[T any] struct {
+	value T
+	err   error
+	hedge bool
+}
+
+// Hedge calls fn and, if it has not answered after delay, calls it again
+// taking the first final answer and cancelling the other call. A non-final
+// error from one call waits for the other one, if still running
+func Hedge[T any](ctx context.Context, delay time.Duration, final func(error) bool, fn func(context.Context) (T, error)) (T, error, HedgeOutcome) {
+	ctx, cancelFunc := context.WithCancel(ctx)
+	defer cancelFunc()
+
+	// REF: buffered so the losing call never blocks after we return
+	results := make(chan hedgeResult[T], 2)
+	call := func(hedge bool) {
+		value, err := fn(ctx)
+		results <- hedgeResult[T]{value: value, err: err, hedge: hedge}
+	}
+
+	go call(false)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	outcome := HedgeOutcome{}
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			outcome.Fired = true
+			pending++
+			go call(true)
+		case r := <-results:
+			pending--
+			if r.err == nil || final(r.err) || pending == 0 {
+				outcome.Won = r.hedge
+				return r.value, r.err, outcome
+			}
+		}
+	}
+}
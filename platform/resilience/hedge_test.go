@@ -0,0 +1,68 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func alwaysFinal(error) bool { return true }
+
+func TestHedgeNotFiredForFastCalls(t *testing.T) {
+	value, err, outcome := Hedge(context.Background(), time.Second, alwaysFinal, func(context.Context) (int, error) {
+		return 1, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, value)
+	assert.False(t, outcome.Fired)
+	assert.False(t, outcome.Won)
+}
+
+func TestHedgeWinsAndCancelsLoser(t *testing.T) {
+	calls := atomic.Int32{}
+	loserCancelled := make(chan struct{})
+
+	value, err, outcome := Hedge(context.Background(), time.Millisecond, alwaysFinal, func(ctx context.Context) (int, error) {
+		if calls.Add(1) == 1 {
+			<-ctx.Done()
+			close(loserCancelled)
+			return 0, ctx.Err()
+		}
+		return 2, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, value)
+	assert.True(t, outcome.Fired)
+	assert.True(t, outcome.Won)
+
+	select {
+	case <-loserCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("loser was not cancelled")
+	}
+}
+
+func TestHedgeWaitsForOtherCallOnTransientError(t *testing.T) {
+	errTransient := errors.New("transient")
+	isFinal := func(err error) bool { return !errors.Is(err, errTransient) }
+	calls := atomic.Int32{}
+
+	value, err, outcome := Hedge(context.Background(), time.Millisecond, isFinal, func(context.Context) (int, error) {
+		if calls.Add(1) == 1 {
+			time.Sleep(5 * time.Millisecond)
+			return 0, errTransient
+		}
+		time.Sleep(10 * time.Millisecond)
+		return 2, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, value)
+	assert.True(t, outcome.Won)
+}
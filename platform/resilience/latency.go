@@ -0,0 +1,74 @@
+package resilience
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// recomputeEvery is how many observations may pass before the percentile is
+// computed again, sorting on every request would cost more than it saves
+const recomputeEvery = 64
+
+// LatencyTracker keeps a window of the most recent latencies and a percentile
+// of them, safe for concurrent use
+type LatencyTracker struct {
+	mu         sync.Mutex
+	quantile   float64
+	samples    []time.Duration
+	next       int
+	filled     bool
+	observed   int
+	percentile time.Duration
+}
+
+func (l *LatencyTracker) Observe(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % len(l.samples)
+	l.filled = l.filled || l.next == 0
+	l.observed++
+
+	if l.observed >= recomputeEvery {
+		l.observed = 0
+		l.recompute()
+	}
+}
+
+// Percentile returns false until a whole window was observed
+func (l *LatencyTracker) Percentile() (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.filled {
+		return 0, false
+	}
+
+	if l.percentile == 0 {
+		l.recompute()
+	}
+
+	return l.percentile, true
+}
+
+// recompute must be called holding the lock
+func (l *LatencyTracker) recompute() {
+	if !l.filled {
+		return
+	}
+
+	sorted := slices.Clone(l.samples)
+	slices.Sort(sorted)
+	l.percentile = sorted[int(l.quantile*float64(len(sorted)-1))]
+}
+
+// NewLatencyTracker tracks the quantile, between 0 and 1, of the last window
+// latencies
+func NewLatencyTracker(quantile float64, window int) *LatencyTracker {
+	return &LatencyTracker{
+		quantile: min(max(quantile, 0), 1),
+		samples:  make([]time.Duration, max(window, 1)),
+	}
+}
@@ -0,0 +1,38 @@
+package resilience
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyTrackerPercentile(t *testing.T) {
+	tracker := NewLatencyTracker(0.9, 100)
+
+	for i := range 99 {
+		tracker.Observe(time.Duration(i+1) * time.Millisecond)
+	}
+	_, ok := tracker.Percentile()
+	assert.False(t, ok)
+
+	tracker.Observe(100 * time.Millisecond)
+	percentile, ok := tracker.Percentile()
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Millisecond, percentile)
+}
+
+func TestLatencyTrackerSlidesWindow(t *testing.T) {
+	tracker := NewLatencyTracker(0.5, 64)
+
+	for range 64 {
+		tracker.Observe(time.Second)
+	}
+	for range 64 {
+		tracker.Observe(time.Millisecond)
+	}
+
+	percentile, ok := tracker.Percentile()
+	assert.True(t, ok)
+	assert.Equal(t, time.Millisecond, percentile)
+}
@@ -0,0 +1,60 @@
+package resilience
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/platform/o11y/semconv"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type RetryConfig struct {
+	// Retries is how many times a failed call is repeated
+	Retries int
+
+	// Backoff is the base delay, doubled on every retry
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+}
+
+// Retry calls fn until it succeeds, retryable reports false or retries run
+// out. Delays use full jitter so replicas do not retry in lockstep
+func Retry(ctx context.Context, cfg RetryConfig, retryable func(error) bool, fn func() error) error {
+	err := fn()
+	for attempt := 1; attempt <= cfg.Retries && err != nil && retryable(err); attempt++ {
+		delay := jitter(cfg.Backoff<<(attempt-1), cfg.MaxBackoff)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.Int(semconv.RetryAttempt, attempt),
+			attribute.Int64(semconv.RetryDelay, delay.Milliseconds()),
+			attribute.String(semconv.RetryError, err.Error()),
+		))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		err = fn()
+	}
+
+	return err
+}
+
+func jitter(backoff time.Duration, maxBackoff time.Duration) time.Duration {
+	if maxBackoff > 0 && (backoff > maxBackoff || backoff <= 0) {
+		backoff = maxBackoff
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	return rand.N(backoff + 1)
+}
@@ -0,0 +1,72 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient")
+
+func retryAll(err error) bool { return errors.Is(err, errTransient) }
+
+func TestRetryUntilSuccess(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), RetryConfig{Retries: 3, Backoff: time.Millisecond}, retryAll, func() error {
+		calls++
+		if calls < 3 {
+			return errTransient
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), RetryConfig{Retries: 2, Backoff: time.Millisecond}, retryAll, func() error {
+		calls++
+		return errTransient
+	})
+
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetrySkipsPermanentErrors(t *testing.T) {
+	permanent := errors.New("permanent")
+	calls := 0
+	err := Retry(context.Background(), RetryConfig{Retries: 2}, retryAll, func() error {
+		calls++
+		return permanent
+	})
+
+	assert.ErrorIs(t, err, permanent)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	cancelFunc()
+
+	calls := 0
+	err := Retry(ctx, RetryConfig{Retries: 5, Backoff: time.Hour}, retryAll, func() error {
+		calls++
+		return errTransient
+	})
+
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 1, calls)
+}
+
+func TestJitterIsBounded(t *testing.T) {
+	for range 100 {
+		assert.LessOrEqual(t, jitter(time.Second, 10*time.Millisecond), 10*time.Millisecond)
+	}
+	assert.Equal(t, time.Duration(0), jitter(0, 0))
+}
@@ -0,0 +1,272 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+var (
+	ErrInvalidSketch     = errors.New("invalid sketch")
+	ErrPrecisionMismatch = errors.New("sketches of different precision cannot be merged")
+)
+
+const (
+	MinPrecision = 4
+	MaxPrecision = 16
+
+	hllVersion = 1
+
+	formatSparse = 0
+	formatDense  = 1
+)
+
+// HyperLogLog estimates how many distinct items were added to it using 2^p
+// one byte registers, with a standard error of 1.04/sqrt(2^p). Registers are
+// kept sparse until a third of them are set, so sketches of links with few
+// visitors stay small. Sketches of the same precision merge without loss,
+// which is what lets replicas count apart and add up later
+type HyperLogLog struct {
+	precision uint8
+
+	// sparse holds the non-zero registers until dense is allocated
+	sparse map[uint16]uint8
+	dense  []uint8
+}
+
+func NewHyperLogLog(precision uint8) (*HyperLogLog, error) {
+	if precision < MinPrecision || precision > MaxPrecision {
+		return nil, fmt.Errorf("%w: precision must be between %d and %d", ErrInvalidSketch, MinPrecision, MaxPrecision)
+	}
+
+	return &HyperLogLog{precision: precision, sparse: map[uint16]uint8{}}, nil
+}
+
+// Add counts an item, adding it again does not change the estimate
+func (h *HyperLogLog) Add(item []byte) {
+	hasher := fnv.New64a()
+	hasher.Write(item)
+	h.AddHash(mix64(hasher.Sum64()))
+}
+
+func (h *HyperLogLog) AddString(item string) {
+	h.Add([]byte(item))
+}
+
+// AddHash counts an already hashed item, hashes must be uniformly distributed
+func (h *HyperLogLog) AddHash(hash uint64) {
+	index := uint16(hash >> (64 - h.precision))
+	rank := uint8(bits.LeadingZeros64(hash<<h.precision)) + 1
+	if limit := 64 - h.precision + 1; rank > limit {
+		rank = limit
+	}
+
+	h.set(index, rank)
+}
+
+func (h *HyperLogLog) set(index uint16, rank uint8) {
+	if h.dense != nil {
+		h.dense[index] = max(h.dense[index], rank)
+		return
+	}
+
+	if rank <= h.sparse[index] {
+		return
+	}
+
+	h.sparse[index] = rank
+	if 3*len(h.sparse) >= h.registers() {
+		h.densify()
+	}
+}
+
+func (h *HyperLogLog) densify() {
+	h.dense = make([]uint8, h.registers())
+	for index, rank := range h.sparse {
+		h.dense[index] = rank
+	}
+	h.sparse = nil
+}
+
+func (h *HyperLogLog) registers() int {
+	return 1 << h.precision
+}
+
+// Merge adds the items of other into h, as if they had been added to h
+func (h *HyperLogLog) Merge(other *HyperLogLog) error {
+	if h.precision != other.precision {
+		return fmt.Errorf("%w: %d and %d", ErrPrecisionMismatch, h.precision, other.precision)
+	}
+
+	if other.dense != nil {
+		for index, rank := range other.dense {
+			if rank > 0 {
+				h.set(uint16(index), rank)
+			}
+		}
+		return nil
+	}
+
+	for index, rank := range other.sparse {
+		h.set(index, rank)
+	}
+
+	return nil
+}
+
+// Estimate returns the approximate number of distinct items added. Small
+// cardinalities use linear counting, which is nearly exact for them
+func (h *HyperLogLog) Estimate() uint64 {
+	m := float64(h.registers())
+	sum, zeros := 0.0, 0
+	for index := 0; index < h.registers(); index++ {
+		rank := h.rank(uint16(index))
+		if rank == 0 {
+			zeros++
+		}
+		sum += math.Ldexp(1, -int(rank))
+	}
+
+	estimate := alpha(h.registers()) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(math.Round(estimate))
+}
+
+func (h *HyperLogLog) rank(index uint16) uint8 {
+	if h.dense != nil {
+		return h.dense[index]
+	}
+
+	return h.sparse[index]
+}
+
+func alpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	default:
+		return 0.7213 / (1 + 1.079/float64(m))
+	}
+}
+
+// MarshalBinary encodes a version, the precision and the registers, sparse
+// ones as sorted index and rank pairs
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	if h.dense != nil {
+		return append([]byte{hllVersion, h.precision, formatDense}, h.dense...), nil
+	}
+
+	indexes := make([]int, 0, len(h.sparse))
+	for index := range h.sparse {
+		indexes = append(indexes, int(index))
+	}
+	sort.Ints(indexes)
+
+	data := binary.AppendUvarint([]byte{hllVersion, h.precision, formatSparse}, uint64(len(indexes)))
+	for _, index := range indexes {
+		data = binary.BigEndian.AppendUint16(data, uint16(index))
+		data = append(data, h.sparse[uint16(index)])
+	}
+
+	return data, nil
+}
+
+func (h *HyperLogLog) UnmarshalBinary(data []byte) error {
+	if len(data) < 3 || data[0] != hllVersion {
+		return fmt.Errorf("%w: unknown header", ErrInvalidSketch)
+	}
+
+	decoded, err := NewHyperLogLog(data[1])
+	if err != nil {
+		return err
+	}
+
+	body := data[3:]
+	switch data[2] {
+	case formatDense:
+		if len(body) != decoded.registers() {
+			return fmt.Errorf("%w: %d registers, expected %d", ErrInvalidSketch, len(body), decoded.registers())
+		}
+		decoded.sparse, decoded.dense = nil, append([]uint8{}, body...)
+	case formatSparse:
+		count, n := binary.Uvarint(body)
+		if n <= 0 || uint64(len(body)-n) != 3*count {
+			return fmt.Errorf("%w: truncated sparse registers", ErrInvalidSketch)
+		}
+
+		for pair := body[n:]; len(pair) > 0; pair = pair[3:] {
+			index := binary.BigEndian.Uint16(pair)
+			if int(index) >= decoded.registers() || pair[2] > 64-decoded.precision+1 {
+				return fmt.Errorf("%w: register %d out of range", ErrInvalidSketch, index)
+			}
+			decoded.set(index, pair[2])
+		}
+	default:
+		return fmt.Errorf("%w: unknown format %d", ErrInvalidSketch, data[2])
+	}
+
+	*h = *decoded
+	return nil
+}
+
+func UnmarshalHyperLogLog(data []byte) (*HyperLogLog, error) {
+	h := &HyperLogLog{}
+	if err := h.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// MergeHyperLogLogs merges serialized sketches, for stores keeping them as
+// opaque values. Empty ones are skipped, all of them being empty is an error
+func MergeHyperLogLogs(encoded ...[]byte) ([]byte, error) {
+	var merged *HyperLogLog
+	for _, data := range encoded {
+		if len(data) == 0 {
+			continue
+		}
+
+		h, err := UnmarshalHyperLogLog(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if merged == nil {
+			merged = h
+			continue
+		}
+
+		if err := merged.Merge(h); err != nil {
+			return nil, err
+		}
+	}
+
+	if merged == nil {
+		return nil, fmt.Errorf("%w: nothing to merge", ErrInvalidSketch)
+	}
+
+	return merged.MarshalBinary()
+}
+
+// mix64 is the MurmurHash3 finalizer, FNV alone leaves the high bits the
+// register index is taken from poorly mixed for short inputs
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,126 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHLL(t *testing.T) *HyperLogLog {
+	h, err := NewHyperLogLog(12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+func assertWithin(t *testing.T, expected int, estimate uint64, tolerance float64) {
+	t.Helper()
+	diff := math.Abs(float64(estimate)-float64(expected)) / float64(expected)
+	assert.LessOrEqual(t, diff, tolerance, "estimated %d, expected %d", estimate, expected)
+}
+
+func TestHyperLogLogEstimates(t *testing.T) {
+	for _, n := range []int{1, 10, 100, 1000, 10000, 100000} {
+		h := newTestHLL(t)
+		for i := 0; i < n; i++ {
+			h.AddString(fmt.Sprintf("visitor-%d", i))
+			// REF: repeated visitors do not count twice
+			h.AddString(fmt.Sprintf("visitor-%d", i))
+		}
+
+		// REF: 1.6% standard error at precision 12, allow for three of them
+		assertWithin(t, n, h.Estimate(), 0.05)
+	}
+
+	assert.Equal(t, uint64(0), newTestHLL(t).Estimate())
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	a, b, union := newTestHLL(t), newTestHLL(t), newTestHLL(t)
+	for i := 0; i < 30000; i++ {
+		item := fmt.Sprintf("visitor-%d", i)
+		union.AddString(item)
+		// REF: replicas see overlapping visitors
+		if i < 20000 {
+			a.AddString(item)
+		}
+		if i >= 10000 {
+			b.AddString(item)
+		}
+	}
+
+	assert.NoError(t, a.Merge(b))
+	assert.Equal(t, union.Estimate(), a.Estimate())
+	assertWithin(t, 30000, a.Estimate(), 0.05)
+
+	other, err := NewHyperLogLog(10)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, a.Merge(other), ErrPrecisionMismatch)
+}
+
+func TestHyperLogLogEncoding(t *testing.T) {
+	for _, n := range []int{0, 5, 5000} {
+		h := newTestHLL(t)
+		for i := 0; i < n; i++ {
+			h.AddString(fmt.Sprintf("visitor-%d", i))
+		}
+
+		data, err := h.MarshalBinary()
+		assert.NoError(t, err)
+
+		decoded, err := UnmarshalHyperLogLog(data)
+		assert.NoError(t, err)
+		assert.Equal(t, h.Estimate(), decoded.Estimate())
+
+		again, err := decoded.MarshalBinary()
+		assert.NoError(t, err)
+		assert.Equal(t, data, again)
+	}
+
+	// REF: small sketches are stored sparse
+	h := newTestHLL(t)
+	h.AddString("visitor")
+	data, err := h.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Len(t, data, 7)
+}
+
+func TestHyperLogLogRejectsInvalid(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		{9, 12, formatDense},
+		{hllVersion, 40, formatDense},
+		{hllVersion, 12, formatDense, 1, 2},
+		{hllVersion, 12, formatSparse, 2, 0, 1, 1},
+		{hllVersion, 12, formatSparse, 1, 0xff, 0xff, 1},
+		{hllVersion, 12, 7},
+	} {
+		_, err := UnmarshalHyperLogLog(data)
+		assert.ErrorIs(t, err, ErrInvalidSketch, data)
+	}
+
+	_, err := NewHyperLogLog(2)
+	assert.ErrorIs(t, err, ErrInvalidSketch)
+}
+
+func TestMergeHyperLogLogs(t *testing.T) {
+	a, b := newTestHLL(t), newTestHLL(t)
+	a.AddString("first")
+	b.AddString("second")
+	encodedA, _ := a.MarshalBinary()
+	encodedB, _ := b.MarshalBinary()
+
+	merged, err := MergeHyperLogLogs(nil, encodedA, encodedB)
+	assert.NoError(t, err)
+
+	decoded, err := UnmarshalHyperLogLog(merged)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), decoded.Estimate())
+
+	_, err = MergeHyperLogLogs(nil, nil)
+	assert.ErrorIs(t, err, ErrInvalidSketch)
+}
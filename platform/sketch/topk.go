@@ -0,0 +1,165 @@
+package sketch
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+)
+
+// Counter is an item tracked by a TopK. Its true count lies between
+// Count-Error and Count
+type Counter struct {
+	Key   string
+	Count int64
+	Error int64
+}
+
+// TopK finds the most frequent items of a stream with the space-saving
+// algorithm, tracking at most capacity of them. Counts are exact until the
+// capacity is reached, then a new item takes over the least counted one and
+// inherits its count as error. Any item seen more than total/capacity times
+// is guaranteed to be tracked
+type TopK struct {
+	capacity int
+	index    map[string]*topKEntry
+	entries  topKHeap
+}
+
+type topKEntry struct {
+	Counter
+	position int
+}
+
+func NewTopK(capacity int) (*TopK, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("%w: capacity must be positive", ErrInvalidSketch)
+	}
+
+	return &TopK{capacity: capacity, index: map[string]*topKEntry{}}, nil
+}
+
+// Add counts count more occurrences of key
+func (t *TopK) Add(key string, count int64) {
+	if e, found := t.index[key]; found {
+		e.Count += count
+		heap.Fix(&t.entries, e.position)
+		return
+	}
+
+	if len(t.entries) < t.capacity {
+		e := &topKEntry{Counter: Counter{Key: key, Count: count}}
+		t.index[key] = e
+		heap.Push(&t.entries, e)
+		return
+	}
+
+	// REF: the least counted item is replaced, its count bounds how often key
+	// could have been seen while untracked
+	e := t.entries[0]
+	delete(t.index, e.Key)
+	e.Key, e.Error, e.Count = key, e.Count, e.Count+count
+	t.index[key] = e
+	heap.Fix(&t.entries, 0)
+}
+
+// Merge adds the items of other into t. Items only one of them tracks get
+// the least count of the other as error, when that other is full, as they
+// could have been evicted from it with up to that many occurrences
+func (t *TopK) Merge(other *TopK) {
+	floor, otherFloor := t.floor(), other.floor()
+
+	merged := make(map[string]Counter, len(t.index)+len(other.index))
+	for key, e := range t.index {
+		merged[key] = Counter{Key: key, Count: e.Count + otherFloor, Error: e.Error + otherFloor}
+	}
+
+	for key, e := range other.index {
+		c, found := merged[key]
+		if found {
+			c.Count += e.Count - otherFloor
+			c.Error += e.Error - otherFloor
+		} else {
+			c = Counter{Key: key, Count: e.Count + floor, Error: e.Error + floor}
+		}
+		merged[key] = c
+	}
+
+	counters := make([]Counter, 0, len(merged))
+	for _, c := range merged {
+		counters = append(counters, c)
+	}
+	sortCounters(counters)
+	if len(counters) > t.capacity {
+		counters = counters[:t.capacity]
+	}
+
+	t.index, t.entries = make(map[string]*topKEntry, len(counters)), make(topKHeap, 0, len(counters))
+	for _, c := range counters {
+		e := &topKEntry{Counter: c}
+		t.index[c.Key] = e
+		heap.Push(&t.entries, e)
+	}
+}
+
+// floor is the count an untracked item could have, zero until the
+// capacity is reached
+func (t *TopK) floor() int64 {
+	if len(t.entries) < t.capacity {
+		return 0
+	}
+
+	return t.entries[0].Count
+}
+
+// Top returns up to n items from the most counted down, ties by key
+func (t *TopK) Top(n int) []Counter {
+	counters := make([]Counter, 0, len(t.entries))
+	for _, e := range t.entries {
+		counters = append(counters, e.Counter)
+	}
+	sortCounters(counters)
+
+	if n >= 0 && len(counters) > n {
+		counters = counters[:n]
+	}
+
+	return counters
+}
+
+// Len is how many items are tracked
+func (t *TopK) Len() int {
+	return len(t.entries)
+}
+
+func sortCounters(counters []Counter) {
+	sort.Slice(counters, func(i, j int) bool {
+		if counters[i].Count != counters[j].Count {
+			return counters[i].Count > counters[j].Count
+		}
+		return counters[i].Key < counters[j].Key
+	})
+}
+
+// topKHeap is a min-heap by count, so the eviction candidate is at the root
+type topKHeap []*topKEntry
+
+func (h topKHeap) Len() int           { return len(h) }
+func (h topKHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+
+func (h topKHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].position, h[j].position = i, j
+}
+
+func (h *topKHeap) Push(x any) {
+	e := x.(*topKEntry)
+	e.position = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *topKHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,79 @@
+package sketch
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTopK(t *testing.T, capacity int) *TopK {
+	topK, err := NewTopK(capacity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return topK
+}
+
+func TestTopKExactUnderCapacity(t *testing.T) {
+	topK := newTestTopK(t, 10)
+	for _, key := range []string{"a", "b", "a", "c", "a", "b"} {
+		topK.Add(key, 1)
+	}
+	assert.Equal(t, 3, topK.Len())
+
+	assert.Equal(t, []Counter{{Key: "a", Count: 3}, {Key: "b", Count: 2}, {Key: "c", Count: 1}}, topK.Top(-1))
+	assert.Equal(t, []Counter{{Key: "a", Count: 3}}, topK.Top(1))
+
+	_, err := NewTopK(0)
+	assert.ErrorIs(t, err, ErrInvalidSketch)
+}
+
+func TestTopKFindsHeavyHitters(t *testing.T) {
+	topK := newTestTopK(t, 20)
+
+	// REF: a long tail of links seen once each, interleaved with two hot ones
+	for i := 0; i < 5000; i++ {
+		topK.Add(fmt.Sprintf("tail-%d", i), 1)
+		if i%5 == 0 {
+			topK.Add("hot", 1)
+		}
+		if i%10 == 0 {
+			topK.Add("warm", 1)
+		}
+	}
+
+	top := topK.Top(2)
+	assert.Equal(t, "hot", top[0].Key)
+	assert.Equal(t, "warm", top[1].Key)
+	assert.Equal(t, 20, topK.Len())
+
+	// REF: counts overestimate by at most their error
+	for _, c := range top {
+		expected := map[string]int64{"hot": 1000, "warm": 500}[c.Key]
+		assert.GreaterOrEqual(t, c.Count, expected)
+		assert.LessOrEqual(t, c.Count-c.Error, expected)
+	}
+}
+
+func TestTopKMerge(t *testing.T) {
+	first, second := newTestTopK(t, 3), newTestTopK(t, 3)
+	first.Add("a", 5)
+	first.Add("b", 2)
+	second.Add("a", 1)
+	second.Add("c", 4)
+
+	// REF: neither is full, counts add up exactly
+	first.Merge(second)
+	assert.Equal(t, []Counter{{Key: "a", Count: 6}, {Key: "c", Count: 4}, {Key: "b", Count: 2}}, first.Top(-1))
+
+	full := newTestTopK(t, 3)
+	full.Add("d", 3)
+	full.Add("e", 2)
+	full.Add("f", 1)
+
+	// REF: items missing from a full summary may have been evicted from it
+	first.Merge(full)
+	assert.Equal(t, []Counter{{Key: "a", Count: 7, Error: 1}, {Key: "c", Count: 5, Error: 1}, {Key: "d", Count: 5, Error: 2}}, first.Top(-1))
+}
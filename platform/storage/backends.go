@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain/validators"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories"
+	bolt "go.etcd.io/bbolt"
+)
+
+func noClose() error {
+	return nil
+}
+
+// newMemory keeps everything in the process, for local development without
+// AWS. URLs optionally survive restarts through a snapshot file
+func newMemory(cfg config.AppConfig) (*Storage, error) {
+	ids, err := validators.NewIdAlphabet(cfg.Hasher.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := repositories.NewMemory(repositories.WithIdAlphabet(ids))
+	result := &Storage{
+		URLs:      urls,
+		Hits:      repositories.NewMemoryHits(),
+		Sequences: repositories.NewMemorySequences(),
+		Close:     noClose,
+	}
+
+	if cfg.Memory.SnapshotFile == "" {
+		return result, nil
+	}
+
+	if err := urls.LoadFile(cfg.Memory.SnapshotFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	result.Close = func() error {
+		return urls.SnapshotFile(cfg.Memory.SnapshotFile)
+	}
+
+	return result, nil
+}
+
+func newDynamo(cfg config.AppConfig) (*Storage, error) {
+	ids, err := validators.NewIdAlphabet(cfg.Hasher.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamoClient, err := clients.NewDynamoClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Storage{
+		URLs:      repositories.NewDynamoURLRepository(cfg, ids, dynamoClient),
+		Hits:      repositories.NewDynamoHitsRepository(cfg, dynamoClient),
+		Sequences: repositories.NewDynamoSequenceRepository(cfg, dynamoClient),
+		Close:     noClose,
+	}, nil
+}
+
+// newSQL serves both sqlite and postgres, migrating the schema before use
+func newSQL(cfg config.AppConfig) (*Storage, error) {
+	ids, err := validators.NewIdAlphabet(cfg.Hasher.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := clients.NewSQLClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelFunc()
+
+	if err := repositories.MigrateSQL(ctx, cfg.Storage.Backend, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	result := &Storage{Close: db.Close}
+	if result.URLs, err = repositories.NewSQLURLRepository(cfg, ids, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if result.Hits, err = repositories.NewSQLHitsRepository(cfg, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if result.Sequences, err = repositories.NewSQLSequenceRepository(cfg, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func newBolt(cfg config.AppConfig) (*Storage, error) {
+	ids, err := validators.NewIdAlphabet(cfg.Hasher.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := clients.NewBoltClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Storage{Close: db.Close}
+	if result.URLs, err = repositories.NewBoltURLRepository(db, ids); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if result.Hits, err = repositories.NewBoltHitsRepository(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if result.Sequences, err = repositories.NewBoltSequenceRepository(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if cfg.Bolt.BackupPath != "" {
+		stopBackups := backupPeriodically(cfg, db)
+		result.Close = func() error {
+			stopBackups()
+			return db.Close()
+		}
+	}
+
+	return result, nil
+}
+
+// backupPeriodically replaces the bolt backup every BackupInterval, the
+// returned function stops it after taking a last one
+func backupPeriodically(cfg config.AppConfig, db *bolt.DB) func() {
+	backup := func() {
+		if err := repositories.BackupBolt(db, cfg.Bolt.BackupPath); err != nil {
+			slog.Error("bolt backup failed", "error", err.Error())
+		}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(cfg.Bolt.BackupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				backup()
+			case <-done:
+				backup()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
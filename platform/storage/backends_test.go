@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	validURL, _ = url.Parse("https://opentelemetry.io")
+	validAuthor = "root@neonmei.cloud"
+)
+
+// REF: every backend but DynamoDB runs without external services
+func TestOpenLocalBackends(t *testing.T) {
+	for _, backend := range []string{clients.BackendMemory, clients.BackendSQLite, clients.BackendBolt} {
+		t.Run(backend, func(t *testing.T) {
+			cfg := config.AppConfig{}
+			cfg.Storage.Backend = backend
+			cfg.ShutdownTimeout = time.Second
+			cfg.Sql.Dsn = filepath.Join(t.TempDir(), "shortener.db")
+			cfg.Sql.ReadTimeout = time.Second
+			cfg.Sql.WriteTimeout = time.Second
+			cfg.Bolt.Path = filepath.Join(t.TempDir(), "shortener.bolt")
+			cfg.Bolt.OpenTimeout = time.Second
+
+			store, err := Open(cfg)
+			require.NoError(t, err)
+			defer store.Close()
+
+			ctx := context.Background()
+			assert.NoError(t, store.URLs.Save(ctx, domain.ShortURL{
+				ID:        "asd",
+				Upstream:  *validURL,
+				CreatedBy: validAuthor,
+				CreatedAt: time.Now(),
+				Enabled:   true,
+			}))
+
+			_, err = store.URLs.Get(ctx, "asd")
+			assert.NoError(t, err)
+
+			start, err := store.Sequences.Lease(ctx, "ids", 10)
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(0), start)
+
+			assert.NoError(t, store.Hits.AddHits(ctx, []domain.HitCount{
+				{URLId: "asd", Granularity: domain.GranularityDay, Start: time.Now(), Hits: 1},
+			}))
+		})
+	}
+}
+
+func TestBoltBackupOnClose(t *testing.T) {
+	cfg := config.AppConfig{}
+	cfg.Storage.Backend = clients.BackendBolt
+	cfg.Bolt.Path = filepath.Join(t.TempDir(), "shortener.bolt")
+	cfg.Bolt.OpenTimeout = time.Second
+	cfg.Bolt.BackupPath = filepath.Join(t.TempDir(), "backup.bolt")
+	cfg.Bolt.BackupInterval = time.Hour
+
+	store, err := Open(cfg)
+	assert.NoError(t, err)
+
+	_, err = os.Stat(cfg.Bolt.BackupPath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// REF: closing takes a last backup
+	assert.NoError(t, store.Close())
+	_, err = os.Stat(cfg.Bolt.BackupPath)
+	assert.NoError(t, err)
+}
+
+func TestMemorySnapshotAcrossRestarts(t *testing.T) {
+	cfg := config.AppConfig{}
+	cfg.Storage.Backend = clients.BackendMemory
+	cfg.Memory.SnapshotFile = filepath.Join(t.TempDir(), "snapshot.json")
+	ctx := context.Background()
+
+	// REF: a missing snapshot starts empty
+	store, err := Open(cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, store.URLs.Save(ctx, domain.ShortURL{
+		ID:        "asd",
+		Upstream:  *validURL,
+		CreatedBy: validAuthor,
+		CreatedAt: time.Now(),
+		Enabled:   true,
+	}))
+	assert.NoError(t, store.Close())
+
+	store, err = Open(cfg)
+	assert.NoError(t, err)
+	_, err = store.URLs.Get(ctx, "asd")
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(cfg.Memory.SnapshotFile, []byte("corrupt"), 0o600))
+	_, err = Open(cfg)
+	assert.ErrorIs(t, err, domain.ErrRepoSchema)
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+)
+
+// Fixture is an element of the JSON array a backend can be seeded from.
+// CreatedBy, CreatedAt and Enabled default to the seeding author, now and true
+type Fixture struct {
+	Id        string    `json:"url_id"`
+	FullURL   string    `json:"full_url"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Enabled   *bool     `json:"enabled"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (f Fixture) Domain(author string, now time.Time) (*domain.ShortURL, error) {
+	u, err := url.Parse(f.FullURL)
+	if err != nil {
+		return nil, errors.Join(domain.ErrInvalidURL, err)
+	}
+
+	shortUrl := domain.ShortURL{
+		ID:        f.Id,
+		Upstream:  *u,
+		CreatedBy: f.CreatedBy,
+		CreatedAt: f.CreatedAt,
+		Enabled:   true,
+		ExpiresAt: f.ExpiresAt,
+	}
+
+	if shortUrl.CreatedBy == "" {
+		shortUrl.CreatedBy = author
+	}
+
+	if shortUrl.CreatedAt.IsZero() {
+		shortUrl.CreatedAt = now
+	}
+
+	if f.Enabled != nil {
+		shortUrl.Enabled = *f.Enabled
+	}
+
+	return &shortUrl, nil
+}
+
+// Seed saves every fixture read from r. Fixtures already stored are left
+// untouched, so seeding on every start is safe. Returns how many were created
+func Seed(ctx context.Context, repo domain.URLRepository, r io.Reader, author string) (int, error) {
+	fixtures := []Fixture{}
+	if err := json.NewDecoder(r).Decode(&fixtures); err != nil {
+		return 0, errors.Join(errors.New("cannot parse fixtures"), err)
+	}
+
+	created := 0
+	now := time.Now()
+	for i, fixture := range fixtures {
+		shortUrl, err := fixture.Domain(author, now)
+		if err != nil {
+			return created, fmt.Errorf("fixture %d: %w", i, err)
+		}
+
+		err = repo.Save(ctx, *shortUrl)
+		if errors.Is(err, domain.ErrURLAlreadyExists) {
+			continue
+		}
+
+		if err != nil {
+			return created, fmt.Errorf("fixture %d (%s): %w", i, fixture.Id, err)
+		}
+		created++
+	}
+
+	return created, nil
+}
+
+// SeedFile seeds repo from a fixtures file
+func SeedFile(ctx context.Context, repo domain.URLRepository, path string, author string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return Seed(ctx, repo, f, author)
+}
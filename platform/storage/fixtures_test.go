@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+const fixtures = `[
+	{"url_id": "docs", "full_url": "https://opentelemetry.io/docs"},
+	{"url_id": "old", "full_url": "https://go.dev", "created_by": "other@neonmei.cloud",
+	 "created_at": "2025-01-01T00:00:00Z", "enabled": false}
+]`
+
+func TestSeed(t *testing.T) {
+	repo := repositories.NewMemory()
+	ctx := context.Background()
+
+	created, err := Seed(ctx, repo, strings.NewReader(fixtures), validAuthor)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, created)
+
+	// REF: defaults
+	docs, err := repo.Get(ctx, "docs")
+	assert.NoError(t, err)
+	assert.Equal(t, validAuthor, docs.CreatedBy)
+	assert.True(t, docs.Enabled)
+	assert.WithinDuration(t, time.Now(), docs.CreatedAt, time.Minute)
+
+	old, err := repo.Get(ctx, "old")
+	assert.NoError(t, err)
+	assert.Equal(t, "other@neonmei.cloud", old.CreatedBy)
+	assert.False(t, old.Enabled)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), old.CreatedAt)
+
+	// REF: seeding again keeps what is stored
+	assert.NoError(t, repo.Restore(ctx, "old"))
+	created, err = Seed(ctx, repo, strings.NewReader(fixtures), validAuthor)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, created)
+
+	old, err = repo.Get(ctx, "old")
+	assert.NoError(t, err)
+	assert.True(t, old.Enabled)
+}
+
+func TestSeedInvalid(t *testing.T) {
+	repo := repositories.NewMemory()
+	ctx := context.Background()
+
+	_, err := Seed(ctx, repo, strings.NewReader(`{"url_id": "docs"}`), validAuthor)
+	assert.Error(t, err)
+
+	created, err := Seed(ctx, repo, strings.NewReader(`[
+		{"url_id": "docs", "full_url": "https://opentelemetry.io/docs"},
+		{"url_id": "insecure", "full_url": "http://opentelemetry.io"}
+	]`), validAuthor)
+	assert.ErrorIs(t, err, domain.ErrInvalidURL)
+	assert.ErrorContains(t, err, "insecure")
+	assert.Equal(t, 1, created)
+
+	_, err = SeedFile(ctx, repo, "missing.json", validAuthor)
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+)
+
+// Storage groups the repositories of a backend
+type Storage struct {
+	URLs      domain.URLRepository
+	Hits      domain.HitsRepository
+	Sequences domain.SequenceRepository
+
+	// Close releases connections and files, flushing whatever is pending
+	Close func() error
+}
+
+// Factory builds the repositories of a backend from configuration
+type Factory func(cfg config.AppConfig) (*Storage, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{
+		clients.BackendMemory:   newMemory,
+		clients.BackendDynamo:   newDynamo,
+		clients.BackendSQLite:   newSQL,
+		clients.BackendPostgres: newSQL,
+		clients.BackendBolt:     newBolt,
+	}
+)
+
+// Register makes a backend selectable by name, replacing any previous one
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	factories[name] = factory
+}
+
+// Backends lists the registered backend names, sorted
+func Backends() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Open builds the backend selected by cfg.Storage.Backend
+func Open(cfg config.AppConfig) (*Storage, error) {
+	mu.RLock()
+	factory, found := factories[cfg.Storage.Backend]
+	mu.RUnlock()
+
+	if !found {
+		return nil, fmt.Errorf("%w: %s, expected one of %v", domain.ErrUnknownBackend, cfg.Storage.Backend, Backends())
+	}
+
+	return factory(cfg)
+}
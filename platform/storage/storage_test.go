@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/clients"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenUnknownBackend(t *testing.T) {
+	cfg := config.AppConfig{}
+	cfg.Storage.Backend = "cassandra"
+
+	_, err := Open(cfg)
+	assert.ErrorIs(t, err, domain.ErrUnknownBackend)
+	assert.ErrorContains(t, err, clients.BackendMemory)
+}
+
+func TestRegister(t *testing.T) {
+	urls := repositories.NewMemory()
+	Register("custom", func(cfg config.AppConfig) (*Storage, error) {
+		return &Storage{URLs: urls, Close: noClose}, nil
+	})
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(factories, "custom")
+	})
+
+	assert.Contains(t, Backends(), "custom")
+	assert.Contains(t, Backends(), clients.BackendDynamo)
+
+	cfg := config.AppConfig{}
+	cfg.Storage.Backend = "custom"
+	store, err := Open(cfg)
+	assert.NoError(t, err)
+	assert.Same(t, urls, store.URLs)
+}
@@ -0,0 +1,179 @@
+package warmup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+	"github.com/neonmei/challenge_urlshortener/platform/invalidation"
+	"github.com/neonmei/challenge_urlshortener/platform/o11y/semconv"
+)
+
+// Path is where replicas serve their trending links to new peers
+const Path = "/platform/trending"
+
+// loaders bounds how many links are read from the repository at once
+const loaders = 8
+
+var ErrPeerRejected = errors.New("peer rejected trending request")
+
+// Warmer asks peer replicas for the links they are redirecting the most and
+// reads them through the cache, so a new replica does not start cold while a
+// campaign is running
+type Warmer struct {
+	peers   invalidation.PeerSource
+	client  *http.Client
+	token   string
+	window  time.Duration
+	limit   int
+	timeout time.Duration
+}
+
+// Trending adds up the trending links of every peer that answers, from the
+// most redirected down. It only fails when none of them does
+func (w *Warmer) Trending(ctx context.Context) ([]string, error) {
+	peers, err := w.peers.Peers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wg := sync.WaitGroup{}
+	answers := make([][]dtos.TrendingURL, len(peers))
+	errs := make([]error, len(peers))
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, peer string) {
+			defer wg.Done()
+			answers[i], errs[i] = w.fetch(ctx, peer)
+		}(i, peer)
+	}
+	wg.Wait()
+
+	hits := map[string]int64{}
+	answered := 0
+	for i, answer := range answers {
+		if errs[i] != nil {
+			continue
+		}
+
+		answered++
+		for _, u := range answer {
+			hits[u.URLId] += u.Hits
+		}
+	}
+
+	if answered == 0 && len(peers) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	ids := make([]string, 0, len(hits))
+	for id := range hits {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if hits[ids[i]] != hits[ids[j]] {
+			return hits[ids[i]] > hits[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	if len(ids) > w.limit {
+		ids = ids[:w.limit]
+	}
+
+	return ids, nil
+}
+
+func (w *Warmer) fetch(ctx context.Context, peer string) ([]dtos.TrendingURL, error) {
+	query := url.Values{"window": {w.window.String()}, "limit": {strconv.Itoa(w.limit)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, peer+Path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", w.token)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Join(ErrPeerRejected, fmt.Errorf("%s answered %d", peer, resp.StatusCode))
+	}
+
+	response := dtos.TrendingResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	return response.URLs, nil
+}
+
+// Warm reads the trending links of peers through repo, which caches them,
+// and returns how many were found. Missing or failing links are skipped
+func (w *Warmer) Warm(ctx context.Context, repo domain.URLRepository) (int, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, w.timeout)
+	defer cancelFunc()
+
+	ids, err := w.Trending(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	work := make(chan string)
+	loaded := make(chan bool, len(ids))
+	wg := sync.WaitGroup{}
+	for range min(loaders, len(ids)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range work {
+				_, err := repo.Get(ctx, id)
+				if err != nil {
+					slog.Debug("cannot warm up link", semconv.UrlId, id, "error", err)
+				}
+				loaded <- err == nil
+			}
+		}()
+	}
+
+	for _, id := range ids {
+		work <- id
+	}
+	close(work)
+	wg.Wait()
+	close(loaded)
+
+	count := 0
+	for ok := range loaded {
+		if ok {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// NewWarmer asks peers with the invalidation token, they trust each other with it
+func NewWarmer(cfg config.AppConfig, peers invalidation.PeerSource) *Warmer {
+	return &Warmer{
+		peers:   peers,
+		client:  &http.Client{},
+		token:   cfg.Invalidation.Token,
+		window:  cfg.Trending.WarmupWindow,
+		limit:   cfg.Trending.WarmupLimit,
+		timeout: cfg.Trending.WarmupTimeout,
+	}
+}
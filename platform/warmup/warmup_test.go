@@ -0,0 +1,110 @@
+package warmup_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2"
+	"github.com/neonmei/challenge_urlshortener/domain"
+	"github.com/neonmei/challenge_urlshortener/platform/config"
+	"github.com/neonmei/challenge_urlshortener/platform/dtos"
+	"github.com/neonmei/challenge_urlshortener/platform/invalidation"
+	"github.com/neonmei/challenge_urlshortener/platform/repositories"
+	"github.com/neonmei/challenge_urlshortener/platform/warmup"
+	"github.com/stretchr/testify/assert"
+)
+
+const testToken = "replica-secret"
+
+var validURL, _ = url.Parse("https://opentelemetry.io")
+
+func testConfig() config.AppConfig {
+	cfg := config.Load()
+	cfg.Invalidation.Token = testToken
+	cfg.Trending.WarmupLimit = 3
+	cfg.Trending.WarmupTimeout = time.Second
+	return cfg
+}
+
+// newPeer serves trending links the way replicas do
+func newPeer(t *testing.T, urls ...dtos.TrendingURL) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, warmup.Path, r.URL.Path)
+		assert.Equal(t, "15m0s", r.URL.Query().Get("window"))
+		assert.Equal(t, "3", r.URL.Query().Get("limit"))
+		if r.Header.Get("Authorization") != testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(dtos.TrendingResponse{Window: "15m0s", URLs: urls})
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func TestWarmerAddsUpPeers(t *testing.T) {
+	dead := httptest.NewServer(nil)
+	dead.Close()
+
+	peers := invalidation.StaticPeers{
+		newPeer(t, dtos.TrendingURL{URLId: "aaa", Hits: 10}, dtos.TrendingURL{URLId: "bbb", Hits: 8}),
+		newPeer(t, dtos.TrendingURL{URLId: "bbb", Hits: 5}, dtos.TrendingURL{URLId: "ccc", Hits: 2}, dtos.TrendingURL{URLId: "ddd", Hits: 1}),
+		dead.URL,
+	}
+
+	// REF: a dead peer does not keep the others from warming this one up
+	ids, err := warmup.NewWarmer(testConfig(), peers).Trending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bbb", "aaa", "ccc"}, ids)
+}
+
+func TestWarmerFailsWithoutPeers(t *testing.T) {
+	cfg := testConfig()
+	cfg.Invalidation.Token = "wrong"
+
+	_, err := warmup.NewWarmer(cfg, invalidation.StaticPeers{newPeer(t)}).Trending(context.Background())
+	assert.ErrorIs(t, err, warmup.ErrPeerRejected)
+
+	// REF: being the first replica is fine
+	ids, err := warmup.NewWarmer(testConfig(), invalidation.StaticPeers{}).Trending(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestWarmLoadsCache(t *testing.T) {
+	ctx := context.Background()
+	shared := repositories.NewMemory()
+	for _, id := range []string{"aaa", "bbb"} {
+		assert.NoError(t, shared.Save(ctx, domain.ShortURL{
+			ID: id, Upstream: *validURL, CreatedBy: "root@neonmei.cloud", CreatedAt: time.Now(), Enabled: true,
+		}))
+	}
+
+	cache, err := ristretto.NewCache(&repositories.URLCacheConfig{NumCounters: 1000, MaxCost: 10000, BufferItems: 64})
+	assert.NoError(t, err)
+	t.Cleanup(cache.Close)
+
+	peers := invalidation.StaticPeers{newPeer(t,
+		dtos.TrendingURL{URLId: "aaa", Hits: 10},
+		dtos.TrendingURL{URLId: "bbb", Hits: 8},
+		dtos.TrendingURL{URLId: "zzz", Hits: 1},
+	)}
+
+	// REF: links deleted meanwhile are skipped
+	warmed, err := warmup.NewWarmer(testConfig(), peers).Warm(ctx, repositories.NewCached(shared, cache))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, warmed)
+
+	cache.Wait()
+	for _, id := range []string{"aaa", "bbb"} {
+		_, found := cache.Get(id)
+		assert.True(t, found, id)
+	}
+}